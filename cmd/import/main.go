@@ -0,0 +1,119 @@
+// Command import loads questions from a CSV, JSON, GIFT or Moodle XML file
+// into an existing quiz, with the same validation and access checks as the
+// API: the user must be allowed to edit the quiz.
+//
+//	go run ./cmd/import -quiz <quiz-id> -user <user-id> [-format gift] [-dry-run] questions.gift
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/bootstrap"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/importer"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+)
+
+// errNothingImported fails the command when every question was rejected
+var errNothingImported = errors.New("no question could be imported")
+
+func main() {
+	quizFlag := flag.String("quiz", "", "ID of the quiz to import into")
+	userFlag := flag.String("user", "", "ID of the user importing, an editor of the quiz")
+	formatFlag := flag.String("format", "", "csv, json, gift or xml (default: from the file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate the file without saving anything")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s -quiz <id> -user <id> [flags] <file|->\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *quizFlag == "" || *userFlag == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// run returns instead of exiting so that its deferred closes happen
+	if err := run(*quizFlag, *userFlag, *formatFlag, flag.Arg(0), *dryRun); err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(quiz, user, formatName, path string, dryRun bool) error {
+	quizID, err := uuid.Parse(quiz)
+	if err != nil {
+		return fmt.Errorf("invalid quiz ID: %w", err)
+	}
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	var format importer.Format
+	if formatName != "" {
+		format, err = importer.ParseFormat(formatName)
+	} else {
+		format, err = importer.FormatFromFilename(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	var source io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		source = file
+	}
+
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config/local.yaml"
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config %s: %w", configPath, err)
+	}
+
+	db, err := bootstrap.NewGormDB(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("connecting to PostgreSQL: %w", err)
+	}
+	defer bootstrap.CloseGormDB(db)
+
+	importService := service.NewImportService(repository.NewQuizRepository(db), repository.NewQuestionRepository(db), repository.NewOrgRepository(db))
+	report, err := importService.ImportQuestions(context.Background(), service.ImportQuestionsInput{
+		QuizID: quizID,
+		Format: format,
+		Source: source,
+		DryRun: dryRun,
+	}, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, rejected := range report.Rejected {
+		fmt.Printf("%s:%d: %s\n", path, rejected.Line, rejected.Reason)
+	}
+	verb := "imported"
+	if report.DryRun {
+		verb = "would import"
+	}
+	fmt.Printf("%s %d question(s), rejected %d\n", verb, report.Imported, len(report.Rejected))
+
+	if report.Imported == 0 && len(report.Rejected) > 0 {
+		return errNothingImported
+	}
+	return nil
+}
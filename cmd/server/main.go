@@ -36,6 +36,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := cfg.JWT.Validate(); err != nil {
+		slog.Error("Invalid configuration", "error", err, "path", configPath)
+		os.Exit(1)
+	}
+
 	slog.Info("Configuration loaded", "path", configPath)
 
 	// Initialize PostgreSQL (GORM)
@@ -74,6 +79,11 @@ func main() {
 		}
 	}()
 
+	// Start the scheduler and key rotation; every replica runs them
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	go router.RunScheduler(backgroundCtx)
+	go router.RunKeyRotation(backgroundCtx)
+
 	slog.Info("Server is running! ✅", "address", addr)
 
 	// Wait for interrupt signal
@@ -82,6 +92,7 @@ func main() {
 	<-quit
 
 	slog.Info("Shutting down server...")
+	stopBackground()
 
 	// Graceful shutdown with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
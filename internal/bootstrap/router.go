@@ -1,6 +1,9 @@
 package bootstrap
 
 import (
+	"context"
+	"log/slog"
+
 	"github.com/gin-gonic/gin"
 	"github.com/nguyen1302/realtime-quiz/internal/config"
 	"github.com/nguyen1302/realtime-quiz/internal/handler"
@@ -26,6 +29,11 @@ func NewRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *Router {
 	// Setup Gin
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
+	// Client IPs are rate limited, so only named proxies may set them
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Error("Invalid trusted proxies, trusting none", "error", err)
+		engine.SetTrustedProxies(nil)
+	}
 	engine.Use(gin.Recovery())
 	engine.Use(middleware.LoggerMiddleware())
 	engine.Use(middleware.CORSMiddleware())
@@ -43,35 +51,124 @@ func NewRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *Router {
 
 func (r *Router) setupRoutes() {
 	api := r.engine.Group("/api/v1")
+	limit := func(rule string) gin.HandlerFunc {
+		return middleware.RateLimitMiddleware(r.services.RateLimit(), rule)
+	}
+	throttle := func(name string) gin.HandlerFunc {
+		return middleware.ThrottleMiddleware(r.services.RateLimit(), name)
+	}
 
 	// Public auth routes
 	auth := api.Group("/auth")
 	{
-		auth.POST("/register", r.handlers.Auth().Register)
-		auth.POST("/login", r.handlers.Auth().Login)
+		auth.POST("/register", limit("register"), r.handlers.Auth().Register)
+		auth.POST("/login", limit("login"), r.handlers.Auth().Login)
+		auth.POST("/refresh", limit("refresh"), r.handlers.Auth().Refresh)
+		auth.POST("/verify-email", limit("account"), r.handlers.Account().VerifyEmail)
+		auth.POST("/password/forgot", limit("account"), r.handlers.Account().ForgotPassword)
+		auth.POST("/password/reset", limit("account"), r.handlers.Account().ResetPassword)
+		auth.GET("/oidc/providers", r.handlers.OIDC().ListProviders)
+		auth.GET("/oidc/:provider/login", r.handlers.OIDC().Login)
+		auth.GET("/oidc/:provider/callback", r.handlers.OIDC().Callback)
 	}
 
-	// WebSocket route
-	api.GET("/ws", r.handlers.Realtime().HandleConnection)
+	// WebSocket route. Anonymous sockets connect but cannot follow quizzes
+	api.GET("/ws", middleware.OptionalAuthMiddleware(r.services.Auth()), r.handlers.Realtime().HandleConnection)
 
 	// Protected routes
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware(r.services.Auth()))
 	{
 		protected.GET("/auth/me", r.handlers.Auth().GetMe)
+		protected.POST("/auth/logout", r.handlers.Auth().Logout)
+		protected.POST("/auth/verify-email/resend", limit("account"), r.handlers.Account().RequestVerification)
+
+		// Profile routes
+		me := protected.Group("/users/me")
+		{
+			me.GET("", r.handlers.Profile().GetProfile)
+			me.PATCH("", r.handlers.Profile().UpdateProfile)
+			me.DELETE("", r.handlers.Profile().DeleteAccount)
+			me.PUT("/email", r.handlers.Profile().ChangeEmail)
+			me.PUT("/password", r.handlers.Profile().ChangePassword)
+			me.GET("/export", r.handlers.Profile().ExportData)
+		}
+
+		// Organisation routes
+		orgs := protected.Group("/orgs")
+		{
+			orgs.POST("", r.handlers.Org().CreateOrg)
+			orgs.GET("", r.handlers.Org().ListOrgs)
+			orgs.PATCH("/:id", r.handlers.Org().RenameOrg)
+			orgs.GET("/:id/quizzes", r.handlers.Org().ListQuizzes)
+			orgs.GET("/:id/members", r.handlers.Org().ListMembers)
+			orgs.PUT("/:id/members/:uid", r.handlers.Org().SetRole)
+			orgs.DELETE("/:id/members/:uid", r.handlers.Org().RemoveMember)
+			orgs.POST("/:id/invitations", r.handlers.Org().CreateInvitation)
+			orgs.GET("/:id/invitations", r.handlers.Org().ListInvitations)
+			orgs.DELETE("/:id/invitations/:iid", r.handlers.Org().RevokeInvitation)
+		}
+		protected.POST("/invitations/accept", limit("account"), r.handlers.Org().AcceptInvitation)
 
 		// Quiz routes
 		quizzes := protected.Group("/quizzes")
 		{
 			quizzes.POST("", r.handlers.Quiz().CreateQuiz)
 			quizzes.GET("/:id", r.handlers.Quiz().GetQuiz)
+			quizzes.POST("/:id/clone", r.handlers.Quiz().CloneQuiz)
+			quizzes.PUT("/:id/cloning", r.handlers.Quiz().SetCloning)
 			quizzes.POST("/:id/questions", r.handlers.Quiz().AddQuestion)
-			quizzes.POST("/:id/submit", r.handlers.Quiz().SubmitAnswer)
+			quizzes.PUT("/:id/questions/:qid", r.handlers.Quiz().UpdateQuestion)
+			quizzes.DELETE("/:id/questions/:qid", r.handlers.Quiz().DeleteQuestion)
+			quizzes.GET("/:id/versions/:version", r.handlers.Quiz().GetVersion)
+			quizzes.POST("/:id/questions/import", r.handlers.Import().ImportQuestions)
+			quizzes.GET("/:id/questions/:qid/stats", r.handlers.Stats().GetQuestionStats)
+			quizzes.GET("/:id/report", r.handlers.Stats().GetQuizReport)
+			quizzes.GET("/:id/export", r.handlers.Export().ExportResults)
+			quizzes.GET("/:id/bundle", r.handlers.Bundle().ExportBundle)
+			quizzes.POST("/import", r.handlers.Bundle().ImportBundle)
+			quizzes.POST("/:id/bank-items", r.handlers.Bank().AddToQuiz)
+			quizzes.GET("/:id/bank-items", r.handlers.Bank().ListQuizItems)
+			quizzes.POST("/:id/sessions", r.handlers.Session().CreateSession)
+			quizzes.GET("/:id/sessions", r.handlers.Session().ListSessions)
+			quizzes.PUT("/:id/schedule", r.handlers.Session().ScheduleSession)
+			quizzes.GET("/:id/mastery", r.handlers.Practice().GetMastery)
+			quizzes.POST("/:id/submit", throttle("submit"), r.handlers.Quiz().SubmitAnswer)
 			quizzes.GET("/:id/leaderboard", r.handlers.Quiz().GetLeaderboard)
-			quizzes.POST("/join", r.handlers.Quiz().JoinQuiz)
+			quizzes.POST("/join", limit("join"), r.handlers.Quiz().JoinQuiz)
+		}
+
+		// Session routes. The /quizzes/:id routes that play or report on a
+		// quiz address its default session.
+		sessions := protected.Group("/sessions")
+		{
+			sessions.POST("/join", limit("join"), r.handlers.Quiz().JoinQuiz)
+			sessions.GET("/:id", r.handlers.Session().GetSession)
+			sessions.PUT("/:id/schedule", r.handlers.Session().ScheduleSession)
+			sessions.POST("/:id/attempts", r.handlers.Attempt().StartAttempt)
+			sessions.GET("/:id/attempts/current", r.handlers.Attempt().CurrentAttempt)
+			sessions.GET("/:id/practice", r.handlers.Practice().GetPractice)
+			sessions.POST("/:id/submit", throttle("submit"), r.handlers.Quiz().SubmitAnswer)
+			sessions.GET("/:id/leaderboard", r.handlers.Quiz().GetLeaderboard)
+			sessions.GET("/:id/questions/:qid/stats", r.handlers.Stats().GetQuestionStats)
+			sessions.GET("/:id/report", r.handlers.Stats().GetQuizReport)
+			sessions.GET("/:id/export", r.handlers.Export().ExportResults)
+		}
+
+		// Question bank routes
+		bank := protected.Group("/bank/questions")
+		{
+			bank.POST("", r.handlers.Bank().CreateQuestion)
+			bank.GET("", r.handlers.Bank().SearchQuestions)
+			bank.GET("/:id", r.handlers.Bank().GetQuestion)
+			bank.PUT("/:id", r.handlers.Bank().UpdateQuestion)
+			bank.DELETE("/:id", r.handlers.Bank().DeleteQuestion)
 		}
 	}
 
+	// Public keys for other services to verify our tokens
+	r.engine.GET("/.well-known/jwks.json", r.handlers.Auth().JWKS)
+
 	// Health check
 	r.engine.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -81,3 +178,13 @@ func (r *Router) setupRoutes() {
 func (r *Router) Engine() *gin.Engine {
 	return r.engine
 }
+
+// RunScheduler runs scheduled sessions until ctx is done
+func (r *Router) RunScheduler(ctx context.Context) {
+	r.services.Scheduler().Run(ctx)
+}
+
+// RunKeyRotation keeps the token signing keys current until ctx is done
+func (r *Router) RunKeyRotation(ctx context.Context) {
+	r.services.Keys().Run(ctx)
+}
@@ -1,23 +1,32 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	JWT      JWTConfig      `yaml:"jwt"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Realtime  RealtimeConfig  `yaml:"realtime"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Mail      MailConfig      `yaml:"mail"`
+	Account   AccountConfig   `yaml:"account"`
+	OIDC      OIDCConfig      `yaml:"oidc"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
 }
 
 type ServerConfig struct {
-	Port int    `yaml:"port"`
-	Host string `yaml:"host"`
+	Port           int      `yaml:"port"`
+	Host           string   `yaml:"host"`
+	TrustedProxies []string `yaml:"trusted_proxies"` // Proxies whose X-Forwarded-For names the client; none by default
 }
 
 type DatabaseConfig struct {
@@ -36,9 +45,283 @@ type RedisConfig struct {
 	DB       int    `yaml:"db"`
 }
 
+// JWTConfig signs access tokens. Zero values fall back to the defaults
+// below.
+//
+// Tokens are signed with the last private key of keys, then of keys_dir in
+// kid order. With no private key, they are signed with the HS256 secret
+// unless algorithm or rotate_hours is set, or there is no secret, in which
+// case a key is generated.
 type JWTConfig struct {
-	Secret      string `yaml:"secret"`
-	ExpiryHours int    `yaml:"expiry_hours"`
+	Secret         string      `yaml:"secret"`           // HS256, still accepted once keys are in use
+	AccessMinutes  int         `yaml:"access_minutes"`   // Lifetime of an access token
+	RefreshDays    int         `yaml:"refresh_days"`     // Lifetime of a refresh token, renewed on each refresh
+	Algorithm      string      `yaml:"algorithm"`        // RS256 or EdDSA, for generated keys
+	Keys           []KeyConfig `yaml:"keys"`             // Inline keys, in order
+	KeysDir        string      `yaml:"keys_dir"`         // Holds <kid>.pem files; share it between replicas
+	RotateHours    int         `yaml:"rotate_hours"`     // Generate a new signing key this often, 0 to never rotate
+	KeyPollSeconds int         `yaml:"key_poll_seconds"` // How often keys_dir is reread and rotation checked
+}
+
+// KeyConfig is one key in PEM. A key with only a public key verifies
+// tokens but never signs them.
+type KeyConfig struct {
+	ID         string `yaml:"kid"`
+	PrivateKey string `yaml:"private_key"`
+	PublicKey  string `yaml:"public_key"`
+}
+
+// AccessTTL defaults to 15 minutes
+func (c *JWTConfig) AccessTTL() time.Duration {
+	if c.AccessMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.AccessMinutes) * time.Minute
+}
+
+// RefreshTTL defaults to 30 days
+func (c *JWTConfig) RefreshTTL() time.Duration {
+	if c.RefreshDays <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.RefreshDays) * 24 * time.Hour
+}
+
+// KeyAlgorithm defaults to RS256
+func (c *JWTConfig) KeyAlgorithm() string {
+	if c.Algorithm == "" {
+		return "RS256"
+	}
+	return c.Algorithm
+}
+
+// RotateInterval is zero when keys are not rotated
+func (c *JWTConfig) RotateInterval() time.Duration {
+	return time.Duration(c.RotateHours) * time.Hour
+}
+
+// ErrKeysDirRequired refuses generated keys that would only live in memory,
+// where a restart loses them and other replicas cannot verify their tokens
+var ErrKeysDirRequired = errors.New("jwt.keys_dir must be set to generate or rotate signing keys")
+
+// Validate requires keys_dir whenever signing keys are generated: when keys
+// rotate, or when no private key is configured and the secret does not sign
+func (c *JWTConfig) Validate() error {
+	hasPrivate := false
+	for _, kc := range c.Keys {
+		hasPrivate = hasPrivate || kc.PrivateKey != ""
+	}
+	generates := c.RotateHours > 0 || (!hasPrivate && (c.Secret == "" || c.Algorithm != ""))
+	if generates && c.KeysDir == "" {
+		return ErrKeysDirRequired
+	}
+	return nil
+}
+
+// KeyPollInterval defaults to one minute
+func (c *JWTConfig) KeyPollInterval() time.Duration {
+	if c.KeyPollSeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.KeyPollSeconds) * time.Second
+}
+
+// RealtimeConfig tunes the WebSocket transport. Limits are token buckets
+// by command type, with "*" counting every message a client sends; types
+// without one are not limited. A client refused more than disconnect_after
+// times in a minute is disconnected, or never with zero.
+type RealtimeConfig struct {
+	EnableCompression bool                    `yaml:"enable_compression"`
+	CompressionLevel  int                     `yaml:"compression_level"`
+	Limits            map[string]MessageLimit `yaml:"limits"`
+	DisconnectAfter   int                     `yaml:"disconnect_after"`
+}
+
+// MessageLimit limits one type of WebSocket message on each connection and
+// across each user's connections
+type MessageLimit struct {
+	PerConnection TokenBucket `yaml:"per_connection"`
+	PerUser       TokenBucket `yaml:"per_user"`
+}
+
+// TokenBucket allows burst at once, refilling rate tokens a second. A rate
+// of zero does not limit; without a burst, one second's worth may be taken
+// at once.
+type TokenBucket struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// SchedulerConfig tunes the scheduler that runs scheduled sessions. Zero
+// values fall back to the defaults below.
+type SchedulerConfig struct {
+	PollIntervalMs int `yaml:"poll_interval_ms"` // How often due sessions are checked
+	LockTTLSeconds int `yaml:"lock_ttl_seconds"` // How long a replica keeps a session after it stops renewing
+	RevealSeconds  int `yaml:"reveal_seconds"`   // How long the answer shows before the next question
+}
+
+// PollInterval defaults to one second
+func (c *SchedulerConfig) PollInterval() time.Duration {
+	if c.PollIntervalMs <= 0 {
+		return time.Second
+	}
+	return time.Duration(c.PollIntervalMs) * time.Millisecond
+}
+
+// LockTTL defaults to 15 seconds
+func (c *SchedulerConfig) LockTTL() time.Duration {
+	if c.LockTTLSeconds <= 0 {
+		return 15 * time.Second
+	}
+	return time.Duration(c.LockTTLSeconds) * time.Second
+}
+
+// RevealDelay defaults to 5 seconds
+func (c *SchedulerConfig) RevealDelay() time.Duration {
+	if c.RevealSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.RevealSeconds) * time.Second
+}
+
+// MailConfig picks how emails are sent: driver "smtp", or "log" to append
+// them to log_file, or to the log when it is unset, for local development
+// and tests. "log" is the default.
+type MailConfig struct {
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	LogFile  string `yaml:"log_file"`
+}
+
+// AccountConfig sets the links in account and invitation emails and how
+// long they work. Zero lifetimes fall back to the defaults below.
+type AccountConfig struct {
+	BaseURL      string `yaml:"base_url"`      // Front end that opens the links
+	VerifyHours  int    `yaml:"verify_hours"`  // Lifetime of an email verification link
+	ResetMinutes int    `yaml:"reset_minutes"` // Lifetime of a password reset link
+	InviteDays   int    `yaml:"invite_days"`   // Lifetime of an organisation invitation link
+}
+
+// VerifyTTL defaults to 48 hours
+func (c *AccountConfig) VerifyTTL() time.Duration {
+	if c.VerifyHours <= 0 {
+		return 48 * time.Hour
+	}
+	return time.Duration(c.VerifyHours) * time.Hour
+}
+
+// ResetTTL defaults to one hour
+func (c *AccountConfig) ResetTTL() time.Duration {
+	if c.ResetMinutes <= 0 {
+		return time.Hour
+	}
+	return time.Duration(c.ResetMinutes) * time.Minute
+}
+
+// InviteTTL defaults to a week
+func (c *AccountConfig) InviteTTL() time.Duration {
+	if c.InviteDays <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return time.Duration(c.InviteDays) * 24 * time.Hour
+}
+
+// OIDCConfig lists the OpenID Connect providers users may log in with,
+// alongside their password
+type OIDCConfig struct {
+	Providers    []OIDCProviderConfig `yaml:"providers"`
+	StateMinutes int                  `yaml:"state_minutes"` // How long a started login may take to come back
+}
+
+// OIDCProviderConfig is one provider, found through its issuer's discovery
+// document. Without a client secret the app logs in as a public client,
+// relying on PKCE alone.
+type OIDCProviderConfig struct {
+	Name         string   `yaml:"name"` // Used in the login URLs
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"` // Where the provider sends the user back with a code
+	Scopes       []string `yaml:"scopes"`       // Asked for besides openid; email and profile if unset
+}
+
+// StateTTL defaults to ten minutes
+func (c *OIDCConfig) StateTTL() time.Duration {
+	if c.StateMinutes <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(c.StateMinutes) * time.Minute
+}
+
+// RateLimitConfig limits how often hot endpoints can be called, by rule
+// name, and locks accounts out after repeated failed logins. Rules that are
+// not set, and limits of zero, do not limit.
+//
+// Throttles are per-user token buckets, by name, for endpoints called too
+// often to count in Redis, such as answer submissions. They are kept in
+// memory on each replica.
+type RateLimitConfig struct {
+	Rules     map[string]RateLimitRule `yaml:"rules"`
+	Throttles map[string]TokenBucket   `yaml:"throttles"`
+	Lockout   LockoutConfig            `yaml:"lockout"`
+}
+
+// RateLimitRule allows so many requests in any window of time, from one IP
+// and for one account: the logged-in user, or the email a request names
+type RateLimitRule struct {
+	PerIP         int `yaml:"per_ip"`
+	PerAccount    int `yaml:"per_account"`
+	WindowSeconds int `yaml:"window_seconds"`
+}
+
+// Window defaults to one minute
+func (r *RateLimitRule) Window() time.Duration {
+	if r.WindowSeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+// LockoutConfig locks an account after max_failures failed logins within
+// window_minutes. The first lock lasts lock_minutes and each further one
+// within a day twice as long, up to max_lock_minutes. Zero max_failures
+// never locks.
+type LockoutConfig struct {
+	MaxFailures    int `yaml:"max_failures"`
+	WindowMinutes  int `yaml:"window_minutes"`
+	LockMinutes    int `yaml:"lock_minutes"`
+	MaxLockMinutes int `yaml:"max_lock_minutes"`
+}
+
+// Window defaults to 15 minutes
+func (c *LockoutConfig) Window() time.Duration {
+	if c.WindowMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.WindowMinutes) * time.Minute
+}
+
+// LockFor is how long the nth lock in a row lasts: one minute by default,
+// doubling each time, up to an hour by default
+func (c *LockoutConfig) LockFor(n int) time.Duration {
+	lock, max := time.Minute, time.Hour
+	if c.LockMinutes > 0 {
+		lock = time.Duration(c.LockMinutes) * time.Minute
+	}
+	if c.MaxLockMinutes > 0 {
+		max = time.Duration(c.MaxLockMinutes) * time.Minute
+	}
+	for i := 1; i < n && lock < max; i++ {
+		lock *= 2
+	}
+	if lock > max {
+		return max
+	}
+	return lock
 }
 
 // DSN returns the PostgreSQL connection string
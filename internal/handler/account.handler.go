@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type AccountHandler interface {
+	RequestVerification(c *gin.Context)
+	VerifyEmail(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+}
+
+type accountHandler struct {
+	accountService service.AccountService
+}
+
+func NewAccountHandler(accountService service.AccountService) AccountHandler {
+	return &accountHandler{accountService: accountService}
+}
+
+// RequestVerification emails the current user a new verification link
+// POST /api/v1/auth/verify-email/resend
+func (h *accountHandler) RequestVerification(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	if err := h.accountService.RequestVerification(c.Request.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrEmailVerified):
+			response.Error(c, http.StatusConflict, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to send verification email", nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Verification email sent", nil)
+}
+
+// VerifyEmail completes verification with the token from the link
+// POST /api/v1/auth/verify-email
+func (h *accountHandler) VerifyEmail(c *gin.Context) {
+	var req service.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.accountService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidLink) {
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to verify email", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Email verified", nil)
+}
+
+// ForgotPassword emails a reset link to the address, if it has an account
+// POST /api/v1/auth/password/forgot
+func (h *accountHandler) ForgotPassword(c *gin.Context) {
+	var req service.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.accountService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to send reset email", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "If an account uses this address, a reset link is on its way", nil)
+}
+
+// ResetPassword sets a new password with the token from the link
+// POST /api/v1/auth/password/reset
+func (h *accountHandler) ResetPassword(c *gin.Context) {
+	var req service.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.accountService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidLink) {
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to reset password", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Password reset, please log in again", nil)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type AttemptHandler interface {
+	StartAttempt(c *gin.Context)
+	CurrentAttempt(c *gin.Context)
+}
+
+type attemptHandler struct {
+	attemptService service.AttemptService
+}
+
+func NewAttemptHandler(attemptService service.AttemptService) AttemptHandler {
+	return &attemptHandler{attemptService: attemptService}
+}
+
+// attemptError writes the response for errors shared by the attempt endpoints
+func attemptError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrSessionNotFound), errors.Is(err, service.ErrAttemptNotFound):
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrNotSelfPaced):
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, service.ErrOutsideWindow), errors.Is(err, service.ErrKicked):
+		response.Error(c, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, service.ErrNoAttemptsLeft), errors.Is(err, service.ErrAttemptConflict):
+		response.Error(c, http.StatusConflict, err.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
+
+// POST /api/v1/sessions/:id/attempts
+func (h *attemptHandler) StartAttempt(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	state, err := h.attemptService.StartAttempt(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		attemptError(c, err, "Failed to start attempt")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Attempt started", state)
+}
+
+// GET /api/v1/sessions/:id/attempts/current
+func (h *attemptHandler) CurrentAttempt(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	state, err := h.attemptService.CurrentAttempt(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		attemptError(c, err, "Failed to get attempt")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Attempt retrieved", state)
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type BankHandler interface {
+	CreateQuestion(c *gin.Context)
+	SearchQuestions(c *gin.Context)
+	GetQuestion(c *gin.Context)
+	UpdateQuestion(c *gin.Context)
+	DeleteQuestion(c *gin.Context)
+	AddToQuiz(c *gin.Context)
+	ListQuizItems(c *gin.Context)
+}
+
+type bankHandler struct {
+	bankService service.BankService
+}
+
+func NewBankHandler(bankService service.BankService) BankHandler {
+	return &bankHandler{bankService: bankService}
+}
+
+type BankQuestionRequest struct {
+	Text          string            `json:"text" binding:"required"`
+	Options       []string          `json:"options" binding:"required,min=2"`
+	CorrectAnswer string            `json:"correct_answer" binding:"required"`
+	Explanation   string            `json:"explanation" binding:"max=2000"`
+	TimeLimit     int               `json:"time_limit"`
+	Points        int               `json:"points"`
+	Difficulty    models.Difficulty `json:"difficulty"`
+	Tags          []string          `json:"tags"`
+}
+
+// BankItemRequest takes either question_id or draw
+type BankItemRequest struct {
+	Order      int     `json:"order"`
+	QuestionID *string `json:"question_id"`
+	Draw       *struct {
+		Count      int               `json:"count" binding:"required"`
+		Tags       []string          `json:"tags"`
+		Difficulty models.Difficulty `json:"difficulty"`
+	} `json:"draw"`
+}
+
+func (r BankQuestionRequest) input() service.BankQuestionInput {
+	return service.BankQuestionInput{
+		Text:          r.Text,
+		Options:       r.Options,
+		CorrectAnswer: r.CorrectAnswer,
+		Explanation:   r.Explanation,
+		TimeLimit:     r.TimeLimit,
+		Points:        r.Points,
+		Difficulty:    r.Difficulty,
+		Tags:          r.Tags,
+	}
+}
+
+// bankError writes the response for errors shared by the bank endpoints
+func bankError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrBankQuestionNotFound), errors.Is(err, service.ErrQuizNotFound):
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrNotAllowed), errors.Is(err, service.ErrBankNotShared):
+		response.Error(c, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, service.ErrQuizAlreadyStarted), errors.Is(err, service.ErrNotEnoughBankQuestions):
+		response.Error(c, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, service.ErrInvalidQuestion), errors.Is(err, service.ErrInvalidBankItem):
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
+
+// POST /api/v1/bank/questions
+func (h *bankHandler) CreateQuestion(c *gin.Context) {
+	var req BankQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	question, err := h.bankService.CreateQuestion(c.Request.Context(), userID, req.input())
+	if err != nil {
+		bankError(c, err, "Failed to create question")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Question created", question)
+}
+
+// GET /api/v1/bank/questions?q=&tags=a,b&difficulty=&limit=&offset=
+func (h *bankHandler) SearchQuestions(c *gin.Context) {
+	search := models.BankSearch{
+		OwnerID:    c.MustGet("userID").(uuid.UUID),
+		Query:      strings.TrimSpace(c.Query("q")),
+		Difficulty: models.Difficulty(strings.ToLower(c.Query("difficulty"))),
+	}
+	if tags := c.Query("tags"); tags != "" {
+		search.Tags = strings.Split(tags, ",")
+	}
+	search.Limit, _ = strconv.Atoi(c.Query("limit"))
+	search.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	questions, total, err := h.bankService.SearchQuestions(c.Request.Context(), search)
+	if err != nil {
+		bankError(c, err, "Failed to search questions")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Questions retrieved", gin.H{
+		"questions": questions,
+		"total":     total,
+	})
+}
+
+// GET /api/v1/bank/questions/:id
+func (h *bankHandler) GetQuestion(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid question ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	question, err := h.bankService.GetQuestion(c.Request.Context(), id, userID)
+	if err != nil {
+		bankError(c, err, "Failed to get question")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Question retrieved", question)
+}
+
+// PUT /api/v1/bank/questions/:id
+func (h *bankHandler) UpdateQuestion(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid question ID", nil)
+		return
+	}
+
+	var req BankQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	question, err := h.bankService.UpdateQuestion(c.Request.Context(), id, userID, req.input())
+	if err != nil {
+		bankError(c, err, "Failed to update question")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Question updated", question)
+}
+
+// DELETE /api/v1/bank/questions/:id
+func (h *bankHandler) DeleteQuestion(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid question ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	if err := h.bankService.DeleteQuestion(c.Request.Context(), id, userID); err != nil {
+		bankError(c, err, "Failed to delete question")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Question deleted", nil)
+}
+
+// POST /api/v1/quizzes/:id/bank-items
+func (h *bankHandler) AddToQuiz(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	var req BankItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	input := service.QuizBankItemInput{Order: req.Order}
+	if req.QuestionID != nil {
+		id, err := uuid.Parse(*req.QuestionID)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid question ID", nil)
+			return
+		}
+		input.BankQuestionID = &id
+	}
+	if req.Draw != nil {
+		input.DrawCount = req.Draw.Count
+		input.DrawTags = req.Draw.Tags
+		input.DrawDifficulty = models.Difficulty(strings.ToLower(string(req.Draw.Difficulty)))
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	item, err := h.bankService.AddToQuiz(c.Request.Context(), quizID, userID, input)
+	if err != nil {
+		bankError(c, err, "Failed to add bank item")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Bank item added", item)
+}
+
+// GET /api/v1/quizzes/:id/bank-items
+func (h *bankHandler) ListQuizItems(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	items, err := h.bankService.ListQuizItems(c.Request.Context(), quizID, userID)
+	if err != nil {
+		bankError(c, err, "Failed to list bank items")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Bank items retrieved", items)
+}
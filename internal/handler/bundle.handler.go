@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type BundleHandler interface {
+	ExportBundle(c *gin.Context)
+	ImportBundle(c *gin.Context)
+}
+
+type bundleHandler struct {
+	bundleService service.BundleService
+}
+
+func NewBundleHandler(bundleService service.BundleService) BundleHandler {
+	return &bundleHandler{bundleService: bundleService}
+}
+
+// GET /api/v1/quizzes/:id/bundle
+func (h *bundleHandler) ExportBundle(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	bundle, err := h.bundleService.ExportBundle(c.Request.Context(), quizID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrQuizNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to export quiz", nil)
+		}
+		return
+	}
+
+	// The bundle is the whole body so that the file can be imported as is
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="quiz-%s.json"`, quizID))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportBundle adds the quiz to the organisation named by ?org_id, or to
+// the user's personal one
+// POST /api/v1/quizzes/import
+func (h *bundleHandler) ImportBundle(c *gin.Context) {
+	var orgID uuid.UUID
+	if param := c.Query("org_id"); param != "" {
+		var err error
+		if orgID, err = uuid.Parse(param); err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid organisation ID", nil)
+			return
+		}
+	}
+
+	var bundle models.Bundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	quiz, err := h.bundleService.ImportBundle(c.Request.Context(), &bundle, userID, orgID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnsupportedBundle), errors.Is(err, service.ErrInvalidBundle):
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, service.ErrOrgNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to import quiz", nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Quiz imported", quiz)
+}
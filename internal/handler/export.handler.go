@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type ExportHandler interface {
+	ExportResults(c *gin.Context)
+}
+
+type exportHandler struct {
+	exportService service.ExportService
+}
+
+func NewExportHandler(exportService service.ExportService) ExportHandler {
+	return &exportHandler{exportService: exportService}
+}
+
+// GET /api/v1/quizzes/:id/export?format=csv|xlsx|json
+// GET /api/v1/sessions/:id/export?format=csv|xlsx|json
+func (h *exportHandler) ExportResults(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	format := service.ExportFormat(strings.ToLower(c.DefaultQuery("format", "csv")))
+	userID := c.MustGet("userID").(uuid.UUID)
+	export, err := h.exportService.OpenExport(c.Request.Context(), sessionID, userID, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnsupportedFormat):
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, service.ErrSessionNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to export results", nil)
+		}
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename()))
+	c.Status(http.StatusOK)
+
+	// The status is already sent, so a failure part way can only cut the
+	// download short
+	if err := export.WriteTo(c.Request.Context(), c.Writer); err != nil {
+		log.Printf("error exporting results of session %s: %v", sessionID, err)
+	}
+}
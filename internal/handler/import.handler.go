@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/importer"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+// Largest import file accepted
+const maxImportBytes = 5 << 20
+
+type ImportHandler interface {
+	ImportQuestions(c *gin.Context)
+}
+
+type importHandler struct {
+	importService service.ImportService
+}
+
+func NewImportHandler(importService service.ImportService) ImportHandler {
+	return &importHandler{importService: importService}
+}
+
+// POST /api/v1/quizzes/:id/questions/import?format=csv|json|gift|xml&dry_run=true
+//
+// The file is either the request body or the "file" field of a multipart
+// form, in which case the format may be left to its extension.
+func (h *importHandler) ImportQuestions(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	var (
+		source   io.Reader = c.Request.Body
+		filename string
+	)
+	if c.ContentType() == "multipart/form-data" {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "Multipart imports need a file field", nil)
+			return
+		}
+		defer file.Close()
+		source, filename = file, header.Filename
+	}
+
+	var format importer.Format
+	if name := c.Query("format"); name != "" {
+		format, err = importer.ParseFormat(name)
+	} else {
+		format, err = importer.FormatFromFilename(filename)
+	}
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	report, err := h.importService.ImportQuestions(c.Request.Context(), service.ImportQuestionsInput{
+		QuizID: quizID,
+		Format: format,
+		Source: source,
+		DryRun: dryRun,
+	}, userID)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			response.Error(c, http.StatusRequestEntityTooLarge, "Import file is too large", nil)
+		case errors.Is(err, service.ErrInvalidImport):
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, service.ErrQuizNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to import questions", nil)
+		}
+		return
+	}
+
+	switch {
+	case report.DryRun:
+		response.Success(c, http.StatusOK, "Import checked", report)
+	case report.Imported == 0:
+		response.Error(c, http.StatusUnprocessableEntity, "No questions could be imported", report)
+	default:
+		response.Success(c, http.StatusCreated, "Questions imported", report)
+	}
+}
@@ -7,22 +7,58 @@ import (
 // Handler is the interface for the handler manager
 type Handler interface {
 	Auth() AuthHandler
+	Account() AccountHandler
+	OIDC() OIDCHandler
+	Profile() ProfileHandler
+	Org() OrgHandler
 	Quiz() QuizHandler
+	Session() SessionHandler
+	Attempt() AttemptHandler
+	Practice() PracticeHandler
+	Stats() StatsHandler
+	Export() ExportHandler
+	Import() ImportHandler
+	Bundle() BundleHandler
+	Bank() BankHandler
 	Realtime() WebSocketHandler
 }
 
 // handlerImpl is the concrete implementation of Handler
 type handlerImpl struct {
 	auth     AuthHandler
+	account  AccountHandler
+	oidc     OIDCHandler
+	profile  ProfileHandler
+	org      OrgHandler
 	quiz     QuizHandler
+	session  SessionHandler
+	attempt  AttemptHandler
+	practice PracticeHandler
+	stats    StatsHandler
+	export   ExportHandler
+	imports  ImportHandler
+	bundle   BundleHandler
+	bank     BankHandler
 	realtime WebSocketHandler
 }
 
 // NewHandler creates a new instance of Handler
 func NewHandler(svc service.Service) Handler {
 	return &handlerImpl{
-		auth:     NewAuthHandler(svc.Auth()),
+		auth:     NewAuthHandler(svc.Auth(), svc.Keys()),
+		account:  NewAccountHandler(svc.Account()),
+		oidc:     NewOIDCHandler(svc.OIDC()),
+		profile:  NewProfileHandler(svc.Profile()),
+		org:      NewOrgHandler(svc.Org()),
 		quiz:     NewQuizHandler(svc.Quiz()),
+		session:  NewSessionHandler(svc.Session()),
+		attempt:  NewAttemptHandler(svc.Attempt()),
+		practice: NewPracticeHandler(svc.Practice()),
+		stats:    NewStatsHandler(svc.Stats()),
+		export:   NewExportHandler(svc.Export()),
+		imports:  NewImportHandler(svc.Import()),
+		bundle:   NewBundleHandler(svc.Bundle()),
+		bank:     NewBankHandler(svc.Bank()),
 		realtime: NewWebSocketHandler(svc.Realtime()),
 	}
 }
@@ -31,10 +67,58 @@ func (h *handlerImpl) Auth() AuthHandler {
 	return h.auth
 }
 
+func (h *handlerImpl) Account() AccountHandler {
+	return h.account
+}
+
+func (h *handlerImpl) OIDC() OIDCHandler {
+	return h.oidc
+}
+
+func (h *handlerImpl) Profile() ProfileHandler {
+	return h.profile
+}
+
+func (h *handlerImpl) Org() OrgHandler {
+	return h.org
+}
+
 func (h *handlerImpl) Quiz() QuizHandler {
 	return h.quiz
 }
 
+func (h *handlerImpl) Session() SessionHandler {
+	return h.session
+}
+
+func (h *handlerImpl) Attempt() AttemptHandler {
+	return h.attempt
+}
+
+func (h *handlerImpl) Practice() PracticeHandler {
+	return h.practice
+}
+
+func (h *handlerImpl) Stats() StatsHandler {
+	return h.stats
+}
+
+func (h *handlerImpl) Export() ExportHandler {
+	return h.export
+}
+
+func (h *handlerImpl) Import() ImportHandler {
+	return h.imports
+}
+
+func (h *handlerImpl) Bundle() BundleHandler {
+	return h.bundle
+}
+
+func (h *handlerImpl) Bank() BankHandler {
+	return h.bank
+}
+
 func (h *handlerImpl) Realtime() WebSocketHandler {
 	return h.realtime
 }
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type OIDCHandler interface {
+	ListProviders(c *gin.Context)
+	Login(c *gin.Context)
+	Callback(c *gin.Context)
+}
+
+type oidcHandler struct {
+	oidcService service.OIDCService
+}
+
+func NewOIDCHandler(oidcService service.OIDCService) OIDCHandler {
+	return &oidcHandler{oidcService: oidcService}
+}
+
+// ListProviders lists the providers users can log in with
+// GET /api/v1/auth/oidc/providers
+func (h *oidcHandler) ListProviders(c *gin.Context) {
+	response.Success(c, http.StatusOK, "Login providers retrieved", h.oidcService.Providers())
+}
+
+// Login sends the user to the provider to log in
+// GET /api/v1/auth/oidc/:provider/login
+func (h *oidcHandler) Login(c *gin.Context) {
+	authURL, err := h.oidcService.Begin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownProvider):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrProviderUnavailable):
+			response.Error(c, http.StatusBadGateway, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to start login", nil)
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback finishes the login with the code the provider sent the user back
+// with, and answers like a password login
+// GET /api/v1/auth/oidc/:provider/callback
+func (h *oidcHandler) Callback(c *gin.Context) {
+	var req service.OIDCCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+	if req.Error != "" {
+		response.Error(c, http.StatusUnauthorized, "Login was not completed", gin.H{
+			"error":             req.Error,
+			"error_description": req.ErrorDescription,
+		})
+		return
+	}
+	if req.Code == "" || req.State == "" {
+		response.Error(c, http.StatusBadRequest, "Invalid request", "code and state are required")
+		return
+	}
+
+	pair, user, err := h.oidcService.Complete(c.Request.Context(), c.Param("provider"), req.Code, req.State)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownProvider):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrInvalidOIDCState):
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, service.ErrOIDCLoginFailed):
+			response.Error(c, http.StatusUnauthorized, err.Error(), nil)
+		case errors.Is(err, service.ErrOIDCEmailUnverified):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		case errors.Is(err, service.ErrProviderUnavailable):
+			response.Error(c, http.StatusBadGateway, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to login", nil)
+		}
+		return
+	}
+
+	loginSuccess(c, pair, user)
+}
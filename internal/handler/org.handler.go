@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type OrgHandler interface {
+	CreateOrg(c *gin.Context)
+	ListOrgs(c *gin.Context)
+	RenameOrg(c *gin.Context)
+	ListQuizzes(c *gin.Context)
+	ListMembers(c *gin.Context)
+	SetRole(c *gin.Context)
+	RemoveMember(c *gin.Context)
+	CreateInvitation(c *gin.Context)
+	ListInvitations(c *gin.Context)
+	RevokeInvitation(c *gin.Context)
+	AcceptInvitation(c *gin.Context)
+}
+
+type orgHandler struct {
+	orgService service.OrgService
+}
+
+func NewOrgHandler(orgService service.OrgService) OrgHandler {
+	return &orgHandler{orgService: orgService}
+}
+
+type OrgRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type RoleRequest struct {
+	Role models.OrgRole `json:"role" binding:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// POST /api/v1/orgs
+func (h *orgHandler) CreateOrg(c *gin.Context) {
+	var req OrgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	org, err := h.orgService.CreateOrg(c.Request.Context(), c.MustGet("userID").(uuid.UUID), req.Name)
+	if err != nil {
+		orgError(c, err, "Failed to create organisation")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Organisation created", org)
+}
+
+// ListOrgs returns the current user's memberships with their organisations
+// GET /api/v1/orgs
+func (h *orgHandler) ListOrgs(c *gin.Context) {
+	memberships, err := h.orgService.ListOrgs(c.Request.Context(), c.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		orgError(c, err, "Failed to list organisations")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organisations retrieved", memberships)
+}
+
+// PATCH /api/v1/orgs/:id
+func (h *orgHandler) RenameOrg(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req OrgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	org, err := h.orgService.RenameOrg(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID), req.Name)
+	if err != nil {
+		orgError(c, err, "Failed to rename organisation")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organisation renamed", org)
+}
+
+// GET /api/v1/orgs/:id/quizzes
+func (h *orgHandler) ListQuizzes(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	quizzes, err := h.orgService.ListQuizzes(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		orgError(c, err, "Failed to list quizzes")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Quizzes retrieved", quizzes)
+}
+
+// GET /api/v1/orgs/:id/members
+func (h *orgHandler) ListMembers(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.orgService.ListMembers(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		orgError(c, err, "Failed to list members")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Members retrieved", members)
+}
+
+// PUT /api/v1/orgs/:id/members/:uid
+func (h *orgHandler) SetRole(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	membership, err := h.orgService.SetRole(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID), memberID, req.Role)
+	if err != nil {
+		orgError(c, err, "Failed to change role")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Role changed", membership)
+}
+
+// RemoveMember removes a member, or lets the current user leave
+// DELETE /api/v1/orgs/:id/members/:uid
+func (h *orgHandler) RemoveMember(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	if err := h.orgService.RemoveMember(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID), memberID); err != nil {
+		orgError(c, err, "Failed to remove member")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Member removed", nil)
+}
+
+// POST /api/v1/orgs/:id/invitations
+func (h *orgHandler) CreateInvitation(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req service.InvitationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	invitation, err := h.orgService.CreateInvitation(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID), req)
+	if err != nil {
+		orgError(c, err, "Failed to create invitation")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Invitation created", invitation)
+}
+
+// ListInvitations returns the invitations that can still be accepted
+// GET /api/v1/orgs/:id/invitations
+func (h *orgHandler) ListInvitations(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.orgService.ListInvitations(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		orgError(c, err, "Failed to list invitations")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitations retrieved", invitations)
+}
+
+// DELETE /api/v1/orgs/:id/invitations/:iid
+func (h *orgHandler) RevokeInvitation(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+	invitationID, err := uuid.Parse(c.Param("iid"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid invitation ID", nil)
+		return
+	}
+
+	if err := h.orgService.RevokeInvitation(c.Request.Context(), orgID, c.MustGet("userID").(uuid.UUID), invitationID); err != nil {
+		orgError(c, err, "Failed to revoke invitation")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitation revoked", nil)
+}
+
+// AcceptInvitation makes the current user a member with the invitation's
+// role
+// POST /api/v1/invitations/accept
+func (h *orgHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	membership, err := h.orgService.AcceptInvitation(c.Request.Context(), c.MustGet("userID").(uuid.UUID), req.Token)
+	if err != nil {
+		orgError(c, err, "Failed to accept invitation")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitation accepted", membership)
+}
+
+func orgIDParam(c *gin.Context) (uuid.UUID, bool) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid organisation ID", nil)
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
+
+// orgError writes the response for errors shared by the organisation
+// endpoints
+func orgError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrOrgNotFound), errors.Is(err, service.ErrMemberNotFound), errors.Is(err, service.ErrInvitationNotFound):
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrNotOrgAdmin), errors.Is(err, service.ErrNotAllowed), errors.Is(err, service.ErrInvitationEmail):
+		response.Error(c, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, service.ErrInvalidOrgName), errors.Is(err, service.ErrInvalidRole), errors.Is(err, service.ErrInvalidLink):
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, service.ErrLastAdmin), errors.Is(err, service.ErrAlreadyMember):
+		response.Error(c, http.StatusConflict, err.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
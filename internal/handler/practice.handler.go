@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type PracticeHandler interface {
+	GetPractice(c *gin.Context)
+	GetMastery(c *gin.Context)
+}
+
+type practiceHandler struct {
+	practiceService service.PracticeService
+}
+
+func NewPracticeHandler(practiceService service.PracticeService) PracticeHandler {
+	return &practiceHandler{practiceService: practiceService}
+}
+
+// GET /api/v1/sessions/:id/practice
+func (h *practiceHandler) GetPractice(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	state, err := h.practiceService.GetPractice(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSessionNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotPractice):
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, service.ErrKicked):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to get practice", nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Practice retrieved", state)
+}
+
+// GET /api/v1/quizzes/:id/mastery
+func (h *practiceHandler) GetMastery(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	mastery, err := h.practiceService.GetMastery(c.Request.Context(), quizID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrQuizNotFound) {
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to get mastery", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Mastery retrieved", mastery)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type ProfileHandler interface {
+	GetProfile(c *gin.Context)
+	UpdateProfile(c *gin.Context)
+	ChangeEmail(c *gin.Context)
+	ChangePassword(c *gin.Context)
+	DeleteAccount(c *gin.Context)
+	ExportData(c *gin.Context)
+}
+
+type profileHandler struct {
+	profileService service.ProfileService
+}
+
+func NewProfileHandler(profileService service.ProfileService) ProfileHandler {
+	return &profileHandler{profileService: profileService}
+}
+
+// GetProfile returns the current user's profile
+// GET /api/v1/users/me
+func (h *profileHandler) GetProfile(c *gin.Context) {
+	user, err := h.profileService.GetProfile(c.Request.Context(), c.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		profileError(c, err, "Failed to get profile")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Profile retrieved", user)
+}
+
+// UpdateProfile changes the username, display name or avatar
+// PATCH /api/v1/users/me
+func (h *profileHandler) UpdateProfile(c *gin.Context) {
+	var req service.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	user, err := h.profileService.UpdateProfile(c.Request.Context(), c.MustGet("userID").(uuid.UUID), req)
+	if err != nil {
+		profileError(c, err, "Failed to update profile")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Profile updated", user)
+}
+
+// ChangeEmail moves the account to a new address, to be verified
+// PUT /api/v1/users/me/email
+func (h *profileHandler) ChangeEmail(c *gin.Context) {
+	var req service.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	user, err := h.profileService.ChangeEmail(c.Request.Context(), c.MustGet("userID").(uuid.UUID), req)
+	if err != nil {
+		profileError(c, err, "Failed to change email")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Email changed, check your inbox to verify it", user)
+}
+
+// ChangePassword sets a new password, ending every other login
+// PUT /api/v1/users/me/password
+func (h *profileHandler) ChangePassword(c *gin.Context) {
+	var req service.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	pair, err := h.profileService.ChangePassword(c.Request.Context(), c.MustGet("userID").(uuid.UUID), req)
+	if err != nil {
+		profileError(c, err, "Failed to change password")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Password changed", pair)
+}
+
+// DeleteAccount anonymises the current user
+// DELETE /api/v1/users/me
+func (h *profileHandler) DeleteAccount(c *gin.Context) {
+	var req service.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.profileService.DeleteAccount(c.Request.Context(), c.MustGet("userID").(uuid.UUID), req); err != nil {
+		profileError(c, err, "Failed to delete account")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Account deleted", nil)
+}
+
+// ExportData downloads everything kept about the current user as JSON
+// GET /api/v1/users/me/export
+func (h *profileHandler) ExportData(c *gin.Context) {
+	data, err := h.profileService.ExportData(c.Request.Context(), c.MustGet("userID").(uuid.UUID))
+	if err != nil {
+		profileError(c, err, "Failed to export data")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="personal-data.json"`)
+	c.JSON(http.StatusOK, data)
+}
+
+// profileError writes the response for errors shared by the profile endpoints
+func profileError(c *gin.Context, err error, fallback string) {
+	var locked *service.LockoutError
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrWrongPassword):
+		response.Error(c, http.StatusUnauthorized, err.Error(), nil)
+	case errors.Is(err, service.ErrPasswordNotSet):
+		response.Error(c, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, service.ErrInvalidUsername), errors.Is(err, service.ErrInvalidAvatar):
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, service.ErrEmailExists):
+		response.Error(c, http.StatusConflict, "Email already exists", nil)
+	case errors.Is(err, service.ErrUsernameExists):
+		response.Error(c, http.StatusConflict, "Username already exists", nil)
+	case errors.Is(err, service.ErrLastAdmin):
+		response.Error(c, http.StatusConflict, err.Error(), nil)
+	case errors.As(err, &locked):
+		response.TooManyRequests(c, err.Error(), locked.RetryAfter)
+	default:
+		response.Error(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
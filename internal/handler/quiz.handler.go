@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,7 +14,12 @@ import (
 type QuizHandler interface {
 	CreateQuiz(c *gin.Context)
 	AddQuestion(c *gin.Context)
+	UpdateQuestion(c *gin.Context)
+	DeleteQuestion(c *gin.Context)
+	GetVersion(c *gin.Context)
 	GetQuiz(c *gin.Context)
+	CloneQuiz(c *gin.Context)
+	SetCloning(c *gin.Context)
 	JoinQuiz(c *gin.Context)
 	SubmitAnswer(c *gin.Context)
 	GetLeaderboard(c *gin.Context)
@@ -26,20 +33,35 @@ func NewQuizHandler(quizService service.QuizService) QuizHandler {
 	return &quizHandler{quizService: quizService}
 }
 
+// CreateQuizRequest adds the quiz to the user's personal organisation
+// unless it names another
 type CreateQuizRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
+	Title       string    `json:"title" binding:"required"`
+	Description string    `json:"description"`
+	OrgID       uuid.UUID `json:"org_id"`
 }
 
 type AddQuestionRequest struct {
 	Text          string   `json:"text" binding:"required"`
 	Options       []string `json:"options" binding:"required,min=2"`
 	CorrectAnswer string   `json:"correct_answer" binding:"required"`
+	Explanation   string   `json:"explanation" binding:"max=2000"`
 	TimeLimit     int      `json:"time_limit"`
 	Points        int      `json:"points"`
 	Order         int      `json:"order"`
 }
 
+// CloneQuizRequest is optional; the clone keeps the original title and goes
+// to the user's personal organisation by default
+type CloneQuizRequest struct {
+	Title string    `json:"title"`
+	OrgID uuid.UUID `json:"org_id"`
+}
+
+type CloningRequest struct {
+	AllowClone *bool `json:"allow_clone" binding:"required"`
+}
+
 type JoinQuizRequest struct {
 	Code string `json:"code" binding:"required,len=6"`
 }
@@ -58,9 +80,16 @@ func (h *quizHandler) CreateQuiz(c *gin.Context) {
 	}
 
 	userID := c.MustGet("userID").(uuid.UUID)
-	quiz, err := h.quizService.CreateQuiz(c.Request.Context(), req.Title, req.Description, userID)
+	quiz, err := h.quizService.CreateQuiz(c.Request.Context(), req.Title, req.Description, userID, req.OrgID)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to create quiz", nil)
+		switch {
+		case errors.Is(err, service.ErrOrgNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to create quiz", nil)
+		}
 		return
 	}
 
@@ -81,23 +110,121 @@ func (h *quizHandler) AddQuestion(c *gin.Context) {
 		return
 	}
 
-	input := service.AddQuestionInput{
-		QuizID:        quizID,
-		Text:          req.Text,
-		Options:       req.Options,
-		CorrectAnswer: req.CorrectAnswer,
-		TimeLimit:     req.TimeLimit,
-		Points:        req.Points,
-		Order:         req.Order,
+	userID := c.MustGet("userID").(uuid.UUID)
+	question, err := h.quizService.AddQuestion(c.Request.Context(), req.input(quizID, userID))
+	if err != nil {
+		questionError(c, err, "Failed to add question")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Question added", question)
+}
+
+// PUT /api/v1/quizzes/:id/questions/:qid
+func (h *quizHandler) UpdateQuestion(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+	questionID, err := uuid.Parse(c.Param("qid"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid question ID", nil)
+		return
 	}
 
-	question, err := h.quizService.AddQuestion(c.Request.Context(), input)
+	var req AddQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	question, err := h.quizService.UpdateQuestion(c.Request.Context(), questionID, req.input(quizID, userID))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to add question", nil)
+		questionError(c, err, "Failed to update question")
 		return
 	}
 
-	response.Success(c, http.StatusCreated, "Question added", question)
+	response.Success(c, http.StatusOK, "Question updated", question)
+}
+
+// DELETE /api/v1/quizzes/:id/questions/:qid
+func (h *quizHandler) DeleteQuestion(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+	questionID, err := uuid.Parse(c.Param("qid"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid question ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	if err := h.quizService.DeleteQuestion(c.Request.Context(), quizID, questionID, userID); err != nil {
+		questionError(c, err, "Failed to delete question")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Question deleted", nil)
+}
+
+// GET /api/v1/quizzes/:id/versions/:version
+func (h *quizHandler) GetVersion(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid version", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	questions, err := h.quizService.GetVersion(c.Request.Context(), quizID, userID, version)
+	if err != nil {
+		questionError(c, err, "Failed to get quiz version")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Quiz version retrieved", gin.H{
+		"version":   version,
+		"questions": questions,
+	})
+}
+
+func (r AddQuestionRequest) input(quizID, userID uuid.UUID) service.AddQuestionInput {
+	return service.AddQuestionInput{
+		QuizID:        quizID,
+		UserID:        userID,
+		Text:          r.Text,
+		Options:       r.Options,
+		CorrectAnswer: r.CorrectAnswer,
+		Explanation:   r.Explanation,
+		TimeLimit:     r.TimeLimit,
+		Points:        r.Points,
+		Order:         r.Order,
+	}
+}
+
+// questionError writes the response for errors from editing questions
+func questionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidQuestion):
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, service.ErrNotAllowed):
+		response.Error(c, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, service.ErrQuizNotFound), errors.Is(err, service.ErrQuestionNotFound), errors.Is(err, service.ErrVersionNotFound):
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrOldRevision):
+		response.Error(c, http.StatusConflict, err.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, fallback, nil)
+	}
 }
 
 // GET /api/v1/quizzes/:id
@@ -108,8 +235,13 @@ func (h *quizHandler) GetQuiz(c *gin.Context) {
 		return
 	}
 
-	quiz, err := h.quizService.GetQuiz(c.Request.Context(), quizID)
+	userID := c.MustGet("userID").(uuid.UUID)
+	quiz, err := h.quizService.GetQuiz(c.Request.Context(), quizID, userID)
 	if err != nil {
+		if errors.Is(err, service.ErrNotAllowed) {
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+			return
+		}
 		response.Error(c, http.StatusNotFound, "Quiz not found", nil)
 		return
 	}
@@ -117,7 +249,74 @@ func (h *quizHandler) GetQuiz(c *gin.Context) {
 	response.Success(c, http.StatusOK, "Quiz info retrieved", quiz)
 }
 
+// POST /api/v1/quizzes/:id/clone
+func (h *quizHandler) CloneQuiz(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	var req CloneQuizRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	quiz, err := h.quizService.CloneQuiz(c.Request.Context(), quizID, userID, req.OrgID, req.Title)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrQuizNotFound), errors.Is(err, service.ErrOrgNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrCloneNotAllowed), errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		case errors.Is(err, service.ErrCloneBankItems):
+			response.Error(c, http.StatusConflict, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to clone quiz", nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Quiz cloned", quiz)
+}
+
+// PUT /api/v1/quizzes/:id/cloning
+func (h *quizHandler) SetCloning(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	var req CloningRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	quiz, err := h.quizService.SetAllowClone(c.Request.Context(), quizID, userID, *req.AllowClone)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrQuizNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to update quiz", nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Quiz updated", quiz)
+}
+
 // POST /api/v1/quizzes/join
+// POST /api/v1/sessions/join
 func (h *quizHandler) JoinQuiz(c *gin.Context) {
 	var req JoinQuizRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -125,18 +324,27 @@ func (h *quizHandler) JoinQuiz(c *gin.Context) {
 		return
 	}
 
-	quiz, err := h.quizService.JoinQuiz(c.Request.Context(), req.Code)
+	userID := c.MustGet("userID").(uuid.UUID)
+	session, err := h.quizService.JoinQuiz(c.Request.Context(), req.Code, userID)
 	if err != nil {
-		response.Error(c, http.StatusNotFound, "Quiz not found", nil)
+		switch {
+		case errors.Is(err, service.ErrLobbyLocked), errors.Is(err, service.ErrLobbyNotOpen), errors.Is(err, service.ErrKicked):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusNotFound, "Quiz not found", nil)
+		}
 		return
 	}
 
-	response.Success(c, http.StatusOK, "Joined quiz successfully", quiz)
+	// The session's ID is the one to play with; for a quiz's default
+	// session it is the quiz's
+	response.Success(c, http.StatusOK, "Joined quiz successfully", session)
 }
 
 // POST /api/v1/quizzes/:id/submit
+// POST /api/v1/sessions/:id/submit
 func (h *quizHandler) SubmitAnswer(c *gin.Context) {
-	quizID, err := uuid.Parse(c.Param("id"))
+	sessionID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
 		return
@@ -157,7 +365,7 @@ func (h *quizHandler) SubmitAnswer(c *gin.Context) {
 	}
 
 	input := service.SubmitAnswerInput{
-		QuizID:     quizID,
+		SessionID:  sessionID,
 		QuestionID: questionID,
 		UserID:     userID,
 		Answer:     req.Answer,
@@ -165,9 +373,21 @@ func (h *quizHandler) SubmitAnswer(c *gin.Context) {
 
 	answer, err := h.quizService.SubmitAnswer(c.Request.Context(), input)
 	if err != nil {
-		if err == service.ErrAlreadyAnswered {
+		switch {
+		case errors.Is(err, service.ErrAlreadyAnswered),
+			errors.Is(err, service.ErrQuestionClosed),
+			errors.Is(err, service.ErrQuizNotActive),
+			errors.Is(err, service.ErrAttemptNotFound),
+			errors.Is(err, service.ErrAttemptFinished),
+			errors.Is(err, service.ErrNotCurrentAnswer):
 			response.Error(c, http.StatusConflict, err.Error(), nil)
 			return
+		case errors.Is(err, service.ErrKicked), errors.Is(err, service.ErrOutsideWindow):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+			return
+		case errors.Is(err, service.ErrQuestionNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+			return
 		}
 		response.Error(c, http.StatusInternalServerError, "Failed to submit answer", nil)
 		return
@@ -177,15 +397,21 @@ func (h *quizHandler) SubmitAnswer(c *gin.Context) {
 }
 
 // GET /api/v1/quizzes/:id/leaderboard
+// GET /api/v1/sessions/:id/leaderboard
 func (h *quizHandler) GetLeaderboard(c *gin.Context) {
-	quizID, err := uuid.Parse(c.Param("id"))
+	sessionID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
 		return
 	}
 
-	leaderboard, err := h.quizService.GetLeaderboard(c.Request.Context(), quizID)
+	userID := c.MustGet("userID").(uuid.UUID)
+	leaderboard, err := h.quizService.GetLeaderboard(c.Request.Context(), sessionID, userID)
 	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to get leaderboard", nil)
 		return
 	}
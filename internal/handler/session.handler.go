@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type SessionHandler interface {
+	CreateSession(c *gin.Context)
+	ListSessions(c *gin.Context)
+	GetSession(c *gin.Context)
+	ScheduleSession(c *gin.Context)
+}
+
+type sessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) SessionHandler {
+	return &sessionHandler{sessionService: sessionService}
+}
+
+// CreateSessionRequest is optional; the label tells sessions apart, e.g. by
+// class
+type CreateSessionRequest struct {
+	Label string `json:"label" binding:"max=100"`
+	Mode  string `json:"mode" binding:"omitempty,oneof=LIVE SELF_PACED PRACTICE"`
+	ScheduleRequest
+	// SELF_PACED only: when attempts can be made, both optional, and how
+	// many each player gets (1 unless set, 0 for no limit)
+	OpensAt     *time.Time `json:"opens_at"`
+	ClosesAt    *time.Time `json:"closes_at"`
+	MaxAttempts *int       `json:"max_attempts" binding:"omitempty,min=0,max=100"`
+}
+
+// ScheduleRequest starts the session by itself at scheduled_at, with the
+// lobby open lobby_minutes before (5 unless set). A null scheduled_at
+// clears the schedule.
+type ScheduleRequest struct {
+	ScheduledAt  *time.Time `json:"scheduled_at"`
+	LobbyMinutes *int       `json:"lobby_minutes" binding:"omitempty,min=0,max=1440"`
+}
+
+func (r ScheduleRequest) input() service.ScheduleInput {
+	return service.ScheduleInput{ScheduledAt: r.ScheduledAt, LobbyMinutes: r.LobbyMinutes}
+}
+
+// sessionError writes the response for errors shared by the session endpoints
+func sessionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrQuizNotFound), errors.Is(err, service.ErrSessionNotFound):
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrNotAllowed):
+		response.Error(c, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, service.ErrScheduleInPast), errors.Is(err, service.ErrInvalidWindow),
+		errors.Is(err, service.ErrUnhostedStart), errors.Is(err, service.ErrNoQuestions):
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, service.ErrQuizAlreadyStarted):
+		response.Error(c, http.StatusConflict, err.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
+
+// POST /api/v1/quizzes/:id/sessions
+func (h *sessionHandler) CreateSession(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	var req CreateSessionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	session, err := h.sessionService.CreateSession(c.Request.Context(), quizID, userID, service.CreateSessionInput{
+		Label:         req.Label,
+		Mode:          models.SessionMode(req.Mode),
+		ScheduleInput: req.input(),
+		OpensAt:       req.OpensAt,
+		ClosesAt:      req.ClosesAt,
+		MaxAttempts:   req.MaxAttempts,
+	})
+	if err != nil {
+		sessionError(c, err, "Failed to create session")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Session created", session)
+}
+
+// GET /api/v1/quizzes/:id/sessions
+func (h *sessionHandler) ListSessions(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	sessions, err := h.sessionService.ListSessions(c.Request.Context(), quizID, userID)
+	if err != nil {
+		sessionError(c, err, "Failed to list sessions")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Sessions retrieved", sessions)
+}
+
+// GET /api/v1/sessions/:id
+func (h *sessionHandler) GetSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	session, err := h.sessionService.GetSession(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		sessionError(c, err, "Failed to get session")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Session retrieved", session)
+}
+
+// PUT /api/v1/sessions/:id/schedule
+// PUT /api/v1/quizzes/:id/schedule
+func (h *sessionHandler) ScheduleSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	session, err := h.sessionService.Schedule(c.Request.Context(), sessionID, userID, req.input())
+	if err != nil {
+		sessionError(c, err, "Failed to schedule session")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Session scheduled", session)
+}
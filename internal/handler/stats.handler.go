@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+type StatsHandler interface {
+	GetQuestionStats(c *gin.Context)
+	GetQuizReport(c *gin.Context)
+}
+
+type statsHandler struct {
+	statsService service.StatsService
+}
+
+func NewStatsHandler(statsService service.StatsService) StatsHandler {
+	return &statsHandler{statsService: statsService}
+}
+
+// GET /api/v1/quizzes/:id/questions/:qid/stats
+// GET /api/v1/sessions/:id/questions/:qid/stats
+func (h *statsHandler) GetQuestionStats(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+	questionID, err := uuid.Parse(c.Param("qid"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid question ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	stats, err := h.statsService.GetQuestionStatsForMember(c.Request.Context(), sessionID, questionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSessionNotFound), errors.Is(err, service.ErrQuestionNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to get question stats", nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Question stats retrieved", stats)
+}
+
+// GET /api/v1/quizzes/:id/report
+// GET /api/v1/sessions/:id/report
+func (h *statsHandler) GetQuizReport(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid quiz ID", nil)
+		return
+	}
+
+	userID := c.MustGet("userID").(uuid.UUID)
+	report, err := h.statsService.GetQuizReport(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSessionNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, service.ErrNotAllowed):
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to build quiz report", nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Quiz report retrieved", report)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/nguyen1302/realtime-quiz/internal/models"
 	"github.com/nguyen1302/realtime-quiz/internal/service"
 	"github.com/nguyen1302/realtime-quiz/pkg/response"
 
@@ -13,15 +14,19 @@ import (
 type AuthHandler interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
 	GetMe(c *gin.Context)
+	JWKS(c *gin.Context)
 }
 
 type authHandler struct {
 	authService service.AuthService
+	keyService  service.KeyService
 }
 
-func NewAuthHandler(authService service.AuthService) AuthHandler {
-	return &authHandler{authService: authService}
+func NewAuthHandler(authService service.AuthService, keyService service.KeyService) AuthHandler {
+	return &authHandler{authService: authService, keyService: keyService}
 }
 
 // Register handles user registration
@@ -62,26 +67,72 @@ func (h *authHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.authService.Login(c.Request.Context(), req)
+	pair, user, err := h.authService.Login(c.Request.Context(), req)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidCredentials) {
+		var locked *service.LockoutError
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
 			response.Error(c, http.StatusUnauthorized, "Invalid email or password", nil)
-			return
+		case errors.As(err, &locked):
+			response.TooManyRequests(c, err.Error(), locked.RetryAfter)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to login", nil)
 		}
-		response.Error(c, http.StatusInternalServerError, "Failed to login", nil)
 		return
 	}
 
+	loginSuccess(c, pair, user)
+}
+
+// loginSuccess answers any kind of login with the new tokens and the user
+func loginSuccess(c *gin.Context, pair *service.TokenPair, user *models.User) {
 	response.Success(c, http.StatusOK, "Login successful", gin.H{
-		"token": token,
+		"token":         pair.AccessToken,
+		"expires_at":    pair.ExpiresAt,
+		"refresh_token": pair.RefreshToken,
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": user.EmailVerifiedAt != nil,
 		},
 	})
 }
 
+// Refresh exchanges a refresh token for a new token pair
+// POST /api/v1/auth/refresh
+func (h *authHandler) Refresh(c *gin.Context) {
+	var req service.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	pair, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefresh) || errors.Is(err, service.ErrRefreshReused) {
+			response.Error(c, http.StatusUnauthorized, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to refresh token", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Token refreshed", pair)
+}
+
+// Logout revokes the current access token and its refresh token
+// POST /api/v1/auth/logout
+func (h *authHandler) Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*service.Claims)
+	if err := h.authService.Logout(c.Request.Context(), claims); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to logout", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Logged out", nil)
+}
+
 // GetMe returns current authenticated user info
 // GET /api/v1/auth/me
 func (h *authHandler) GetMe(c *gin.Context) {
@@ -98,3 +149,11 @@ func (h *authHandler) GetMe(c *gin.Context) {
 		"email":    userClaims.Email,
 	})
 }
+
+// JWKS publishes the public keys that verify access tokens, in the standard
+// JWK Set form rather than the API envelope, for other services to fetch
+// GET /.well-known/jwks.json
+func (h *authHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keyService.JWKS())
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseCSV reads a spreadsheet with a header row. Columns are matched by
+// name: text and correct_answer are required, options come from an options
+// column separated by "|" and/or one column per option (option_1,
+// option_2, ...), and time_limit and points are optional.
+func parseCSV(r io.Reader) ([]Item, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	var optionColumns []int
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+		if strings.HasPrefix(name, "option") && name != "options" {
+			optionColumns = append(optionColumns, i)
+		}
+	}
+	_, hasOptions := columns["options"]
+	if _, ok := columns["text"]; !ok {
+		return nil, errors.New("header has no text column")
+	}
+	if _, ok := columns["correct_answer"]; !ok {
+		return nil, errors.New("header has no correct_answer column")
+	}
+	if !hasOptions && len(optionColumns) == 0 {
+		return nil, errors.New("header has no options or option_N columns")
+	}
+
+	var items []Item
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				items = append(items, Item{Line: parseErr.StartLine, Err: parseErr.Err})
+				continue
+			}
+			return nil, err
+		}
+		if blankRecord(record) {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := Item{
+			Line:          line,
+			Text:          cell("text"),
+			CorrectAnswer: cell("correct_answer"),
+		}
+		if options := cell("options"); options != "" {
+			for _, option := range strings.Split(options, "|") {
+				item.Options = append(item.Options, strings.TrimSpace(option))
+			}
+		}
+		for _, i := range optionColumns {
+			if i < len(record) && strings.TrimSpace(record[i]) != "" {
+				item.Options = append(item.Options, strings.TrimSpace(record[i]))
+			}
+		}
+		if item.TimeLimit, err = intCell(cell("time_limit")); err != nil {
+			item.Err = fmt.Errorf("time_limit %w", err)
+		} else if item.Points, err = intCell(cell("points")); err != nil {
+			item.Err = fmt.Errorf("points %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func blankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// intCell parses an optional whole number; empty cells are 0
+func intCell(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("must be a whole number, got %q", value)
+	}
+	return n, nil
+}
@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// parseGIFT reads Moodle GIFT. Questions are separated by blank lines and
+// may carry a ::title::. Multiple choice ({=right ~wrong}) and true/false
+// ({T}, {FALSE}) questions are supported; other GIFT types have no single
+// correct option and are rejected.
+func parseGIFT(r io.Reader) ([]Item, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var (
+		items []Item
+		block []string
+		start int
+		line  int
+	)
+	flush := func() {
+		if len(block) > 0 {
+			items = append(items, parseGIFTQuestion(start, strings.Join(block, "\n")))
+			block = nil
+		}
+	}
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case strings.HasPrefix(trimmed, "//"):
+			continue
+		case strings.HasPrefix(trimmed, "$CATEGORY:"):
+			flush()
+			continue
+		case trimmed == "":
+			// Blank lines only end a question outside its answer block
+			if len(block) > 0 && !giftInsideAnswers(strings.Join(block, "\n")) {
+				flush()
+			}
+			continue
+		}
+
+		if len(block) == 0 {
+			start = line
+		}
+		block = append(block, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return items, nil
+}
+
+// giftInsideAnswers reports whether text opens an answer block it does not
+// close
+func giftInsideAnswers(text string) bool {
+	open := giftIndex(text, "{")
+	return open >= 0 && giftIndex(text[open:], "}") < 0
+}
+
+func parseGIFTQuestion(line int, text string) Item {
+	item := Item{Line: line}
+
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "::") {
+		if end := giftIndex(text[2:], "::"); end >= 0 {
+			text = strings.TrimSpace(text[end+4:])
+		}
+	}
+
+	open := giftIndex(text, "{")
+	if open < 0 {
+		item.Err = errors.New("no answer block; descriptions are not questions")
+		return item
+	}
+	closing := giftIndex(text[open:], "}")
+	if closing < 0 {
+		item.Err = errors.New("answer block is not closed")
+		return item
+	}
+	closing += open
+
+	before := strings.TrimSpace(text[:open])
+	after := strings.TrimSpace(text[closing+1:])
+	if after != "" {
+		// Missing word format: the answers stand in for a blank
+		before += " _____ " + after
+	}
+	item.Text = giftUnescape(stripMarkup(before))
+
+	answers := strings.TrimSpace(text[open+1 : closing])
+	switch strings.ToUpper(strings.TrimSpace(giftCut(answers, "#"))) {
+	case "T", "TRUE":
+		item.Options = []string{"True", "False"}
+		item.CorrectAnswer = "True"
+		return item
+	case "F", "FALSE":
+		item.Options = []string{"True", "False"}
+		item.CorrectAnswer = "False"
+		return item
+	case "":
+		item.Err = errors.New("essay questions are not supported")
+		return item
+	}
+	if strings.HasPrefix(answers, "#") {
+		item.Err = errors.New("numerical questions are not supported")
+		return item
+	}
+
+	var correct []string
+	wrong := 0
+	for _, choice := range giftChoices(answers) {
+		value := strings.TrimSpace(giftCut(choice[1:], "#"))
+		if giftIndex(value, "->") >= 0 {
+			item.Err = errors.New("matching questions are not supported")
+			return item
+		}
+
+		isCorrect := choice[0] == '='
+		if strings.HasPrefix(value, "%") {
+			if end := strings.Index(value[1:], "%"); end >= 0 {
+				isCorrect = value[1:end+1] == "100"
+				value = strings.TrimSpace(value[end+2:])
+			}
+		}
+		if choice[0] == '~' {
+			wrong++
+		}
+
+		value = giftUnescape(value)
+		item.Options = append(item.Options, value)
+		if isCorrect {
+			correct = append(correct, value)
+		}
+	}
+
+	switch {
+	case wrong == 0:
+		item.Err = errors.New("short answer questions are not supported")
+	case len(correct) == 0:
+		item.Err = errors.New("no correct answer marked with =")
+	case len(correct) > 1:
+		item.Err = errors.New("questions with more than one correct answer are not supported")
+	default:
+		item.CorrectAnswer = correct[0]
+	}
+	return item
+}
+
+// giftChoices splits an answer block into choices, each starting with its
+// unescaped = or ~ marker. Matching pairs (=cat -> meow) split the same way,
+// and their arrow marks them.
+func giftChoices(answers string) []string {
+	var (
+		choices []string
+		start   = -1
+	)
+	for i := 0; i < len(answers); i++ {
+		switch answers[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			if start >= 0 {
+				choices = append(choices, answers[start:i])
+			}
+			start = i
+		}
+	}
+	if start >= 0 {
+		choices = append(choices, answers[start:])
+	}
+	return choices
+}
+
+// giftIndex is strings.Index that skips backslash-escaped characters
+func giftIndex(s, substr string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// giftCut returns s up to the first unescaped sep, dropping feedback
+func giftCut(s, sep string) string {
+	if i := giftIndex(s, sep); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+var giftEscapes = strings.NewReplacer(`\~`, "~", `\=`, "=", `\#`, "#", `\{`, "{", `\}`, "}", `\:`, ":", `\n`, "\n", `\\`, `\`)
+
+func giftUnescape(s string) string {
+	return strings.TrimSpace(giftEscapes.Replace(s))
+}
+
+// stripMarkup drops a leading [html], [moodle], [plain] or [markdown]
+// format marker, and HTML tags if the text was marked as HTML
+func stripMarkup(text string) string {
+	for _, marker := range []string{"[html]", "[moodle]", "[plain]", "[markdown]"} {
+		if strings.HasPrefix(text, marker) {
+			text = strings.TrimSpace(text[len(marker):])
+			if marker == "[html]" {
+				text = htmlToText(text)
+			}
+			break
+		}
+	}
+	return text
+}
@@ -0,0 +1,81 @@
+// Package importer reads question banks exported from other tools. Parsers
+// only turn a file into items; validation and storage are left to the
+// service layer so imported questions follow the same rules as added ones.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatJSON   Format = "json"
+	FormatGIFT   Format = "gift"
+	FormatMoodle Format = "xml"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported import format, use csv, json, gift or xml")
+
+// Item is one question read from a file. Line is where it starts, counting
+// from 1. Items that could not be read have Err set and may be incomplete.
+type Item struct {
+	Line          int
+	Text          string
+	Options       []string
+	CorrectAnswer string
+	Explanation   string
+	TimeLimit     int
+	Points        int
+	Err           error
+}
+
+// ParseFormat resolves a format name. "moodle" is accepted for Moodle XML
+// and "txt", the extension Moodle gives GIFT exports, for GIFT.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatCSV, FormatJSON, FormatGIFT, FormatMoodle:
+		return format, nil
+	case "moodle":
+		return FormatMoodle, nil
+	case "txt":
+		return FormatGIFT, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// FormatFromFilename guesses the format from a file extension
+func FormatFromFilename(name string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// Parse reads every item in r. An error is returned only when the file as a
+// whole cannot be read; problems with single items are reported on the item.
+func Parse(format Format, r io.Reader) ([]Item, error) {
+	var (
+		items []Item
+		err   error
+	)
+	switch format {
+	case FormatCSV:
+		items, err = parseCSV(r)
+	case FormatJSON:
+		items, err = parseJSON(r)
+	case FormatGIFT:
+		items, err = parseGIFT(r)
+	case FormatMoodle:
+		items, err = parseMoodleXML(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", format, err)
+	}
+	return items, nil
+}
@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// jsonQuestion is one question of the JSON import schema:
+//
+//	{
+//	  "questions": [
+//	    {
+//	      "text": "Capital of France?",
+//	      "options": ["Paris", "Lyon"],
+//	      "correct_answer": "Paris",
+//	      "time_limit": 30,
+//	      "points": 100,
+//	      "explanation": "Paris has been the capital since 987."
+//	    }
+//	  ]
+//	}
+//
+// A bare array of questions is accepted too. time_limit, points and
+// explanation are optional.
+type jsonQuestion struct {
+	Text          string   `json:"text"`
+	Options       []string `json:"options"`
+	CorrectAnswer string   `json:"correct_answer"`
+	TimeLimit     int      `json:"time_limit"`
+	Points        int      `json:"points"`
+	Explanation   string   `json:"explanation"`
+}
+
+func parseJSON(r io.Reader) ([]Item, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := seekQuestions(dec); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var question jsonQuestion
+		strict := json.NewDecoder(bytes.NewReader(raw))
+		strict.DisallowUnknownFields()
+		if err := strict.Decode(&question); err != nil {
+			items = append(items, Item{Line: line, Err: err})
+			continue
+		}
+		items = append(items, Item{
+			Line:          line,
+			Text:          question.Text,
+			Options:       question.Options,
+			CorrectAnswer: question.CorrectAnswer,
+			Explanation:   question.Explanation,
+			TimeLimit:     question.TimeLimit,
+			Points:        question.Points,
+		})
+	}
+	return items, nil
+}
+
+// seekQuestions moves dec just inside the array of questions, either the
+// top-level value or the "questions" field of a top-level object
+func seekQuestions(dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == json.Delim('[') {
+		return nil
+	}
+	if token != json.Delim('{') {
+		return errors.New(`expected an array or an object with "questions"`)
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key == "questions" {
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if token != json.Delim('[') {
+				return errors.New(`"questions" must be an array`)
+			}
+			return nil
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return errors.New(`no "questions" array found`)
+}
+
+// lineAt returns the line of the first non-space byte at or after offset,
+// skipping the comma between array elements
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
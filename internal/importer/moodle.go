@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type moodleText struct {
+	Format string `xml:"format,attr"`
+	Text   string `xml:"text"`
+}
+
+type moodleAnswer struct {
+	Fraction string `xml:"fraction,attr"`
+	Format   string `xml:"format,attr"`
+	Text     string `xml:"text"`
+}
+
+type moodleQuestion struct {
+	Type         string         `xml:"type,attr"`
+	QuestionText moodleText     `xml:"questiontext"`
+	Answers      []moodleAnswer `xml:"answer"`
+}
+
+// parseMoodleXML reads a Moodle XML export. Multiple choice questions with a
+// single right answer and true/false questions are supported; categories are
+// skipped and other question types are rejected.
+func parseMoodleXML(r io.Reader) ([]Item, error) {
+	dec := xml.NewDecoder(r)
+
+	var items []Item
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "question" {
+			continue
+		}
+		line, _ := dec.InputPos()
+
+		var question moodleQuestion
+		if err := dec.DecodeElement(&question, &start); err != nil {
+			return nil, err
+		}
+		if question.Type == "category" {
+			continue
+		}
+		items = append(items, moodleItem(line, &question))
+	}
+	return items, nil
+}
+
+func moodleItem(line int, question *moodleQuestion) Item {
+	item := Item{Line: line, Text: moodleContent(question.QuestionText.Format, question.QuestionText.Text)}
+
+	switch question.Type {
+	case "multichoice", "truefalse":
+	default:
+		item.Err = fmt.Errorf("%s questions are not supported", question.Type)
+		return item
+	}
+
+	var correct []string
+	for _, answer := range question.Answers {
+		value := moodleContent(answer.Format, answer.Text)
+		if question.Type == "truefalse" {
+			// Moodle stores these in lower case; match GIFT's options
+			switch strings.ToLower(value) {
+			case "true":
+				value = "True"
+			case "false":
+				value = "False"
+			}
+		}
+		item.Options = append(item.Options, value)
+
+		fraction, err := strconv.ParseFloat(strings.TrimSpace(answer.Fraction), 64)
+		if err != nil && answer.Fraction != "" {
+			item.Err = fmt.Errorf("answer %q has an invalid fraction %q", value, answer.Fraction)
+			return item
+		}
+		if fraction >= 100 {
+			correct = append(correct, value)
+		}
+	}
+
+	switch len(correct) {
+	case 0:
+		item.Err = errors.New("no answer has a fraction of 100")
+	case 1:
+		item.CorrectAnswer = correct[0]
+	default:
+		item.Err = errors.New("questions with more than one correct answer are not supported")
+	}
+	return item
+}
+
+// moodleContent returns the plain text of a Moodle text field, which is HTML
+// unless marked otherwise
+func moodleContent(format, text string) string {
+	switch format {
+	case "", "html", "moodle_auto_format":
+		return htmlToText(text)
+	default:
+		return strings.TrimSpace(text)
+	}
+}
+
+var (
+	htmlBlockTags = regexp.MustCompile(`(?i)</?(p|br|div|li|ul|ol|h[1-6]|tr|td|table)\b[^>]*>`)
+	htmlTags      = regexp.MustCompile(`<[^>]*>`)
+	whitespace    = regexp.MustCompile(`\s+`)
+)
+
+// htmlToText strips tags and entities, collapsing whitespace. Block tags
+// become spaces so paragraphs do not run together.
+func htmlToText(text string) string {
+	text = htmlBlockTags.ReplaceAllString(text, " ")
+	text = htmlTags.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
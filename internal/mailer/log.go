@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// LogMailer sends nothing. It appends each email to a file, or writes it to
+// the log when there is no file, so links can be followed by hand or read
+// back by tests.
+type LogMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if m.path == "" {
+		slog.Info("Email", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "To: %s\nSubject: %s\n\n%s\n\n", headerValue(msg.To), headerValue(msg.Subject), msg.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
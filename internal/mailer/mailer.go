@@ -0,0 +1,46 @@
+// Package mailer sends the emails the server writes, such as account
+// verification and password reset links. Senders are chosen by config so
+// local development and tests never need a mail server.
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer for the configured driver, logging emails when the
+// driver is unset or unknown
+func New(cfg config.MailConfig) Mailer {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg)
+	case "", DriverLog:
+	default:
+		slog.Warn("Unknown mail driver, logging emails instead", "driver", cfg.Driver)
+	}
+	return NewLogMailer(cfg.LogFile)
+}
+
+// headerValue keeps a header on one line, so a value cannot add headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+}
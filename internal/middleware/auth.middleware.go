@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -12,20 +13,7 @@ import (
 
 func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString := ""
-		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
-				tokenString = parts[1]
-			}
-		}
-
-		// Fallback to query parameter (often used for WebSockets)
-		if tokenString == "" {
-			tokenString = c.Query("token")
-		}
-
+		tokenString := extractToken(c)
 		if tokenString == "" {
 			response.Error(c, http.StatusUnauthorized, "Authorization required", nil)
 			c.Abort()
@@ -33,10 +21,9 @@ func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
 		}
 
 		// Validate token
-		claims, err := authService.ValidateToken(tokenString)
+		claims, err := authService.ValidateToken(c.Request.Context(), tokenString)
 		if err != nil {
-			response.Error(c, http.StatusUnauthorized, "Invalid or expired token", nil)
-			c.Abort()
+			tokenError(c, err)
 			return
 		}
 
@@ -48,3 +35,46 @@ func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware identifies the user when a valid token is supplied
+// but lets anonymous requests through, e.g. to the WebSocket, which checks
+// each quiz a socket asks to follow.
+func OptionalAuthMiddleware(authService service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString := extractToken(c); tokenString != "" {
+			claims, err := authService.ValidateToken(c.Request.Context(), tokenString)
+			if err != nil {
+				tokenError(c, err)
+				return
+			}
+			c.Set("claims", claims)
+			c.Set("userID", claims.UserID)
+			c.Set("username", claims.Username)
+		}
+
+		c.Next()
+	}
+}
+
+// tokenError rejects a request whose token failed validation
+func tokenError(c *gin.Context, err error) {
+	message := "Invalid or expired token"
+	if errors.Is(err, service.ErrTokenRevoked) {
+		message = "Token has been revoked"
+	}
+	response.Error(c, http.StatusUnauthorized, message, nil)
+	c.Abort()
+}
+
+// extractToken reads the bearer token from the Authorization header, falling
+// back to the token query parameter (often used for WebSockets)
+func extractToken(c *gin.Context) string {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader != "" {
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			return parts[1]
+		}
+	}
+	return c.Query("token")
+}
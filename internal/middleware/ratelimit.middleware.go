@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/nguyen1302/realtime-quiz/pkg/response"
+)
+
+// RateLimitMiddleware applies the named rule to the client's IP and to the
+// account: the logged-in user, so put it after AuthMiddleware on protected
+// routes, or else the email in the JSON body. If Redis fails the request
+// goes through rather than the endpoint going down with it.
+func RateLimitMiddleware(limiter service.RateLimitService, rule string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wait, err := limiter.Allow(c.Request.Context(), rule, c.ClientIP(), rateLimitAccount(c))
+		if err != nil {
+			slog.Error("Rate limiter unavailable", "rule", rule, "error", err)
+			c.Next()
+			return
+		}
+		if wait > 0 {
+			response.TooManyRequests(c, "Too many requests, please try again later", wait)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ThrottleMiddleware applies the named throttle to the logged-in user, so
+// it goes after AuthMiddleware
+func ThrottleMiddleware(limiter service.RateLimitService, throttle string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("userID")
+		if !ok {
+			c.Next()
+			return
+		}
+		if wait := limiter.Throttle(throttle, userID.(uuid.UUID).String()); wait > 0 {
+			response.TooManyRequests(c, "Too many requests, please slow down", wait)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitAccount names the account a request is for, or returns "". The
+// body is put back for the handler.
+func rateLimitAccount(c *gin.Context) string {
+	if userID, ok := c.Get("userID"); ok {
+		return userID.(uuid.UUID).String()
+	}
+	if c.Request.Body == nil || c.ContentType() != "application/json" {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	return req.Email
+}
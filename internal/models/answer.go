@@ -8,16 +8,20 @@ import (
 )
 
 type Answer struct {
-	ID         uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
-	QuizID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"quiz_id"`
-	QuestionID uuid.UUID      `gorm:"type:uuid;not null;index" json:"question_id"`
-	UserID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	Answer     string         `gorm:"type:text;not null" json:"answer"`
-	IsCorrect  bool           `gorm:"default:false" json:"is_correct"`
-	Points     int            `gorm:"default:0" json:"points"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	QuizID         uuid.UUID       `gorm:"type:uuid;not null;index" json:"quiz_id"`
+	SessionID      uuid.UUID       `gorm:"type:uuid;not null;index" json:"session_id"`
+	AttemptID      *uuid.UUID      `gorm:"type:uuid;index" json:"attempt_id,omitempty"` // Set in self-paced sessions
+	QuestionID     uuid.UUID       `gorm:"type:uuid;not null;index" json:"question_id"`
+	UserID         uuid.UUID       `gorm:"type:uuid;not null;index" json:"user_id"`
+	Answer         string          `gorm:"type:text;not null" json:"answer"`
+	IsCorrect      bool            `gorm:"default:false" json:"is_correct"`
+	Points         int             `gorm:"default:0" json:"points"`
+	ResponseTimeMs *int64          `gorm:"column:response_time_ms" json:"response_time_ms,omitempty"` // Nil outside live play
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt  `gorm:"index" json:"-"`
+	Feedback       *AnswerFeedback `gorm:"-" json:"feedback,omitempty"` // Practice only
 }
 
 func (a *Answer) BeforeCreate(tx *gorm.DB) (err error) {
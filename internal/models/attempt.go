@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Attempt is one player's run through a self-paced session. Each attempt has
+// its own question cursor, and each question its own timer, started when the
+// question is served.
+type Attempt struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SessionID         uuid.UUID  `gorm:"type:uuid;not null;index;uniqueIndex:idx_attempts_session_user_number" json:"session_id"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null;index;uniqueIndex:idx_attempts_session_user_number" json:"user_id"`
+	Number            int        `gorm:"not null;uniqueIndex:idx_attempts_session_user_number" json:"number"` // 1 for the player's first attempt
+	QuestionIndex     int        `gorm:"not null;default:0" json:"question_index"`                            // Cursor, the number of questions done
+	QuestionStartedAt time.Time  `json:"question_started_at"`                                                 // When the current question was served
+	Score             int        `gorm:"not null;default:0" json:"score"`
+	Correct           int        `gorm:"not null;default:0" json:"correct"`
+	TotalTimeMs       int64      `gorm:"not null;default:0" json:"total_time_ms"`  // Time spent on the questions done
+	Best              bool       `gorm:"not null;default:false;index" json:"best"` // The player's attempt that counts
+	StartedAt         time.Time  `json:"started_at"`
+	FinishedAt        *time.Time `json:"finished_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (a *Attempt) Finished() bool {
+	return a.FinishedAt != nil
+}
+
+func (a *Attempt) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+// AttemptState is a player's view of their self-paced attempt
+type AttemptState struct {
+	Attempt         *Attempt      `json:"attempt"`
+	CurrentQuestion *LiveQuestion `json:"current_question,omitempty"` // Nil once the attempt is finished
+	AttemptsLeft    *int          `json:"attempts_left,omitempty"`    // Nil when attempts are unlimited
+}
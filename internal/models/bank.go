@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyMedium Difficulty = "medium"
+	DifficultyHard   Difficulty = "hard"
+)
+
+func (d Difficulty) Valid() bool {
+	switch d {
+	case DifficultyEasy, DifficultyMedium, DifficultyHard:
+		return true
+	}
+	return false
+}
+
+// BankQuestion is a question in a user's reusable bank. Quizzes reference
+// bank questions instead of copying them; they are copied into the quiz only
+// when it starts, so later edits to the bank do not change past results.
+type BankQuestion struct {
+	ID            uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	OwnerID       uuid.UUID         `gorm:"type:uuid;not null;index" json:"owner_id"`
+	Text          string            `gorm:"not null" json:"text"`
+	Options       JSONB             `gorm:"type:jsonb" json:"options"`
+	CorrectAnswer string            `gorm:"not null" json:"correct_answer"`
+	Explanation   string            `gorm:"type:text;not null;default:''" json:"explanation,omitempty"`
+	TimeLimit     int               `gorm:"default:30" json:"time_limit"`
+	Points        int               `gorm:"default:100" json:"points"`
+	Difficulty    Difficulty        `gorm:"type:varchar(10);not null;default:'medium';index" json:"difficulty"`
+	Tags          []BankQuestionTag `gorm:"foreignKey:BankQuestionID;constraint:OnDelete:CASCADE" json:"tags"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+func (q *BankQuestion) BeforeCreate(tx *gorm.DB) (err error) {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return
+}
+
+// BankQuestionTag is one tag of a bank question. Tags are lower case and
+// serialise as plain strings.
+type BankQuestionTag struct {
+	BankQuestionID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Tag            string    `gorm:"type:varchar(50);primaryKey;index"`
+}
+
+func (t BankQuestionTag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Tag)
+}
+
+func (t *BankQuestionTag) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.Tag)
+}
+
+// QuizBankItem places bank questions in a quiz at Order: either one chosen
+// question, or Count questions drawn at random from those matching every tag
+// and the difficulty. Items are resolved into questions when the quiz starts.
+type QuizBankItem struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	QuizID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"quiz_id"`
+	Order          int        `gorm:"column:item_order;default:0" json:"order"`
+	BankQuestionID *uuid.UUID `gorm:"type:uuid" json:"bank_question_id,omitempty"`
+	DrawCount      int        `gorm:"default:0" json:"draw_count,omitempty"`
+	DrawTags       JSONB      `gorm:"type:jsonb" json:"draw_tags,omitempty"`
+	DrawDifficulty Difficulty `gorm:"type:varchar(10)" json:"draw_difficulty,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (i *QuizBankItem) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
+
+// BankSearch filters a user's bank. Every tag must match; Query matches the
+// question text.
+type BankSearch struct {
+	OwnerID    uuid.UUID
+	Query      string
+	Tags       []string
+	Difficulty Difficulty
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+const (
+	BundleFormat  = "realtime-quiz/bundle"
+	BundleVersion = 3
+)
+
+// Bundle is a portable copy of a quiz definition, used to back quizzes up and
+// move them between environments. It carries no IDs, join code or results;
+// importing it always creates a new quiz. Version is raised whenever a field
+// is added, and older versions remain importable.
+type Bundle struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Quiz       BundleQuiz       `json:"quiz"`
+	Questions  []BundleQuestion `json:"questions"`
+	BankItems  []BundleBankItem `json:"bank_items,omitempty"` // Since version 3
+	// Bank holds the bank questions the items choose or may draw. Importing
+	// copies them into the importing user's bank.
+	Bank []BundleBankQuestion `json:"bank,omitempty"` // Since version 3
+	// Media lists the URLs the quiz's text links to, so the files can be
+	// moved along with it. It is informational and ignored on import.
+	Media []string `json:"media,omitempty"` // Since version 3
+}
+
+type BundleQuiz struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Settings    BundleSettings `json:"settings"` // Since version 3
+}
+
+type BundleSettings struct {
+	AllowClone bool `json:"allow_clone"`
+}
+
+// BundleQuestion is a question in bundle order; Order is kept so that gaps
+// and ties survive a round trip
+type BundleQuestion struct {
+	Text          string   `json:"text"`
+	Options       []string `json:"options"`
+	CorrectAnswer string   `json:"correct_answer"`
+	Explanation   string   `json:"explanation,omitempty"` // Since version 2
+	TimeLimit     int      `json:"time_limit"`
+	Points        int      `json:"points"`
+	Order         int      `json:"order"`
+}
+
+// BundleBankItem is a bank item still to be resolved: either the bank
+// question at index Question of Bundle.Bank, or a random draw
+type BundleBankItem struct {
+	Order          int        `json:"order"`
+	Question       *int       `json:"question,omitempty"`
+	DrawCount      int        `json:"draw_count,omitempty"`
+	DrawTags       []string   `json:"draw_tags,omitempty"`
+	DrawDifficulty Difficulty `json:"draw_difficulty,omitempty"`
+}
+
+type BundleBankQuestion struct {
+	Text          string     `json:"text"`
+	Options       []string   `json:"options"`
+	CorrectAnswer string     `json:"correct_answer"`
+	Explanation   string     `json:"explanation,omitempty"`
+	TimeLimit     int        `json:"time_limit"`
+	Points        int        `json:"points"`
+	Difficulty    Difficulty `json:"difficulty"`
+	Tags          []string   `json:"tags,omitempty"`
+}
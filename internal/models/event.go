@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payloads of the quiz lifecycle events broadcast over WebSocket
+
+type QuizStartedEvent struct {
+	QuizID         uuid.UUID `json:"quiz_id"`
+	SessionID      uuid.UUID `json:"session_id"`
+	TotalQuestions int       `json:"total_questions"`
+}
+
+// TimerEvent is sent when a question's clock is paused, resumed or extended
+type TimerEvent struct {
+	QuestionID      uuid.UUID `json:"question_id"`
+	EndsAt          time.Time `json:"ends_at"`
+	TimeRemainingMs int64     `json:"time_remaining_ms"`
+	Paused          bool      `json:"paused"`
+}
+
+type AnswerRevealedEvent struct {
+	QuestionID    uuid.UUID `json:"question_id"`
+	CorrectAnswer string    `json:"correct_answer"`
+}
+
+type PlayerKickedEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type LobbyLockedEvent struct {
+	Locked bool `json:"locked"`
+}
+
+type QuizEndedEvent struct {
+	FinalRankings []LeaderboardEntry `json:"final_rankings"`
+	Winner        *LeaderboardEntry  `json:"winner,omitempty"`
+}
+
+// AnswerCountEvent is sent to hosts as answers arrive
+type AnswerCountEvent struct {
+	QuestionID uuid.UUID        `json:"question_id"`
+	Counts     map[string]int64 `json:"counts"`
+	Total      int64            `json:"total"`
+}
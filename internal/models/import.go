@@ -0,0 +1,17 @@
+package models
+
+// ImportRejection explains why an item of an import file was left out
+type ImportRejection struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport is the outcome of a question import. With DryRun nothing was
+// saved and Imported counts the questions that would have been.
+type ImportReport struct {
+	Imported  int               `json:"imported"`
+	Rejected  []ImportRejection `json:"rejected"`
+	DryRun    bool              `json:"dry_run"`
+	Questions []*Question       `json:"questions,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// JWK is a public signing key in JSON Web Key form (RFC 7517). RSA keys
+// fill n and e; Ed25519 keys are OKP keys that fill crv and x. EC keys, which
+// only identity providers publish here, fill crv, x and y.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the set of keys that verify access tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -3,8 +3,9 @@ package models
 import "github.com/google/uuid"
 
 type LeaderboardEntry struct {
-	UserID   uuid.UUID `json:"user_id"`
-	Username string    `json:"username,omitempty"` // Enriched later
-	Score    float64   `json:"score"`
-	Rank     int       `json:"rank"`
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username,omitempty"` // Enriched later
+	Score       float64   `json:"score"`
+	Rank        int       `json:"rank"`
+	TotalTimeMs int64     `json:"total_time_ms,omitempty"` // Time the ranked attempt took, in self-paced sessions
 }
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrgRole is what a member may do in an organisation. Each role may do
+// everything the roles before it may.
+type OrgRole string
+
+const (
+	OrgRoleViewer OrgRole = "viewer" // Sees quizzes, results and leaderboards
+	OrgRoleHost   OrgRole = "host"   // Runs and schedules sessions
+	OrgRoleEditor OrgRole = "editor" // Creates and edits quizzes
+	OrgRoleAdmin  OrgRole = "admin"  // Manages members and invitations
+)
+
+var orgRoleRanks = map[OrgRole]int{
+	OrgRoleViewer: 1,
+	OrgRoleHost:   2,
+	OrgRoleEditor: 3,
+	OrgRoleAdmin:  4,
+}
+
+func (r OrgRole) Valid() bool {
+	return orgRoleRanks[r] > 0
+}
+
+// Allows reports whether the role may do what needs the other role
+func (r OrgRole) Allows(need OrgRole) bool {
+	return r.Valid() && orgRoleRanks[r] >= orgRoleRanks[need]
+}
+
+// Organization owns quizzes on behalf of its members. Every user has a
+// personal one, made with their account, for the quizzes they keep to
+// themselves.
+type Organization struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Name           string     `gorm:"not null" json:"name"`
+	PersonalUserID *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"personal_user_id,omitempty"` // The user whose personal organisation this is
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (o *Organization) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return
+}
+
+// Membership gives a user a role in an organisation
+type Membership struct {
+	OrgID        uuid.UUID     `gorm:"type:uuid;primaryKey" json:"org_id"`
+	UserID       uuid.UUID     `gorm:"type:uuid;primaryKey;index" json:"user_id"`
+	Role         OrgRole       `gorm:"type:varchar(20);not null" json:"role"`
+	Organization *Organization `gorm:"foreignKey:OrgID" json:"organization,omitempty"`
+	User         *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// Invitation lets whoever follows its link join an organisation with a
+// role, up to MaxUses times. The link carries a signed token naming the
+// record.
+type Invitation struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex" json:"-"` // SHA-256 of the link's token, in hex
+	OrgID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"org_id"`
+	Role      OrgRole    `gorm:"type:varchar(20);not null" json:"role"`
+	Email     string     `json:"email,omitempty"` // Only this address may accept, if set
+	MaxUses   int        `gorm:"not null;default:1" json:"max_uses"`
+	Uses      int        `gorm:"not null;default:0" json:"uses"`
+	CreatedBy uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (i *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
+
+// Open reports whether the invitation can still be accepted
+func (i *Invitation) Open(now time.Time) bool {
+	return i.RevokedAt == nil && now.Before(i.ExpiresAt) && i.Uses < i.MaxUses
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PersonalData is everything kept about a user, as they download it
+type PersonalData struct {
+	ExportedAt      time.Time        `json:"exported_at"`
+	User            User             `json:"user"`
+	Identities      []UserIdentity   `json:"identities"`
+	Memberships     []Membership     `json:"memberships"`
+	Quizzes         []OwnedQuiz      `json:"quizzes"`
+	Answers         []Answer         `json:"answers"`
+	Attempts        []Attempt        `json:"attempts"`
+	PracticeAnswers []PracticeAnswer `json:"practice_answers"`
+	Mastery         []Mastery        `json:"mastery"`
+}
+
+// OwnedQuiz is a quiz the user created, without its questions
+type OwnedQuiz struct {
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	Status    QuizStatus `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PracticeAnswer is an answer given in a practice session. Practice answers
+// are kept apart from scored answers, so they never reach leaderboards,
+// reports or exports.
+type PracticeAnswer struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	QuizID     uuid.UUID `gorm:"type:uuid;not null;index" json:"quiz_id"`
+	SessionID  uuid.UUID `gorm:"type:uuid;not null;index" json:"session_id"`
+	QuestionID uuid.UUID `gorm:"type:uuid;not null;index" json:"question_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Answer     string    `gorm:"type:text;not null" json:"answer"`
+	IsCorrect  bool      `gorm:"not null;default:false" json:"is_correct"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (a *PracticeAnswer) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+// Mastery is how well a user knows one question, built up from every
+// practice answer they gave to it
+type Mastery struct {
+	UserID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	QuestionID      uuid.UUID `gorm:"type:uuid;primaryKey" json:"question_id"`
+	QuizID          uuid.UUID `gorm:"type:uuid;not null;index" json:"quiz_id"`
+	Attempts        int       `gorm:"not null;default:0" json:"attempts"`
+	Correct         int       `gorm:"not null;default:0" json:"correct"`
+	Streak          int       `gorm:"not null;default:0" json:"streak"` // Correct answers in a row
+	Level           float64   `gorm:"not null;default:0" json:"level"`  // From 0 to 1, recent answers weigh most
+	LastPracticedAt time.Time `json:"last_practiced_at"`
+}
+
+// Weight of the latest answer in a mastery level
+const masteryRate = 0.3
+
+// Record adds a practice answer to the mastery
+func (m *Mastery) Record(correct bool, at time.Time) {
+	result := 0.0
+	m.Attempts++
+	if correct {
+		result = 1
+		m.Correct++
+		m.Streak++
+	} else {
+		m.Streak = 0
+	}
+	m.Level += masteryRate * (result - m.Level)
+	m.LastPracticedAt = at
+}
+
+// AnswerFeedback is returned with an answer in practice: the correct answer,
+// why it is correct, and the player's mastery of the question now
+type AnswerFeedback struct {
+	CorrectAnswer string   `json:"correct_answer"`
+	Explanation   string   `json:"explanation,omitempty"`
+	Mastery       *Mastery `json:"mastery"`
+}
+
+// PracticeState is a practice session as a player sees it: its questions,
+// without answers, and their mastery of each one they have practised
+type PracticeState struct {
+	SessionID uuid.UUID        `json:"session_id"`
+	Questions []PublicQuestion `json:"questions"`
+	Mastery   []Mastery        `json:"mastery"`
+}
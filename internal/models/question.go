@@ -26,16 +26,20 @@ func (j *JSONB) Scan(value interface{}) error {
 }
 
 type Question struct {
-	ID            uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	QuizID        uuid.UUID `gorm:"type:uuid;not null;index" json:"quiz_id"`
-	Text          string    `gorm:"not null" json:"text"`
-	Options       JSONB     `gorm:"type:jsonb" json:"options"`
-	CorrectAnswer string    `gorm:"not null" json:"correct_answer"`
-	TimeLimit     int       `gorm:"default:30" json:"time_limit"`
-	Points        int       `gorm:"default:100" json:"points"`
-	Order         int       `gorm:"column:item_order;default:0" json:"order"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	QuizID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"quiz_id"`
+	Text           string     `gorm:"not null" json:"text"`
+	Options        JSONB      `gorm:"type:jsonb" json:"options"`
+	CorrectAnswer  string     `gorm:"not null" json:"correct_answer"`
+	Explanation    string     `gorm:"type:text;not null;default:''" json:"explanation,omitempty"` // Shown with the answer in practice
+	TimeLimit      int        `gorm:"default:30" json:"time_limit"`
+	Points         int        `gorm:"default:100" json:"points"`
+	Order          int        `gorm:"column:item_order;default:0" json:"order"`
+	Version        int        `gorm:"not null;default:1;index" json:"version"`           // Quiz version the revision belongs to
+	PreviousID     *uuid.UUID `gorm:"type:uuid" json:"previous_id,omitempty"`            // Revision in the previous version it was copied from
+	BankQuestionID *uuid.UUID `gorm:"type:uuid;index" json:"bank_question_id,omitempty"` // Copied from the bank at quiz start
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 func (q *Question) BeforeCreate(tx *gorm.DB) (err error) {
@@ -44,3 +48,25 @@ func (q *Question) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// PublicQuestion is a question without its correct answer, safe to send to
+// players while the question is open.
+type PublicQuestion struct {
+	ID        uuid.UUID `json:"id"`
+	Text      string    `json:"text"`
+	Options   JSONB     `json:"options"`
+	TimeLimit int       `json:"time_limit"`
+	Points    int       `json:"points"`
+	Order     int       `json:"order"`
+}
+
+func (q *Question) Public() PublicQuestion {
+	return PublicQuestion{
+		ID:        q.ID,
+		Text:      q.Text,
+		Options:   q.Options,
+		TimeLimit: q.TimeLimit,
+		Points:    q.Points,
+		Order:     q.Order,
+	}
+}
@@ -16,16 +16,28 @@ const (
 )
 
 type Quiz struct {
-	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
-	Title       string     `gorm:"not null" json:"title"`
-	Description string     `json:"description"`
-	Code        string     `gorm:"uniqueIndex;not null" json:"code"`
-	Status      QuizStatus `gorm:"type:varchar(20);default:'DRAFT'" json:"status"`
-	OwnerID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"owner_id"`
-	Owner       User       `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
-	Questions   []Question `gorm:"foreignKey:QuizID" json:"questions,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID            uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	Title         string         `gorm:"not null" json:"title"`
+	Description   string         `json:"description"`
+	Code          string         `gorm:"uniqueIndex;not null" json:"code"`
+	Status        QuizStatus     `gorm:"type:varchar(20);default:'DRAFT'" json:"status"` // Status of the default session
+	OwnerID       uuid.UUID      `gorm:"type:uuid;not null;index" json:"owner_id"`       // The user who created it
+	Owner         User           `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	OrgID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"org_id"`       // The organisation whose members may manage it
+	Version       int            `gorm:"not null;default:1" json:"version"`            // Latest version, the one edits go to
+	PlayedVersion int            `gorm:"not null;default:0" json:"played_version"`     // Latest version any session played, 0 before one starts
+	AllowClone    bool           `gorm:"not null;default:false" json:"allow_clone"`    // Lets other users clone the quiz
+	ClonedFrom    *uuid.UUID     `gorm:"type:uuid;index" json:"cloned_from,omitempty"` // Quiz this one was cloned from
+	Questions     []Question     `gorm:"foreignKey:QuizID" json:"questions,omitempty"`
+	BankItems     []QuizBankItem `gorm:"foreignKey:QuizID" json:"-"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Frozen reports whether the latest version has been played. Played versions
+// are never edited; edits go to a new version instead.
+func (q *Quiz) Frozen() bool {
+	return q.PlayedVersion >= q.Version
 }
 
 func (q *Quiz) BeforeCreate(tx *gorm.DB) (err error) {
@@ -34,3 +46,16 @@ func (q *Quiz) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// AfterCreate gives the quiz its default session, which shares the quiz's ID
+// and join code
+func (q *Quiz) AfterCreate(tx *gorm.DB) error {
+	return tx.Create(&Session{
+		ID:     q.ID,
+		QuizID: q.ID,
+		HostID: q.OwnerID,
+		Code:   q.Code,
+		Status: QuizStatusDraft,
+		Mode:   SessionModeLive,
+	}).Error
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LiveState tracks which question of a running quiz is currently open.
+// It lives in Redis for the duration of the game.
+type LiveState struct {
+	QuestionID    uuid.UUID `json:"question_id"`
+	QuestionIndex int       `json:"question_index"`
+	StartedAt     time.Time `json:"started_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	Paused        bool      `json:"paused"`
+	RemainingMs   int64     `json:"remaining_ms"` // Time left when paused
+	Revealed      bool      `json:"revealed"`
+}
+
+// TimeRemaining returns how long the current question stays open, never
+// negative. The clock is frozen while the quiz is paused.
+func (s *LiveState) TimeRemaining(now time.Time) time.Duration {
+	if s.Paused {
+		return time.Duration(s.RemainingMs) * time.Millisecond
+	}
+	if remaining := s.EndsAt.Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// AcceptsAnswers reports whether the current question can still be answered
+func (s *LiveState) AcceptsAnswers(now time.Time) bool {
+	return !s.Paused && !s.Revealed && s.TimeRemaining(now) > 0
+}
+
+// LiveQuestion is the sanitised current question as seen by players
+type LiveQuestion struct {
+	PublicQuestion
+	Index           int       `json:"index"`
+	Total           int       `json:"total"`
+	EndsAt          time.Time `json:"ends_at"`
+	TimeRemainingMs int64     `json:"time_remaining_ms"`
+	Paused          bool      `json:"paused"`
+}
+
+// NewLiveQuestion builds the player view of the open question
+func NewLiveQuestion(question *Question, state *LiveState, total int, now time.Time) *LiveQuestion {
+	return &LiveQuestion{
+		PublicQuestion:  question.Public(),
+		Index:           state.QuestionIndex,
+		Total:           total,
+		EndsAt:          state.EndsAt,
+		TimeRemainingMs: state.TimeRemaining(now).Milliseconds(),
+		Paused:          state.Paused,
+	}
+}
+
+// PlayerState is the requesting player's own standing in the quiz
+type PlayerState struct {
+	Score    float64 `json:"score"`
+	Rank     int     `json:"rank"` // 0 when the player has no score yet
+	Streak   int     `json:"streak"`
+	Answered bool    `json:"answered"` // Whether the current question was answered
+}
+
+// QuizState is a point-in-time snapshot of a live quiz, sent to clients that
+// join late or cannot be caught up from the replay buffer.
+type QuizState struct {
+	QuizID           uuid.UUID          `json:"quiz_id"`
+	SessionID        uuid.UUID          `json:"session_id"`
+	Status           QuizStatus         `json:"status"`
+	CurrentQuestion  *LiveQuestion      `json:"current_question,omitempty"`
+	Player           *PlayerState       `json:"player,omitempty"`
+	Leaderboard      []LeaderboardEntry `json:"leaderboard"`
+	ParticipantCount int64              `json:"participant_count"`
+	LobbyLocked      bool               `json:"lobby_locked"`
+	ServerTime       time.Time          `json:"server_time"`
+}
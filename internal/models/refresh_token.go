@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken is one refresh token, stored as a hash. Each refresh uses the
+// token up and issues the next one in the same family, which starts at
+// login; a used token coming back means it was stolen, and ends the family.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	FamilyID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"family_id"`      // Shared by every token since the login
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"` // SHA-256 of the token, in hex
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`                 // When it was exchanged for the next token
+	RevokedAt *time.Time `gorm:"index" json:"revoked_at,omitempty"` // When its family was ended
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// Usable reports whether the token can still be exchanged
+func (t *RefreshToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DistractorStat shows how an option drew the strongest and weakest players.
+// A working distractor is picked more often by the lower group than the upper.
+type DistractorStat struct {
+	Option     string  `json:"option"`
+	IsCorrect  bool    `json:"is_correct"`
+	Count      int64   `json:"count"`
+	Percent    float64 `json:"percent"`
+	UpperCount int64   `json:"upper_count"`
+	LowerCount int64   `json:"lower_count"`
+}
+
+// QuestionReport measures how hard a question was and how well it separated
+// strong players from weak ones
+type QuestionReport struct {
+	QuestionID    uuid.UUID `json:"question_id"`
+	Text          string    `json:"text"`
+	Order         int       `json:"order"`
+	CorrectAnswer string    `json:"correct_answer"`
+	Responses     int64     `json:"responses"`
+	Correct       int64     `json:"correct"`
+	// Share of participants who answered correctly, from 0 (hardest) to 1
+	PValue float64 `json:"p_value"`
+	// Upper group p-value minus lower group p-value, from -1 to 1. Nil with
+	// fewer than two participants.
+	DiscriminationIndex *float64         `json:"discrimination_index,omitempty"`
+	AvgResponseMs       *float64         `json:"avg_response_ms,omitempty"`
+	Options             []DistractorStat `json:"options"`
+}
+
+// ScorePoint is a participant's result on one question and their running
+// total after it
+type ScorePoint struct {
+	QuestionID      uuid.UUID `json:"question_id"`
+	Order           int       `json:"order"`
+	Answered        bool      `json:"answered"`
+	IsCorrect       bool      `json:"is_correct"`
+	Points          int       `json:"points"`
+	CumulativeScore int       `json:"cumulative_score"`
+	ResponseTimeMs  *int64    `json:"response_time_ms,omitempty"`
+}
+
+// ParticipantReport is one player's accuracy and score over the quiz
+type ParticipantReport struct {
+	UserID   uuid.UUID    `json:"user_id"`
+	Username string       `json:"username"`
+	Rank     int          `json:"rank"`
+	Score    int          `json:"score"`
+	Answered int64        `json:"answered"`
+	Correct  int64        `json:"correct"`
+	Accuracy float64      `json:"accuracy"`
+	Timeline []ScorePoint `json:"timeline"`
+}
+
+// QuizReport is the analytics report of one session of a quiz. It is cached
+// once the session has finished.
+type QuizReport struct {
+	QuizID           uuid.UUID           `json:"quiz_id"`
+	SessionID        uuid.UUID           `json:"session_id"`
+	Title            string              `json:"title"`
+	Status           QuizStatus          `json:"status"`
+	ParticipantCount int                 `json:"participant_count"`
+	AverageScore     float64             `json:"average_score"`
+	Questions        []QuestionReport    `json:"questions"`
+	Participants     []ParticipantReport `json:"participants"`
+	GeneratedAt      time.Time           `json:"generated_at"`
+}
@@ -1 +1,21 @@
 package models
+
+import "github.com/google/uuid"
+
+// QuestionResult is what a participant answered to one question
+type QuestionResult struct {
+	Answer    string `json:"answer"`
+	IsCorrect bool   `json:"is_correct"`
+	Points    int    `json:"points"`
+}
+
+// ParticipantResult is one participant's row in a results export
+type ParticipantResult struct {
+	Rank     int                          `json:"rank"`
+	UserID   uuid.UUID                    `json:"user_id"`
+	Username string                       `json:"username"`
+	Email    string                       `json:"email"`
+	Score    int                          `json:"score"`
+	Correct  int                          `json:"correct"`
+	Answers  map[uuid.UUID]QuestionResult `json:"answers"`
+}
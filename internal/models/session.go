@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SessionMode is how a session is played
+type SessionMode string
+
+const (
+	// SessionModeLive runs every player through the questions together,
+	// paced by the host or the scheduler
+	SessionModeLive SessionMode = "LIVE"
+	// SessionModeSelfPaced lets each player take the quiz whenever they like
+	// within the session's window
+	SessionModeSelfPaced SessionMode = "SELF_PACED"
+	// SessionModePractice lets players answer any question as often as they
+	// like, with the answer shown straight away and nothing scored
+	SessionModePractice SessionMode = "PRACTICE"
+)
+
+// Session is one run of a quiz, with its own join code, players, answers and
+// leaderboard. A quiz can have many sessions, running at the same time.
+//
+// Every quiz has a default session that shares the quiz's ID and code, so
+// clients addressing a quiz by its ID play in that session.
+type Session struct {
+	ID      uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	QuizID  uuid.UUID   `gorm:"type:uuid;not null;index" json:"quiz_id"`
+	Quiz    *Quiz       `gorm:"foreignKey:QuizID" json:"quiz,omitempty"`
+	HostID  uuid.UUID   `gorm:"type:uuid;not null;index" json:"host_id"`
+	Code    string      `gorm:"uniqueIndex;not null" json:"code"`
+	Label   string      `json:"label"`
+	Status  QuizStatus  `gorm:"type:varchar(20);default:'DRAFT'" json:"status"`
+	Mode    SessionMode `gorm:"type:varchar(20);not null;default:'LIVE'" json:"mode"`
+	Version int         `gorm:"not null;default:0" json:"version"` // Quiz version pinned at start, 0 before
+	// A scheduled session starts by itself at ScheduledAt; players can join
+	// from LobbyOpensAt
+	ScheduledAt  *time.Time `gorm:"index" json:"scheduled_at,omitempty"`
+	LobbyOpensAt *time.Time `json:"lobby_opens_at,omitempty"`
+	// A self-paced session takes attempts between OpensAt and ClosesAt, when
+	// set, up to MaxAttempts per player (0 for no limit)
+	OpensAt     *time.Time `json:"opens_at,omitempty"`
+	ClosesAt    *time.Time `json:"closes_at,omitempty"`
+	MaxAttempts int        `gorm:"not null;default:0" json:"max_attempts"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Default reports whether this is the quiz's default session
+func (s *Session) Default() bool {
+	return s.ID == s.QuizID
+}
+
+func (s *Session) SelfPaced() bool {
+	return s.Mode == SessionModeSelfPaced
+}
+
+func (s *Session) Practice() bool {
+	return s.Mode == SessionModePractice
+}
+
+// Available reports whether a self-paced session takes attempts at now
+func (s *Session) Available(now time.Time) bool {
+	if s.OpensAt != nil && now.Before(*s.OpensAt) {
+		return false
+	}
+	return s.ClosesAt == nil || now.Before(*s.ClosesAt)
+}
+
+// LobbyOpen reports whether players can join yet. Only a scheduled
+// session's lobby opens late.
+func (s *Session) LobbyOpen(now time.Time) bool {
+	return s.LobbyOpensAt == nil || !now.Before(*s.LobbyOpensAt)
+}
+
+// RunVersion is the version players see: the pinned one once the session
+// has started, the quiz's latest before
+func (s *Session) RunVersion(latest int) int {
+	if s.Version > 0 {
+		return s.Version
+	}
+	return latest
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+// SessionSummary sets a session's results beside those of the quiz's other
+// sessions
+type SessionSummary struct {
+	Session
+	ParticipantCount int64   `json:"participant_count"`
+	AnswerCount      int64   `json:"answer_count"`
+	PercentCorrect   float64 `json:"percent_correct"`
+	AverageScore     float64 `json:"average_score"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "github.com/google/uuid"
+
+// OptionStat is how many players picked one option of a question
+type OptionStat struct {
+	Option    string  `json:"option"`
+	Count     int64   `json:"count"`
+	Percent   float64 `json:"percent"`
+	IsCorrect bool    `json:"is_correct"`
+}
+
+// FastestAnswer identifies the quickest correct answer to a question
+type FastestAnswer struct {
+	UserID         uuid.UUID `json:"user_id"`
+	Username       string    `json:"username,omitempty"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+}
+
+// QuestionStats summarises the answers to a question once it closes
+type QuestionStats struct {
+	QuestionID       uuid.UUID      `json:"question_id"`
+	CorrectAnswer    string         `json:"correct_answer"`
+	TotalAnswers     int64          `json:"total_answers"`
+	CorrectAnswers   int64          `json:"correct_answers"`
+	PercentCorrect   float64        `json:"percent_correct"`
+	Distribution     []OptionStat   `json:"distribution"`
+	MedianResponseMs *int64         `json:"median_response_ms,omitempty"`
+	FastestCorrect   *FastestAnswer `json:"fastest_correct,omitempty"`
+}
@@ -8,12 +8,16 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Username     string    `gorm:"uniqueIndex;not null" json:"username"`
-	Email        string    `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash string    `gorm:"not null" json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Username        string     `gorm:"uniqueIndex;not null" json:"username"`
+	Email           string     `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash    string     `gorm:"not null" json:"-"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"` // Nil until the user follows the verification link
+	DisplayName     string     `gorm:"not null;default:''" json:"display_name"`
+	AvatarURL       string     `gorm:"column:avatar_url;not null;default:''" json:"avatar_url"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // Set once the account is deleted and its personal data scrubbed
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a user to their account with an OpenID Connect
+// provider. The provider's subject identifies them, so a later change of
+// email at the provider still finds the same user.
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Provider  string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject" json:"subject"`
+	Email     string    `json:"email"` // As the provider last reported it
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (i *UserIdentity) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
+
+// OIDCLogin is a login started with a provider, kept until the user comes
+// back with a code
+type OIDCLogin struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+	Nonce    string `json:"nonce"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TokenPurpose string
+
+const (
+	TokenPurposeVerifyEmail   TokenPurpose = "verify_email"
+	TokenPurposeResetPassword TokenPurpose = "reset_password"
+)
+
+// UserToken records a link emailed to a user, so it works only once. The
+// link carries a random token, of which only the hash is kept.
+type UserToken struct {
+	ID        uuid.UUID    `gorm:"type:uuid;primaryKey" json:"id"`
+	TokenHash string       `gorm:"type:varchar(64);uniqueIndex" json:"-"` // SHA-256 of the token, in hex
+	UserID    uuid.UUID    `gorm:"type:uuid;not null;index" json:"user_id"`
+	Purpose   TokenPurpose `gorm:"type:varchar(20);not null" json:"purpose"`
+	Email     string       `gorm:"not null" json:"email"` // Address it was sent to
+	ExpiresAt time.Time    `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at,omitempty"` // When it was followed, or replaced by a newer link
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func (t *UserToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
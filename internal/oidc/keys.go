@@ -0,0 +1,71 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"log/slog"
+	"math/big"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+)
+
+// parseKeys reads the signing keys of a JWK Set by kid, skipping keys of
+// other uses and types it cannot read
+func parseKeys(set models.JWKS) map[string]crypto.PublicKey {
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, ok := parseKey(jwk)
+		if !ok {
+			slog.Warn("Skipping unreadable provider key", "kid", jwk.Kid, "kty", jwk.Kty)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys
+}
+
+func parseKey(jwk models.JWK) (crypto.PublicKey, bool) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err1 := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			return nil, false
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, true
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, false
+		}
+		x, err1 := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err1 != nil || err2 != nil {
+			return nil, false
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, false
+		}
+		return key, true
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, false
+		}
+		return ed25519.PublicKey(x), true
+	}
+	return nil, false
+}
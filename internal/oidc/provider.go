@@ -0,0 +1,277 @@
+// Package oidc logs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE (RFC 7636). It discovers the provider's
+// endpoints from its issuer, exchanges the code for an ID token and checks
+// that token against the provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+)
+
+var (
+	ErrDiscovery  = errors.New("could not read the provider's discovery document")
+	ErrExchange   = errors.New("the provider refused the authorization code")
+	ErrIDToken    = errors.New("the provider's ID token is invalid")
+	ErrUnknownKey = errors.New("ID token signed with an unknown key")
+)
+
+// keyRefetchInterval stops tokens with made-up key IDs from hammering the
+// provider's JWKS endpoint
+const keyRefetchInterval = 10 * time.Second
+
+// Claims are the parts of an ID token used to find or create the user
+type Claims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// metadata is the part of the discovery document the flow needs
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one configured provider. It reads the discovery
+// document once, on first use, and the provider's keys again whenever a
+// token names a key it has not seen.
+type Provider struct {
+	cfg    config.OIDCProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	meta      *metadata
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func NewProvider(cfg config.OIDCProviderConfig) *Provider {
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL is where the user logs in with the provider. The challenge
+// binds the code to the verifier only this server knows.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, scopes...), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Exchange trades the code for an ID token and returns its verified claims.
+// The nonce must be the one sent with the login.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.do(req, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in the response", ErrExchange)
+	}
+	return p.verify(ctx, meta, token.IDToken, nonce)
+}
+
+// verify checks the ID token's signature, issuer, audience, expiry and nonce
+func (p *Provider) verify(ctx context.Context, meta *metadata, raw, nonce string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.key(ctx, meta, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIDToken, err)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrIDToken)
+	}
+	return claims, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	if err := p.do(req, &meta); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	// The document must come from the issuer it claims, or tokens from
+	// another issuer could be accepted
+	if strings.TrimRight(meta.Issuer, "/") != strings.TrimRight(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("%w: issuer %q does not match", ErrDiscovery, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: endpoints missing", ErrDiscovery)
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// key returns the provider's key with the ID, fetching the key set again if
+// it is not known yet
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.fetchedAt) < keyRefetchInterval {
+		return nil, ErrUnknownKey
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set models.JWKS
+	p.fetchedAt = time.Now()
+	if err := p.do(req, &set); err != nil {
+		return nil, err
+	}
+	p.keys = parseKeys(set)
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// lookup finds the key with the ID. Providers with a single key may leave
+// the ID out of their tokens.
+func (p *Provider) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// do sends the request and decodes a JSON response into v
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// RandomString returns a URL-safe random value for a state, nonce or PKCE
+// verifier
+func RandomString() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Challenge is the S256 PKCE challenge of the verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -1,13 +1,14 @@
 package realtime
 
 import (
-	"encoding/json"
 	"log"
+	"math"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/nguyen1302/realtime-quiz/pkg/ratelimit"
 )
 
 const (
@@ -24,12 +25,36 @@ const (
 	maxMessageSize = 512
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for dev
-	},
+// Options configures the WebSocket transport
+type Options struct {
+	// EnableCompression negotiates permessage-deflate with clients that support it.
+	EnableCompression bool
+	// CompressionLevel is a flate level; 0 keeps gorilla's default.
+	CompressionLevel int
+	// Limits are by command type, with AnyMessage counting every message.
+	Limits map[string]MessageLimit
+	// DisconnectAfter is how many refused messages in a minute get a client
+	// disconnected; 0 never does.
+	DisconnectAfter int
+}
+
+// MessageLimit limits one type of message on each connection and across
+// each user's connections to this hub
+type MessageLimit struct {
+	PerConnection ratelimit.Rate
+	PerUser       ratelimit.Rate
+}
+
+func newUpgrader(opts Options) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: opts.EnableCompression,
+		Subprotocols:      []string{SubprotocolJSON, SubprotocolMsgPack},
+		CheckOrigin: func(r *http.Request) bool {
+			return true // Allow all origins for dev
+		},
+	}
 }
 
 // Client is a middleman between the websocket connection and the hub.
@@ -42,8 +67,16 @@ type Client struct {
 	// Buffered channel of outbound messages.
 	send chan *WSMessage
 
+	// Wire format negotiated at upgrade time.
+	codec Codec
+
 	// User info
 	userID string
+
+	// Per-connection buckets by message type, and the refusals left before
+	// disconnecting. Only readPump touches them.
+	limits  map[string]*ratelimit.Bucket
+	strikes *ratelimit.Bucket
 }
 
 // readPump pumps messages from the websocket connection to the hub.
@@ -64,28 +97,73 @@ func (c *Client) readPump() {
 			break
 		}
 
+		now := time.Now()
+		if wait := c.throttle(AnyMessage, now); wait > 0 {
+			if !c.refuse("messages", wait) {
+				break
+			}
+			continue
+		}
+
 		// Handle incoming messages
-		var wsMsg WSMessage
-		if err := json.Unmarshal(message, &wsMsg); err != nil {
+		var msg Message
+		if err := c.codec.Decode(message, &msg); err != nil {
 			log.Printf("error unmarshalling message: %v", err)
 			continue
 		}
 
-		switch wsMsg.Type {
-		case "join_quiz":
-			if wsMsg.Payload != nil {
-				// Assuming payload contains quiz_id. simpler if payload is just the ID string or a struct
-				// For simplicity, let's assume payload is a map for now or we cast it
-				if payloadMap, ok := wsMsg.Payload.(map[string]interface{}); ok {
-					if quizID, ok := payloadMap["quiz_id"].(string); ok {
-						c.hub.SubscribeToQuiz(c, quizID)
-					}
-				}
+		if wait := c.throttle(msg.Type, now); wait > 0 {
+			if !c.refuse(msg.Type+" messages", wait) {
+				break
 			}
+			continue
 		}
+
+		c.handleMessage(&msg)
 	}
 }
 
+// throttle takes a token for the message type from the connection's bucket
+// and then the user's, returning how long until one is free if either is
+// empty, or 0
+func (c *Client) throttle(kind string, now time.Time) time.Duration {
+	limit, ok := c.hub.options.Limits[kind]
+	if !ok {
+		return 0
+	}
+
+	bucket, ok := c.limits[kind]
+	if !ok {
+		bucket = ratelimit.NewBucket(limit.PerConnection, now)
+		c.limits[kind] = bucket
+	}
+	if wait := bucket.Take(now); wait > 0 {
+		return wait
+	}
+	if users := c.hub.userLimits[kind]; users != nil && c.userID != "" {
+		return users.Take(c.userID, now)
+	}
+	return 0
+}
+
+// refuse tells the client it is sending too fast. It returns false, having
+// closed the connection, when the client keeps on regardless.
+func (c *Client) refuse(what string, wait time.Duration) bool {
+	if c.strikes != nil && c.strikes.Take(time.Now()) > 0 {
+		log.Printf("disconnecting client %s for flooding %s", c.userID, what)
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many messages"),
+			time.Now().Add(writeWait))
+		return false
+	}
+
+	c.trySend(&WSMessage{Type: EventError, Payload: ErrorPayload{
+		Message:      "too many " + what + ", slow down",
+		RetryAfterMs: int64(math.Ceil(float64(wait) / float64(time.Millisecond))),
+	}})
+	return true
+}
+
 // writePump pumps messages from the hub to the websocket connection.
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -103,7 +181,12 @@ func (c *Client) writePump() {
 				return
 			}
 
-			if err := c.conn.WriteJSON(message); err != nil {
+			prepared, err := message.prepare(c.codec)
+			if err != nil {
+				log.Printf("error encoding %s: %v", message.Type, err)
+				continue
+			}
+			if err := c.conn.WritePreparedMessage(prepared); err != nil {
 				return
 			}
 
@@ -118,12 +201,31 @@ func (c *Client) writePump() {
 
 // ServeWs handles websocket requests from the peer.
 func ServeWs(hub *Hub, c *gin.Context, userID string) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := hub.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, send: make(chan *WSMessage, 256), userID: userID}
+	if hub.options.EnableCompression {
+		conn.EnableWriteCompression(true)
+		if hub.options.CompressionLevel != 0 {
+			if err := conn.SetCompressionLevel(hub.options.CompressionLevel); err != nil {
+				log.Printf("invalid compression level: %v", err)
+			}
+		}
+	}
+
+	client := &Client{
+		hub:    hub,
+		conn:   conn,
+		send:   make(chan *WSMessage, 256),
+		codec:  codecFor(conn.Subprotocol()),
+		userID: userID,
+		limits: make(map[string]*ratelimit.Bucket),
+	}
+	if n := hub.options.DisconnectAfter; n > 0 {
+		client.strikes = ratelimit.NewBucket(ratelimit.Rate{PerSecond: float64(n) / 60, Burst: n}, time.Now())
+	}
 	client.hub.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in
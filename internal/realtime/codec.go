@@ -0,0 +1,139 @@
+package realtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Subprotocols offered at upgrade time. Clients that don't ask for one get
+// JSON.
+const (
+	SubprotocolJSON    = "quiz.v1.json"
+	SubprotocolMsgPack = "quiz.v1.msgpack"
+)
+
+// Codec serialises messages for one wire format.
+type Codec interface {
+	Subprotocol() string
+	// FrameType is the WebSocket frame type messages are sent with.
+	FrameType() int
+	Encode(message *WSMessage) ([]byte, error)
+	Decode(data []byte, message *Message) error
+}
+
+var codecs = map[string]Codec{
+	SubprotocolJSON:    jsonCodec{},
+	SubprotocolMsgPack: msgpackCodec{},
+}
+
+// codecFor returns the codec of a negotiated subprotocol, defaulting to JSON.
+func codecFor(subprotocol string) Codec {
+	if codec, ok := codecs[subprotocol]; ok {
+		return codec
+	}
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string { return SubprotocolJSON }
+
+func (jsonCodec) FrameType() int { return websocket.TextMessage }
+
+func (jsonCodec) Encode(message *WSMessage) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (jsonCodec) Decode(data []byte, message *Message) error {
+	return json.Unmarshal(data, message)
+}
+
+// msgpackCodec carries the same document as jsonCodec in MessagePack.
+// Payloads are encoded with their json tags, and UUIDs and times as the same
+// strings encoding/json writes, so both formats agree on field names and
+// value shapes.
+type msgpackCodec struct{}
+
+func init() {
+	msgpack.Register(uuid.UUID{}, func(e *msgpack.Encoder, v reflect.Value) error {
+		return e.EncodeString(v.Interface().(uuid.UUID).String())
+	}, nil)
+	msgpack.Register(time.Time{}, func(e *msgpack.Encoder, v reflect.Value) error {
+		return e.EncodeString(v.Interface().(time.Time).Format(time.RFC3339Nano))
+	}, nil)
+}
+
+func (msgpackCodec) Subprotocol() string { return SubprotocolMsgPack }
+
+func (msgpackCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (msgpackCodec) Encode(message *WSMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(message); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Decode(data []byte, message *Message) error {
+	var raw struct {
+		Type    string      `msgpack:"type"`
+		Payload interface{} `msgpack:"payload"`
+	}
+	if err := msgpack.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	message.Type = raw.Type
+	message.Payload = nil
+	if raw.Payload == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(raw.Payload)
+	if err != nil {
+		return err
+	}
+	message.Payload = payload
+	return nil
+}
+
+// preparedFrames caches a message as a websocket.PreparedMessage per codec,
+// so a broadcast is serialised (and compressed) once per wire format and the
+// same frame bytes are fanned out to every client.
+type preparedFrames struct {
+	mu       sync.Mutex
+	prepared map[string]*websocket.PreparedMessage
+}
+
+func (f *preparedFrames) get(message *WSMessage, codec Codec) (*websocket.PreparedMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if pm, ok := f.prepared[codec.Subprotocol()]; ok {
+		return pm, nil
+	}
+
+	data, err := codec.Encode(message)
+	if err != nil {
+		return nil, err
+	}
+	pm, err := websocket.NewPreparedMessage(codec.FrameType(), data)
+	if err != nil {
+		return nil, err
+	}
+	if f.prepared == nil {
+		f.prepared = make(map[string]*websocket.PreparedMessage)
+	}
+	f.prepared[codec.Subprotocol()] = pm
+	return pm, nil
+}
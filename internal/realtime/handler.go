@@ -1 +1,156 @@
 package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Time allowed for the service layer to build a state snapshot or run a
+// host command.
+const snapshotTimeout = 5 * time.Second
+
+// handleMessage dispatches a command received from the client.
+func (c *Client) handleMessage(msg *Message) {
+	switch msg.Type {
+	case CommandJoinQuiz:
+		var payload JoinQuizPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.Session() == "" {
+			c.sendError("join_quiz requires a quiz_id or session_id")
+			return
+		}
+		room := payload.Session()
+		if err := c.admit(room); err != nil {
+			return
+		}
+		// Send the joining client the full picture right away instead of
+		// leaving it blank until the next broadcast.
+		seq := c.hub.SubscribeToQuiz(c, room)
+		if err := c.sendQuizState(room, seq); err != nil {
+			c.hub.UnsubscribeFromQuiz(c, room)
+		}
+
+	case CommandResume:
+		var payload ResumePayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.Session() == "" {
+			c.sendError("resume requires a quiz_id or session_id and last_seq")
+			return
+		}
+		// A kicked or locked-out player must not get back in by resuming
+		room := payload.Session()
+		if err := c.admit(room); err != nil {
+			return
+		}
+		if replayed, seq := c.hub.Resume(c, room, payload.LastSeq); !replayed {
+			if err := c.sendQuizState(room, seq); err != nil {
+				c.hub.UnsubscribeFromQuiz(c, room)
+			}
+		}
+
+	case CommandHostJoin, CommandHostStart, CommandHostPause, CommandHostResume, CommandHostSkip,
+		CommandHostExtendTimer, CommandHostReveal, CommandHostKick, CommandHostLockLobby:
+		var payload HostCommandPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.Session() == "" {
+			c.sendError(msg.Type + " requires a quiz_id or session_id")
+			return
+		}
+		c.handleHostCommand(msg.Type, payload)
+	}
+}
+
+// handleHostCommand checks the client is a host of the quiz and runs the
+// command through the service layer. Results reach players as quiz events.
+func (c *Client) handleHostCommand(command string, payload HostCommandPayload) {
+	c.hub.mu.RLock()
+	controller := c.hub.hostController
+	c.hub.mu.RUnlock()
+
+	if controller == nil || c.userID == "" {
+		c.sendError("host commands require an authenticated host")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	if command == CommandHostJoin {
+		room := payload.Session()
+		if err := controller.AuthorizeHost(ctx, room, c.userID); err != nil {
+			c.sendError(err.Error())
+			return
+		}
+		// Hosts see the player stream as well as their own
+		c.hub.SubscribeHost(c, room)
+		seq := c.hub.SubscribeToQuiz(c, room)
+		if err := c.sendQuizState(room, seq); err != nil {
+			c.hub.UnsubscribeFromQuiz(c, room)
+			c.hub.UnsubscribeHost(c, room)
+		}
+		return
+	}
+
+	if err := controller.HandleHostCommand(ctx, c.userID, command, payload); err != nil {
+		c.sendError(err.Error())
+	}
+}
+
+// admit asks the service layer whether the client may follow the quiz. The
+// client is told why if it may not.
+func (c *Client) admit(quizID string) error {
+	c.hub.mu.RLock()
+	provider := c.hub.stateProvider
+	c.hub.mu.RUnlock()
+
+	if provider == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	if err := provider.Admit(ctx, quizID, c.userID); err != nil {
+		c.sendError(err.Error())
+		return err
+	}
+	return nil
+}
+
+// sendQuizState sends a full quiz_state snapshot tagged with the given
+// stream position. The client is told why if the snapshot is refused.
+func (c *Client) sendQuizState(quizID string, seq uint64) error {
+	c.hub.mu.RLock()
+	provider := c.hub.stateProvider
+	c.hub.mu.RUnlock()
+
+	if provider == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	state, err := provider.QuizState(ctx, quizID, c.userID)
+	if err != nil {
+		log.Printf("error building quiz state for %s: %v", quizID, err)
+		c.sendError(err.Error())
+		return err
+	}
+
+	c.trySend(&WSMessage{Type: EventQuizState, Seq: seq, Payload: state})
+	return nil
+}
+
+func (c *Client) sendError(message string) {
+	c.trySend(&WSMessage{Type: EventError, Payload: ErrorPayload{Message: message}})
+}
+
+// trySend queues a message for this client only, dropping it if the send
+// buffer is full.
+func (c *Client) trySend(message *WSMessage) {
+	select {
+	case c.send <- message:
+	default:
+		log.Printf("dropping %s for slow client %s", message.Type, c.userID)
+	}
+}
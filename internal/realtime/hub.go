@@ -1,10 +1,32 @@
 package realtime
 
 import (
+	"context"
 	"log"
 	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nguyen1302/realtime-quiz/pkg/ratelimit"
 )
 
+// HostController authorises and executes host commands. It is implemented by
+// the service layer.
+type HostController interface {
+	AuthorizeHost(ctx context.Context, quizID, userID string) error
+	HandleHostCommand(ctx context.Context, userID, command string, payload HostCommandPayload) error
+}
+
+// StateProvider admits clients to a quiz and builds the quiz_state snapshot
+// sent to a client that cannot be caught up from the replay buffer. It is
+// implemented by the service layer.
+type StateProvider interface {
+	// Admit checks the user may follow the quiz, entering them as a player
+	// where needed. It runs before every subscription, including resumes.
+	Admit(ctx context.Context, quizID, userID string) error
+	QuizState(ctx context.Context, quizID, userID string) (interface{}, error)
+}
+
 // Hub maintains the set of active clients and broadcasts messages to clients.
 type Hub struct {
 	// Registered clients.
@@ -24,21 +46,65 @@ type Hub struct {
 
 	// Optional: Map UserID to Clients for targeted messaging
 	userClients map[string]map[*Client]bool
-	mu          sync.RWMutex
+
+	// Map QuizID to the quiz hosts' clients
+	hostClients map[string]map[*Client]bool
+
+	// Map QuizID to its event sequence and replay buffer
+	streams map[string]*quizStream
+
+	// Per-user buckets by message type, fixed once the hub is created
+	userLimits map[string]*ratelimit.Buckets
+
+	stateProvider  StateProvider
+	hostController HostController
+
+	options  Options
+	upgrader *websocket.Upgrader
+	mu       sync.RWMutex
 }
 
-func NewHub() *Hub {
+func NewHub(opts Options) *Hub {
+	userLimits := make(map[string]*ratelimit.Buckets)
+	for kind, limit := range opts.Limits {
+		if !limit.PerUser.Unlimited() {
+			userLimits[kind] = ratelimit.NewBuckets(limit.PerUser)
+		}
+	}
+
 	return &Hub{
+		options:     opts,
+		upgrader:    newUpgrader(opts),
 		broadcast:   make(chan *WSMessage),
 		register:    make(chan *Client),
 		unregister:  make(chan *Client),
 		clients:     make(map[*Client]bool),
 		userClients: make(map[string]map[*Client]bool),
 		quizClients: make(map[string]map[*Client]bool),
+		hostClients: make(map[string]map[*Client]bool),
+		streams:     make(map[string]*quizStream),
+		userLimits:  userLimits,
 	}
 }
 
+// SetHostController configures how host commands are executed.
+func (h *Hub) SetHostController(controller HostController) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hostController = controller
+}
+
+// SetStateProvider configures how quiz_state snapshots are built.
+func (h *Hub) SetStateProvider(provider StateProvider) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stateProvider = provider
+}
+
 func (h *Hub) Run() {
+	cleanup := time.NewTicker(time.Minute)
+	defer cleanup.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -72,6 +138,14 @@ func (h *Hub) Run() {
 						}
 					}
 				}
+				for quizID, clients := range h.hostClients {
+					if _, ok := clients[client]; ok {
+						delete(clients, client)
+						if len(clients) == 0 {
+							delete(h.hostClients, quizID)
+						}
+					}
+				}
 
 				close(client.send)
 				log.Printf("Client unregistered: %s", client.userID)
@@ -81,10 +155,37 @@ func (h *Hub) Run() {
 		case message := <-h.broadcast:
 			// Logic handled by helper methods now, but this channel can still be used for global broadcast
 			h.broadcastMessage(message)
+
+		case <-cleanup.C:
+			h.pruneStreams()
 		}
 	}
 }
 
+// pruneStreams drops replay buffers of quizzes that have had no subscribers
+// and no events for longer than streamIdleTTL.
+func (h *Hub) pruneStreams() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for quizID, stream := range h.streams {
+		if len(h.quizClients[quizID]) == 0 && time.Since(stream.lastActivity) > streamIdleTTL {
+			delete(h.streams, quizID)
+		}
+	}
+}
+
+// stream returns the event stream of a quiz, creating it if needed.
+// The caller must hold h.mu for writing.
+func (h *Hub) stream(quizID string) *quizStream {
+	stream, ok := h.streams[quizID]
+	if !ok {
+		stream = newQuizStream(replayBufferSize)
+		h.streams[quizID] = stream
+	}
+	return stream
+}
+
 func (h *Hub) broadcastMessage(message *WSMessage) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -114,7 +215,9 @@ func (h *Hub) BroadcastToUser(userID string, message *WSMessage) {
 	}
 }
 
-func (h *Hub) SubscribeToQuiz(client *Client, quizID string) {
+// SubscribeToQuiz adds the client to the quiz and returns the current stream
+// position, so a snapshot sent right after can be tagged with it.
+func (h *Hub) SubscribeToQuiz(client *Client, quizID string) uint64 {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -123,6 +226,8 @@ func (h *Hub) SubscribeToQuiz(client *Client, quizID string) {
 	}
 	h.quizClients[quizID][client] = true
 	log.Printf("Client %s subscribed to quiz %s", client.userID, quizID)
+
+	return h.stream(quizID).seq
 }
 
 func (h *Hub) UnsubscribeFromQuiz(client *Client, quizID string) {
@@ -137,9 +242,13 @@ func (h *Hub) UnsubscribeFromQuiz(client *Client, quizID string) {
 	}
 }
 
+// BroadcastToQuiz sequences the message in the quiz stream and sends it to
+// every client subscribed to the quiz.
 func (h *Hub) BroadcastToQuiz(quizID string, message *WSMessage) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stream(quizID).append(message)
 
 	if clients, ok := h.quizClients[quizID]; ok {
 		for client := range clients {
@@ -151,3 +260,112 @@ func (h *Hub) BroadcastToQuiz(quizID string, message *WSMessage) {
 		}
 	}
 }
+
+// SendToQuizUser sequences the message in the quiz stream and sends it only
+// to the given user's clients subscribed to the quiz. The message is kept for
+// replay so the user can recover it after reconnecting.
+func (h *Hub) SendToQuizUser(quizID, userID string, message *WSMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	message.UserID = userID
+	h.stream(quizID).append(message)
+
+	for client := range h.quizClients[quizID] {
+		if client.userID != userID {
+			continue
+		}
+		select {
+		case client.send <- message:
+		default:
+			// Handle slow client
+		}
+	}
+}
+
+// Resume subscribes the client to the quiz and queues every event it missed
+// since lastSeq. The client must have been admitted first. It returns false
+// when the gap cannot be replayed, in which case the client needs a full
+// state snapshot; seq is the stream position the snapshot should be tagged
+// with.
+func (h *Hub) Resume(client *Client, quizID string, lastSeq uint64) (replayed bool, seq uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream := h.stream(quizID)
+	missed, ok := stream.since(lastSeq, client.userID)
+	for _, message := range missed {
+		select {
+		case client.send <- message:
+			continue
+		default:
+			// Send buffer is full, fall back to a snapshot
+			ok = false
+		}
+		break
+	}
+
+	if h.quizClients[quizID] == nil {
+		h.quizClients[quizID] = make(map[*Client]bool)
+	}
+	h.quizClients[quizID][client] = true
+	log.Printf("Client %s resumed quiz %s from seq %d (replayed: %t)", client.userID, quizID, lastSeq, ok)
+
+	return ok, stream.seq
+}
+
+// SubscribeHost adds the client to the quiz's host stream. Host events are not
+// sequenced; a reconnecting host simply joins again.
+func (h *Hub) SubscribeHost(client *Client, quizID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.hostClients[quizID] == nil {
+		h.hostClients[quizID] = make(map[*Client]bool)
+	}
+	h.hostClients[quizID][client] = true
+	log.Printf("Client %s subscribed to host stream of quiz %s", client.userID, quizID)
+}
+
+func (h *Hub) UnsubscribeHost(client *Client, quizID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if clients, ok := h.hostClients[quizID]; ok {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.hostClients, quizID)
+		}
+	}
+}
+
+// BroadcastToHosts sends the message to every host client of the quiz.
+func (h *Hub) BroadcastToHosts(quizID string, message *WSMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.hostClients[quizID] {
+		select {
+		case client.send <- message:
+		default:
+			// Handle slow client
+		}
+	}
+}
+
+// RemoveUserFromQuiz unsubscribes all of the user's clients from the quiz.
+// The connections stay open.
+func (h *Hub) RemoveUserFromQuiz(quizID, userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.quizClients[quizID]
+	for client := range clients {
+		if client.userID == userID {
+			delete(clients, client)
+		}
+	}
+	if len(clients) == 0 {
+		delete(h.quizClients, quizID)
+	}
+}
@@ -0,0 +1,78 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+)
+
+// Compares fanning a leaderboard broadcast out to a room of simulated clients
+// when each client marshals the message itself (the old WriteJSON path)
+// against preparing the frame once and sharing it.
+//
+//	go test ./internal/realtime -run '^$' -bench BroadcastToQuiz -benchmem
+func BenchmarkBroadcastToQuiz(b *testing.B) {
+	leaderboard := make([]models.LeaderboardEntry, 10)
+	for i := range leaderboard {
+		leaderboard[i] = models.LeaderboardEntry{
+			UserID:   uuid.New(),
+			Username: fmt.Sprintf("player-%d", i),
+			Score:    float64(1000 - i*90),
+			Rank:     i + 1,
+		}
+	}
+
+	for _, size := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("clients=%d/per_client_json", size), func(b *testing.B) {
+			hub, clients := newBenchRoom(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				hub.BroadcastToQuiz("bench", &WSMessage{Type: EventLeaderboard, Payload: leaderboard})
+				for _, client := range clients {
+					message := <-client.send
+					if err := json.NewEncoder(io.Discard).Encode(message); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("clients=%d/prepared", size), func(b *testing.B) {
+			hub, clients := newBenchRoom(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				hub.BroadcastToQuiz("bench", &WSMessage{Type: EventLeaderboard, Payload: leaderboard})
+				for _, client := range clients {
+					message := <-client.send
+					if _, err := message.prepare(client.codec); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// newBenchRoom subscribes size connection-less clients to the "bench" quiz.
+func newBenchRoom(size int) (*Hub, []*Client) {
+	hub := NewHub(Options{})
+	clients := make([]*Client, size)
+	for i := range clients {
+		clients[i] = &Client{
+			hub:    hub,
+			send:   make(chan *WSMessage, 1),
+			codec:  jsonCodec{},
+			userID: uuid.NewString(),
+		}
+		hub.SubscribeToQuiz(clients[i], "bench")
+	}
+	return hub, clients
+}
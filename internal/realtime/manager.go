@@ -5,8 +5,8 @@ type Manager struct {
 	Hub *Hub
 }
 
-func NewManager() *Manager {
-	hub := NewHub()
+func NewManager(opts Options) *Manager {
+	hub := NewHub(opts)
 	go hub.Run()
 	return &Manager{
 		Hub: hub,
@@ -24,3 +24,23 @@ func (m *Manager) SendToUser(userID string, message *WSMessage) {
 func (m *Manager) BroadcastToQuiz(quizID string, message *WSMessage) {
 	m.Hub.BroadcastToQuiz(quizID, message)
 }
+
+func (m *Manager) SendToQuizUser(quizID, userID string, message *WSMessage) {
+	m.Hub.SendToQuizUser(quizID, userID, message)
+}
+
+func (m *Manager) SetStateProvider(provider StateProvider) {
+	m.Hub.SetStateProvider(provider)
+}
+
+func (m *Manager) BroadcastToHosts(quizID string, message *WSMessage) {
+	m.Hub.BroadcastToHosts(quizID, message)
+}
+
+func (m *Manager) RemoveUserFromQuiz(quizID, userID string) {
+	m.Hub.RemoveUserFromQuiz(quizID, userID)
+}
+
+func (m *Manager) SetHostController(controller HostController) {
+	m.Hub.SetHostController(controller)
+}
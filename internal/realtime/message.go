@@ -2,6 +2,8 @@ package realtime
 
 import (
 	"encoding/json"
+
+	"github.com/gorilla/websocket"
 )
 
 // Event types
@@ -14,6 +16,40 @@ const (
 	EventLeaderboard  = "leaderboard_update"
 	EventQuestion     = "new_question"
 	EventAnswerResult = "answer_result"
+
+	// Quiz lifecycle events reflecting host actions
+	EventQuizStarted    = "quiz_started"
+	EventQuizPaused     = "quiz_paused"
+	EventQuizResumed    = "quiz_resumed"
+	EventQuizEnded      = "quiz_ended"
+	EventTimerExtended  = "timer_extended"
+	EventAnswerRevealed = "answer_revealed"
+	EventQuestionStats  = "question_stats"
+	EventPlayerKicked   = "player_kicked"
+	EventLobbyLocked    = "lobby_locked"
+
+	// Host-only events
+	EventAnswerCount = "answer_count"
+)
+
+// Client commands
+const (
+	CommandJoinQuiz = "join_quiz"
+	CommandResume   = "resume"
+
+	// Host-only commands
+	CommandHostJoin        = "host_join"
+	CommandHostStart       = "host_start"
+	CommandHostPause       = "host_pause"
+	CommandHostResume      = "host_resume"
+	CommandHostSkip        = "host_skip"
+	CommandHostExtendTimer = "host_extend_timer"
+	CommandHostReveal      = "host_reveal"
+	CommandHostKick        = "host_kick"
+	CommandHostLockLobby   = "host_lock_lobby"
+
+	// AnyMessage names the limit on every message a client sends
+	AnyMessage = "*"
 )
 
 // Message represents a WebSocket message
@@ -25,7 +61,60 @@ type Message struct {
 // WSMessage is the raw message passed around in the Hub
 type WSMessage struct {
 	Type    string      `json:"type"`
+	Seq     uint64      `json:"seq,omitempty"` // Set for events sequenced in a quiz stream
 	Payload interface{} `json:"payload,omitempty"`
 	RoomID  string      `json:"-"` // Optional: for room-based broadcasting
 	UserID  string      `json:"-"` // Optional: for direct messaging
+
+	frames preparedFrames
+}
+
+// prepare returns the message serialised with the given codec, reusing the
+// prepared frame if another client with the same codec already asked for it.
+func (m *WSMessage) prepare(codec Codec) (*websocket.PreparedMessage, error) {
+	return m.frames.get(m, codec)
+}
+
+// Target names the play session a command is for. A session_id addresses
+// any session; a quiz_id addresses the quiz's default session, whose ID is
+// the quiz's.
+type Target struct {
+	QuizID    string `json:"quiz_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// Session returns the ID of the session the command is for, and so of its
+// room
+func (t Target) Session() string {
+	if t.SessionID != "" {
+		return t.SessionID
+	}
+	return t.QuizID
+}
+
+// JoinQuizPayload is the payload of the join_quiz command
+type JoinQuizPayload struct {
+	Target
+}
+
+// ResumePayload is the payload of the resume command. LastSeq is the
+// sequence number of the last event the client received for the session.
+type ResumePayload struct {
+	Target
+	LastSeq uint64 `json:"last_seq"`
+}
+
+// HostCommandPayload is the payload of every host command. Fields beyond
+// the target only apply to some commands.
+type HostCommandPayload struct {
+	Target
+	Seconds int    `json:"seconds,omitempty"` // host_extend_timer
+	UserID  string `json:"user_id,omitempty"` // host_kick
+	Locked  *bool  `json:"locked,omitempty"`  // host_lock_lobby, defaults to true
+}
+
+// ErrorPayload is sent with EventError
+type ErrorPayload struct {
+	Message      string `json:"message"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"` // Set when the client is sending too fast
 }
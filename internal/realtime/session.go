@@ -1 +1,79 @@
 package realtime
+
+import "time"
+
+const (
+	// Number of recent events kept per quiz for replay on resume.
+	replayBufferSize = 256
+
+	// How long an idle stream with no subscribers is kept around.
+	streamIdleTTL = 30 * time.Minute
+)
+
+// quizStream holds the event sequence of a single quiz: a monotonically
+// increasing counter and a ring buffer of the most recent events, so clients
+// that lost their connection can be caught up with what they missed.
+//
+// A stream is not safe for concurrent use; the Hub guards it with its lock.
+type quizStream struct {
+	seq uint64
+
+	// Ring buffer of recent events, oldest at head.
+	events []*WSMessage
+	head   int
+	size   int
+
+	lastActivity time.Time
+}
+
+func newQuizStream(capacity int) *quizStream {
+	return &quizStream{
+		events:       make([]*WSMessage, capacity),
+		lastActivity: time.Now(),
+	}
+}
+
+// append assigns the next sequence number to the message and stores it.
+func (s *quizStream) append(message *WSMessage) {
+	s.seq++
+	message.Seq = s.seq
+	s.lastActivity = time.Now()
+
+	if s.size < len(s.events) {
+		s.events[(s.head+s.size)%len(s.events)] = message
+		s.size++
+		return
+	}
+	// Buffer is full: overwrite the oldest event.
+	s.events[s.head] = message
+	s.head = (s.head + 1) % len(s.events)
+}
+
+// since returns the buffered events with a sequence number greater than
+// lastSeq that are visible to userID. ok is false when the gap cannot be
+// filled, either because older events have already been evicted or because
+// the stream was restarted.
+func (s *quizStream) since(lastSeq uint64, userID string) (missed []*WSMessage, ok bool) {
+	if lastSeq == s.seq {
+		return nil, true
+	}
+	// A client ahead of the stream saw events from before a server restart.
+	if lastSeq > s.seq {
+		return nil, false
+	}
+	if s.size == 0 || s.events[s.head].Seq > lastSeq+1 {
+		return nil, false
+	}
+
+	for i := 0; i < s.size; i++ {
+		msg := s.events[(s.head+i)%len(s.events)]
+		if msg.Seq <= lastSeq {
+			continue
+		}
+		if msg.UserID != "" && msg.UserID != userID {
+			continue
+		}
+		missed = append(missed, msg)
+	}
+	return missed, true
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/nguyen1302/realtime-quiz/internal/models"
@@ -10,7 +11,64 @@ import (
 
 type AnswerRepository interface {
 	Create(ctx context.Context, answer *models.Answer) error
-	HasAnswered(ctx context.Context, quizID, questionID, userID uuid.UUID) (bool, error)
+	HasAnswered(ctx context.Context, sessionID, questionID, userID uuid.UUID) (bool, error)
+	ListByUser(ctx context.Context, sessionID, userID uuid.UUID) ([]models.Answer, error)
+	ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	CountByOption(ctx context.Context, sessionID, questionID uuid.UUID) (map[string]int64, error)
+	MedianResponseTime(ctx context.Context, sessionID, questionID uuid.UUID) (*float64, error)
+	FastestCorrect(ctx context.Context, sessionID, questionID uuid.UUID) (*models.Answer, error)
+	SummarizeQuestions(ctx context.Context, sessionID uuid.UUID) ([]QuestionSummary, error)
+	CountOptionsByQuestion(ctx context.Context, sessionID uuid.UUID) ([]OptionCount, error)
+	SummarizeParticipants(ctx context.Context, sessionID uuid.UUID) ([]ParticipantTotals, error)
+	CountOptionsByGroup(ctx context.Context, sessionID uuid.UUID, groupSize int) ([]GroupOptionCount, error)
+	StreamScorePoints(ctx context.Context, sessionID uuid.UUID, fn func(userID uuid.UUID, point models.ScorePoint) error) error
+	StreamResults(ctx context.Context, sessionID uuid.UUID, fn func(*models.ParticipantResult) error) error
+	SummarizeSessions(ctx context.Context, quizID uuid.UUID) ([]SessionTotals, error)
+}
+
+// QuestionSummary aggregates the answers to one question in a session
+type QuestionSummary struct {
+	QuestionID    uuid.UUID
+	Responses     int64
+	Correct       int64
+	AvgResponseMs *float64
+}
+
+// OptionCount is how many answers one option of a question received
+type OptionCount struct {
+	QuestionID uuid.UUID
+	Answer     string
+	Count      int64
+}
+
+// ParticipantTotals aggregates one player's answers in a session. Players
+// with equal scores share a rank.
+type ParticipantTotals struct {
+	UserID   uuid.UUID
+	Rank     int
+	Score    int
+	Answered int64
+	Correct  int64
+}
+
+// GroupOptionCount is how many players of the upper and lower score groups
+// gave one answer to a question, and how many of those answers were correct
+type GroupOptionCount struct {
+	QuestionID   uuid.UUID
+	Answer       string
+	UpperCount   int64
+	LowerCount   int64
+	UpperCorrect int64
+	LowerCorrect int64
+}
+
+// SessionTotals aggregates the answers of one session of a quiz
+type SessionTotals struct {
+	SessionID    uuid.UUID
+	Participants int64
+	Answers      int64
+	Correct      int64
+	Points       int64
 }
 
 type answerRepository struct {
@@ -25,13 +83,283 @@ func (r *answerRepository) Create(ctx context.Context, answer *models.Answer) er
 	return r.db.WithContext(ctx).Create(answer).Error
 }
 
-func (r *answerRepository) HasAnswered(ctx context.Context, quizID, questionID, userID uuid.UUID) (bool, error) {
+func (r *answerRepository) HasAnswered(ctx context.Context, sessionID, questionID, userID uuid.UUID) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).Model(&models.Answer{}).
-		Where("quiz_id = ? AND question_id = ? AND user_id = ?", quizID, questionID, userID).
+		Where("session_id = ? AND question_id = ? AND user_id = ?", sessionID, questionID, userID).
 		Count(&count).Error
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
+
+// ListByUser returns the user's answers in a session, most recent first
+func (r *answerRepository) ListByUser(ctx context.Context, sessionID, userID uuid.UUID) ([]models.Answer, error) {
+	var answers []models.Answer
+	err := r.db.WithContext(ctx).
+		Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Order("created_at desc").
+		Find(&answers).Error
+	if err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// ListSessionsByUser returns the sessions the user answered in
+func (r *answerRepository) ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var sessionIDs []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Where("user_id = ?", userID).
+		Distinct("session_id").
+		Pluck("session_id", &sessionIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessionIDs, nil
+}
+
+// CountByOption returns how many answers each submitted option received
+func (r *answerRepository) CountByOption(ctx context.Context, sessionID, questionID uuid.UUID) (map[string]int64, error) {
+	var rows []struct {
+		Answer string
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Select("answer, COUNT(*) AS count").
+		Where("session_id = ? AND question_id = ?", sessionID, questionID).
+		Group("answer").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Answer] = row.Count
+	}
+	return counts, nil
+}
+
+// MedianResponseTime returns the median recorded response time of a
+// question, or nil if none was recorded
+func (r *answerRepository) MedianResponseTime(ctx context.Context, sessionID, questionID uuid.UUID) (*float64, error) {
+	var median *float64
+	times := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Where("session_id = ? AND question_id = ? AND response_time_ms IS NOT NULL", sessionID, questionID)
+	if r.db.Dialector.Name() == "postgres" {
+		err := times.Select("percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time_ms)").Row().Scan(&median)
+		return median, err
+	}
+
+	// SQLite, which the tests run on, has no percentile_cont: average the
+	// middle one or two times instead
+	ranked := times.Select("response_time_ms, ROW_NUMBER() OVER (ORDER BY response_time_ms) AS rn, COUNT(*) OVER () AS n")
+	err := r.db.WithContext(ctx).Table("(?) AS t", ranked).
+		Select("AVG(response_time_ms)").
+		Where("rn IN ((n + 1) / 2, (n + 2) / 2)").
+		Row().Scan(&median)
+	return median, err
+}
+
+// FastestCorrect returns the correct answer with the lowest response time, or
+// nil if there is none
+func (r *answerRepository) FastestCorrect(ctx context.Context, sessionID, questionID uuid.UUID) (*models.Answer, error) {
+	var answer models.Answer
+	err := r.db.WithContext(ctx).
+		Where("session_id = ? AND question_id = ? AND is_correct = ? AND response_time_ms IS NOT NULL", sessionID, questionID, true).
+		Order("response_time_ms asc").
+		First(&answer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &answer, nil
+}
+
+// SummarizeQuestions returns response counts, correct counts and average
+// response time for every answered question in a session
+func (r *answerRepository) SummarizeQuestions(ctx context.Context, sessionID uuid.UUID) ([]QuestionSummary, error) {
+	var rows []QuestionSummary
+	err := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Select("question_id, COUNT(*) AS responses, "+
+			"SUM(CASE WHEN is_correct THEN 1 ELSE 0 END) AS correct, "+
+			"AVG(response_time_ms) AS avg_response_ms").
+		Where("session_id = ?", sessionID).
+		Group("question_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// CountOptionsByQuestion returns how many answers each submitted option
+// received, for every question in a session
+func (r *answerRepository) CountOptionsByQuestion(ctx context.Context, sessionID uuid.UUID) ([]OptionCount, error) {
+	var rows []OptionCount
+	err := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Select("question_id, answer, COUNT(*) AS count").
+		Where("session_id = ?", sessionID).
+		Group("question_id, answer").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// SummarizeParticipants returns every player's totals in a session, highest
+// score first
+func (r *answerRepository) SummarizeParticipants(ctx context.Context, sessionID uuid.UUID) ([]ParticipantTotals, error) {
+	var rows []ParticipantTotals
+	err := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Select("user_id, RANK() OVER (ORDER BY SUM(points) DESC) AS rank, SUM(points) AS score, "+
+			"COUNT(*) AS answered, SUM(CASE WHEN is_correct THEN 1 ELSE 0 END) AS correct").
+		Where("session_id = ? AND "+countedAnswers, sessionID).
+		Group("user_id").
+		Order("score DESC, user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// CountOptionsByGroup counts the answers of the groupSize highest and
+// groupSize lowest scoring players of a session, per question and option.
+// Ties are broken by user ID, in the order SummarizeParticipants lists them.
+func (r *answerRepository) CountOptionsByGroup(ctx context.Context, sessionID uuid.UUID, groupSize int) ([]GroupOptionCount, error) {
+	var rows []GroupOptionCount
+	err := r.db.WithContext(ctx).Raw(`
+		WITH ranked AS (
+			SELECT user_id,
+				ROW_NUMBER() OVER (ORDER BY SUM(points) DESC, user_id) AS from_top,
+				ROW_NUMBER() OVER (ORDER BY SUM(points) ASC, user_id DESC) AS from_bottom
+			FROM answers
+			WHERE session_id = ? AND deleted_at IS NULL AND `+countedAnswers+`
+			GROUP BY user_id
+		)
+		SELECT a.question_id, a.answer,
+			SUM(CASE WHEN r.from_top <= ? THEN 1 ELSE 0 END) AS upper_count,
+			SUM(CASE WHEN r.from_bottom <= ? THEN 1 ELSE 0 END) AS lower_count,
+			SUM(CASE WHEN r.from_top <= ? AND a.is_correct THEN 1 ELSE 0 END) AS upper_correct,
+			SUM(CASE WHEN r.from_bottom <= ? AND a.is_correct THEN 1 ELSE 0 END) AS lower_correct
+		FROM answers a
+		JOIN ranked r ON r.user_id = a.user_id AND (r.from_top <= ? OR r.from_bottom <= ?)
+		WHERE a.session_id = ? AND a.deleted_at IS NULL
+			AND (a.attempt_id IS NULL OR a.attempt_id IN (SELECT id FROM attempts WHERE best))
+		GROUP BY a.question_id, a.answer`,
+		sessionID, groupSize, groupSize, groupSize, groupSize, groupSize, groupSize, sessionID).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// StreamScorePoints calls fn with each counted answer of a session, reading
+// only the columns of a timeline point as rows arrive
+func (r *answerRepository) StreamScorePoints(ctx context.Context, sessionID uuid.UUID, fn func(userID uuid.UUID, point models.ScorePoint) error) error {
+	rows, err := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Select("user_id, question_id, is_correct, points, response_time_ms").
+		Where("session_id = ? AND "+countedAnswers, sessionID).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			userID uuid.UUID
+			point  = models.ScorePoint{Answered: true}
+		)
+		if err := rows.Scan(&userID, &point.QuestionID, &point.IsCorrect, &point.Points, &point.ResponseTimeMs); err != nil {
+			return err
+		}
+		if err := fn(userID, point); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamResults calls fn with each participant's results, highest score first,
+// reading rows as they arrive so that only one participant is held in memory
+func (r *answerRepository) StreamResults(ctx context.Context, sessionID uuid.UUID, fn func(*models.ParticipantResult) error) error {
+	rows, err := r.db.WithContext(ctx).Raw(`
+		SELECT t.user_id, u.username, u.email, t.score, t.correct,
+			a.question_id, a.answer, a.is_correct, a.points
+		FROM (
+			SELECT user_id, SUM(points) AS score, SUM(CASE WHEN is_correct THEN 1 ELSE 0 END) AS correct
+			FROM answers
+			WHERE session_id = ? AND deleted_at IS NULL AND `+countedAnswers+`
+			GROUP BY user_id
+		) t
+		JOIN users u ON u.id = t.user_id
+		JOIN answers a ON a.user_id = t.user_id AND a.session_id = ? AND a.deleted_at IS NULL
+			AND (a.attempt_id IS NULL OR a.attempt_id IN (SELECT id FROM attempts WHERE best))
+		ORDER BY t.score DESC, t.correct DESC, u.username, t.user_id`, sessionID, sessionID).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current *models.ParticipantResult
+	for rows.Next() {
+		var (
+			userID, questionID uuid.UUID
+			username, email    string
+			score, correct     int
+			result             models.QuestionResult
+		)
+		if err := rows.Scan(&userID, &username, &email, &score, &correct,
+			&questionID, &result.Answer, &result.IsCorrect, &result.Points); err != nil {
+			return err
+		}
+
+		if current == nil || current.UserID != userID {
+			if current != nil {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+			current = &models.ParticipantResult{
+				UserID:   userID,
+				Username: username,
+				Email:    email,
+				Score:    score,
+				Correct:  correct,
+				Answers:  make(map[uuid.UUID]models.QuestionResult),
+			}
+		}
+		current.Answers[questionID] = result
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if current != nil {
+		return fn(current)
+	}
+	return nil
+}
+
+// SummarizeSessions returns participant, answer, correct and points totals
+// for every session of a quiz that has answers
+func (r *answerRepository) SummarizeSessions(ctx context.Context, quizID uuid.UUID) ([]SessionTotals, error) {
+	var rows []SessionTotals
+	err := r.db.WithContext(ctx).Model(&models.Answer{}).
+		Select("session_id, COUNT(DISTINCT user_id) AS participants, COUNT(*) AS answers, "+
+			"SUM(CASE WHEN is_correct THEN 1 ELSE 0 END) AS correct, SUM(points) AS points").
+		Where("quiz_id = ? AND "+countedAnswers, quizID).
+		Group("session_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
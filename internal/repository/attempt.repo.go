@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"gorm.io/gorm"
+)
+
+type AttemptRepository interface {
+	Create(ctx context.Context, attempt *models.Attempt) (bool, error)
+	GetLatest(ctx context.Context, sessionID, userID uuid.UUID) (*models.Attempt, error)
+	CountByUser(ctx context.Context, sessionID, userID uuid.UUID) (int64, error)
+	Advance(ctx context.Context, attempt *models.Attempt, from int) (bool, error)
+	Answer(ctx context.Context, attempt *models.Attempt, from int, answer *models.Answer) (bool, error)
+	GetLeaderboard(ctx context.Context, sessionID uuid.UUID, limit int) ([]models.LeaderboardEntry, error)
+}
+
+// countedAnswers keeps live answers and those of each player's best attempt,
+// so a player's other attempts do not add to their results
+const countedAnswers = "(attempt_id IS NULL OR attempt_id IN (SELECT id FROM attempts WHERE best))"
+
+type attemptRepository struct {
+	db *gorm.DB
+}
+
+func NewAttemptRepository(db *gorm.DB) AttemptRepository {
+	return &attemptRepository{db: db}
+}
+
+// Create saves a new attempt and marks the user's best. It reports false
+// when the user already has an attempt with that number, started by a
+// concurrent request.
+func (r *attemptRepository) Create(ctx context.Context, attempt *models.Attempt) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return markBest(tx, attempt.SessionID, attempt.UserID)
+	})
+	if isDuplicate(r.db, err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetLatest returns the user's most recent attempt, or nil if they have none
+func (r *attemptRepository) GetLatest(ctx context.Context, sessionID, userID uuid.UUID) (*models.Attempt, error) {
+	var attempt models.Attempt
+	err := r.db.WithContext(ctx).
+		Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Order("number desc").
+		First(&attempt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *attemptRepository) CountByUser(ctx context.Context, sessionID, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Attempt{}).
+		Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// Advance saves the attempt's progress if its cursor is still at from. It
+// reports false when another request moved the cursor first.
+func (r *attemptRepository) Advance(ctx context.Context, attempt *models.Attempt, from int) (bool, error) {
+	return advance(r.db.WithContext(ctx), attempt, from)
+}
+
+// Answer saves the answer to the question at from together with the
+// attempt's progress and the user's best attempt, or nothing at all. It
+// reports false when another request moved the cursor first.
+func (r *attemptRepository) Answer(ctx context.Context, attempt *models.Attempt, from int, answer *models.Answer) (bool, error) {
+	moved := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if moved, err = advance(tx, attempt, from); err != nil || !moved {
+			return err
+		}
+		if err := tx.Create(answer).Error; err != nil {
+			return err
+		}
+		return markBest(tx, attempt.SessionID, attempt.UserID)
+	})
+	return moved && err == nil, err
+}
+
+func advance(tx *gorm.DB, attempt *models.Attempt, from int) (bool, error) {
+	result := tx.Model(&models.Attempt{}).
+		Where("id = ? AND question_index = ? AND finished_at IS NULL", attempt.ID, from).
+		Updates(map[string]interface{}{
+			"question_index":      attempt.QuestionIndex,
+			"question_started_at": attempt.QuestionStartedAt,
+			"score":               attempt.Score,
+			"correct":             attempt.Correct,
+			"total_time_ms":       attempt.TotalTimeMs,
+			"finished_at":         attempt.FinishedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// markBest flags the user's highest scoring attempt, the fastest of equals,
+// as the one that counts
+func markBest(tx *gorm.DB, sessionID, userID uuid.UUID) error {
+	var best models.Attempt
+	err := tx.Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Order("score desc, total_time_ms asc, number asc").
+		First(&best).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Attempt{}).
+		Where("session_id = ? AND user_id = ? AND id <> ?", sessionID, userID, best.ID).
+		Update("best", false).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Attempt{}).Where("id = ?", best.ID).Update("best", true).Error
+}
+
+// isDuplicate reports whether err is a unique constraint violation, in the
+// words of whichever database db talks to
+func isDuplicate(db *gorm.DB, err error) bool {
+	if err == nil {
+		return false
+	}
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// GetLeaderboard ranks players by the score of their best attempt, then by
+// the time it took
+func (r *attemptRepository) GetLeaderboard(ctx context.Context, sessionID uuid.UUID, limit int) ([]models.LeaderboardEntry, error) {
+	var rows []struct {
+		UserID      uuid.UUID
+		Username    string
+		Score       int
+		TotalTimeMs int64
+	}
+	err := r.db.WithContext(ctx).Table("attempts").
+		Select("attempts.user_id, users.username, attempts.score, attempts.total_time_ms").
+		Joins("JOIN users ON users.id = attempts.user_id").
+		Where("attempts.session_id = ? AND attempts.best", sessionID).
+		Order("attempts.score desc, attempts.total_time_ms asc, users.username").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.LeaderboardEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = models.LeaderboardEntry{
+			UserID:      row.UserID,
+			Username:    row.Username,
+			Score:       float64(row.Score),
+			Rank:        i + 1,
+			TotalTimeMs: row.TotalTimeMs,
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"gorm.io/gorm"
+)
+
+type BankRepository interface {
+	Create(ctx context.Context, question *models.BankQuestion) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.BankQuestion, error)
+	GetByIDs(ctx context.Context, ownerID uuid.UUID, ids []uuid.UUID) ([]models.BankQuestion, error)
+	Update(ctx context.Context, question *models.BankQuestion) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	Search(ctx context.Context, search models.BankSearch) ([]models.BankQuestion, int64, error)
+	Draw(ctx context.Context, ownerID uuid.UUID, tags []string, difficulty models.Difficulty, exclude []uuid.UUID, count int) ([]models.BankQuestion, error)
+	CountMatching(ctx context.Context, ownerID uuid.UUID, tags []string, difficulty models.Difficulty) (int64, error)
+	ListMatching(ctx context.Context, ownerID uuid.UUID, tags []string, difficulty models.Difficulty) ([]models.BankQuestion, error)
+	AddItem(ctx context.Context, item *models.QuizBankItem) error
+	ListItems(ctx context.Context, quizID uuid.UUID, unresolvedOnly bool) ([]models.QuizBankItem, error)
+	Materialize(ctx context.Context, quizID uuid.UUID, questions []*models.Question, orders map[uuid.UUID]int) error
+}
+
+type bankRepository struct {
+	db *gorm.DB
+}
+
+func NewBankRepository(db *gorm.DB) BankRepository {
+	return &bankRepository{db: db}
+}
+
+func (r *bankRepository) Create(ctx context.Context, question *models.BankQuestion) error {
+	return r.db.WithContext(ctx).Create(question).Error
+}
+
+func (r *bankRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.BankQuestion, error) {
+	var question models.BankQuestion
+	if err := r.db.WithContext(ctx).Preload("Tags").First(&question, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// GetByIDs returns the owner's bank questions among ids
+func (r *bankRepository) GetByIDs(ctx context.Context, ownerID uuid.UUID, ids []uuid.UUID) ([]models.BankQuestion, error) {
+	var questions []models.BankQuestion
+	if len(ids) == 0 {
+		return questions, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ? AND id IN ?", ownerID, ids).
+		Find(&questions).Error
+	if err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// Update saves the question and replaces its tags
+func (r *bankRepository) Update(ctx context.Context, question *models.BankQuestion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Save(question).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("bank_question_id = ?", question.ID).Delete(&models.BankQuestionTag{}).Error; err != nil {
+			return err
+		}
+		if len(question.Tags) == 0 {
+			return nil
+		}
+		for i := range question.Tags {
+			question.Tags[i].BankQuestionID = question.ID
+		}
+		return tx.Create(&question.Tags).Error
+	})
+}
+
+// Delete removes the question along with its tags and any draft quiz
+// references to it. Quizzes that already started keep their copy.
+func (r *bankRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bank_question_id = ? AND resolved_at IS NULL", id).Delete(&models.QuizBankItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("bank_question_id = ?", id).Delete(&models.BankQuestionTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.BankQuestion{}, "id = ?", id).Error
+	})
+}
+
+// matching scopes a query to the owner's questions with every tag and, if
+// set, the difficulty
+func (r *bankRepository) matching(ctx context.Context, ownerID uuid.UUID, tags []string, difficulty models.Difficulty) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.BankQuestion{}).Where("owner_id = ?", ownerID)
+	if difficulty != "" {
+		query = query.Where("difficulty = ?", difficulty)
+	}
+	if len(tags) > 0 {
+		tagged := r.db.Model(&models.BankQuestionTag{}).
+			Select("bank_question_id").
+			Where("tag IN ?", tags).
+			Group("bank_question_id").
+			Having("COUNT(DISTINCT tag) = ?", len(tags))
+		query = query.Where("id IN (?)", tagged)
+	}
+	return query
+}
+
+// Search returns a page of matching questions, newest first, and the total
+// number of matches
+func (r *bankRepository) Search(ctx context.Context, search models.BankSearch) ([]models.BankQuestion, int64, error) {
+	query := r.matching(ctx, search.OwnerID, search.Tags, search.Difficulty)
+	if search.Query != "" {
+		query = query.Where("LOWER(text) LIKE ?", "%"+strings.ToLower(search.Query)+"%")
+	}
+	// Reused for the count and the page
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var questions []models.BankQuestion
+	err := query.Preload("Tags").
+		Order("created_at desc, id").
+		Limit(search.Limit).
+		Offset(search.Offset).
+		Find(&questions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return questions, total, nil
+}
+
+// Draw picks up to count matching questions at random, leaving out exclude
+func (r *bankRepository) Draw(ctx context.Context, ownerID uuid.UUID, tags []string, difficulty models.Difficulty, exclude []uuid.UUID, count int) ([]models.BankQuestion, error) {
+	query := r.matching(ctx, ownerID, tags, difficulty)
+	if len(exclude) > 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+
+	var questions []models.BankQuestion
+	if err := query.Order("RANDOM()").Limit(count).Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func (r *bankRepository) CountMatching(ctx context.Context, ownerID uuid.UUID, tags []string, difficulty models.Difficulty) (int64, error) {
+	var count int64
+	err := r.matching(ctx, ownerID, tags, difficulty).Count(&count).Error
+	return count, err
+}
+
+// ListMatching returns every matching question with its tags, by text
+func (r *bankRepository) ListMatching(ctx context.Context, ownerID uuid.UUID, tags []string, difficulty models.Difficulty) ([]models.BankQuestion, error) {
+	var questions []models.BankQuestion
+	err := r.matching(ctx, ownerID, tags, difficulty).
+		Preload("Tags").
+		Order("text asc, id").
+		Find(&questions).Error
+	if err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func (r *bankRepository) AddItem(ctx context.Context, item *models.QuizBankItem) error {
+	return r.db.WithContext(ctx).Create(item).Error
+}
+
+func (r *bankRepository) ListItems(ctx context.Context, quizID uuid.UUID, unresolvedOnly bool) ([]models.QuizBankItem, error) {
+	query := r.db.WithContext(ctx).Where("quiz_id = ?", quizID)
+	if unresolvedOnly {
+		query = query.Where("resolved_at IS NULL")
+	}
+
+	var items []models.QuizBankItem
+	if err := query.Order("item_order asc, created_at asc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Materialize stores the questions resolved from the quiz's bank items,
+// renumbers the quiz's existing questions and marks the items resolved, all
+// in one transaction
+func (r *bankRepository) Materialize(ctx context.Context, quizID uuid.UUID, questions []*models.Question, orders map[uuid.UUID]int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, order := range orders {
+			if err := tx.Model(&models.Question{}).Where("id = ?", id).Update("item_order", order).Error; err != nil {
+				return err
+			}
+		}
+		if len(questions) > 0 {
+			if err := tx.Create(questions).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.QuizBankItem{}).
+			Where("quiz_id = ? AND resolved_at IS NULL", quizID).
+			Update("resolved_at", time.Now()).Error
+	})
+}
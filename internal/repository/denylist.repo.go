@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// DenylistRepository holds the token families that were ended, so their
+// access tokens stop working before they expire
+type DenylistRepository interface {
+	Deny(ctx context.Context, familyID uuid.UUID, ttl time.Duration) error
+	IsDenied(ctx context.Context, familyID uuid.UUID) (bool, error)
+}
+
+type denylistRepository struct {
+	rdb *redis.Client
+}
+
+func NewDenylistRepository(rdb *redis.Client) DenylistRepository {
+	return &denylistRepository{rdb: rdb}
+}
+
+func denylistKey(familyID uuid.UUID) string {
+	return fmt.Sprintf("auth:denylist:%s", familyID)
+}
+
+// Deny refuses the family's access tokens for ttl, the longest any of them
+// can still be valid
+func (r *denylistRepository) Deny(ctx context.Context, familyID uuid.UUID, ttl time.Duration) error {
+	return r.rdb.Set(ctx, denylistKey(familyID), 1, ttl).Err()
+}
+
+func (r *denylistRepository) IsDenied(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	n, err := r.rdb.Exists(ctx, denylistKey(familyID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"gorm.io/gorm"
+)
+
+type IdentityRepository interface {
+	Find(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	UpdateEmail(ctx context.Context, identity *models.UserIdentity, email string) error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+// Find returns the identity of the provider's subject, or nil if no user is
+// linked to it yet
+func (r *identityRepository) Find(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *identityRepository) UpdateEmail(ctx context.Context, identity *models.UserIdentity, email string) error {
+	return r.db.WithContext(ctx).Model(identity).Update("email", email).Error
+}
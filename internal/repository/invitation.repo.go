@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *models.Invitation) error
+	GetByHash(ctx context.Context, hash string) (*models.Invitation, error)
+	ListOpen(ctx context.Context, orgID uuid.UUID, now time.Time) ([]models.Invitation, error)
+	Revoke(ctx context.Context, orgID, id uuid.UUID, at time.Time) (bool, error)
+	Accept(ctx context.Context, invitation *models.Invitation, userID uuid.UUID, now time.Time) (bool, error)
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *invitationRepository) GetByHash(ctx context.Context, hash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListOpen returns the organisation's invitations that can still be
+// accepted, newest first
+func (r *invitationRepository) ListOpen(ctx context.Context, orgID uuid.UUID, now time.Time) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.WithContext(ctx).
+		Where("org_id = ? AND revoked_at IS NULL AND expires_at > ? AND uses < max_uses", orgID, now).
+		Order("created_at desc").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// Revoke stops the organisation's invitation from being accepted, reporting
+// whether it was open
+func (r *invitationRepository) Revoke(ctx context.Context, orgID, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND org_id = ? AND revoked_at IS NULL", id, orgID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Accept uses the invitation once and makes the user a member with its
+// role, in one transaction. It reports false, changing nothing, if the
+// invitation was used up, revoked or expired first. Members keep their
+// role.
+func (r *invitationRepository) Accept(ctx context.Context, invitation *models.Invitation, userID uuid.UUID, now time.Time) (bool, error) {
+	accepted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND uses < max_uses", invitation.ID, now).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		accepted = true
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Membership{
+			OrgID:  invitation.OrgID,
+			UserID: userID,
+			Role:   invitation.Role,
+		}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return accepted, nil
+}
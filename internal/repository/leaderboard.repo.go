@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,9 +12,13 @@ import (
 )
 
 type LeaderboardRepository interface {
-	GetSubmissionRank(ctx context.Context, quizID, questionID uuid.UUID) (int64, error)
-	UpdateScore(ctx context.Context, quizID uuid.UUID, userID uuid.UUID, points float64) error
-	GetLeaderboard(ctx context.Context, quizID uuid.UUID, limit int64) ([]models.LeaderboardEntry, error)
+	GetSubmissionRank(ctx context.Context, sessionID, questionID uuid.UUID) (int64, error)
+	UpdateScore(ctx context.Context, sessionID uuid.UUID, userID uuid.UUID, points float64) error
+	GetLeaderboard(ctx context.Context, sessionID uuid.UUID, limit int64) ([]models.LeaderboardEntry, error)
+	GetUserEntry(ctx context.Context, sessionID, userID uuid.UUID) (*models.LeaderboardEntry, error)
+	AddParticipant(ctx context.Context, sessionID, userID uuid.UUID) error
+	CountParticipants(ctx context.Context, sessionID uuid.UUID) (int64, error)
+	IsParticipant(ctx context.Context, sessionID, userID uuid.UUID) (bool, error)
 }
 
 type leaderboardRepository struct {
@@ -24,8 +29,14 @@ func NewLeaderboardRepository(rdb *redis.Client) LeaderboardRepository {
 	return &leaderboardRepository{rdb: rdb}
 }
 
-func (r *leaderboardRepository) GetSubmissionRank(ctx context.Context, quizID, questionID uuid.UUID) (int64, error) {
-	key := fmt.Sprintf("quiz:%s:question:%s:submissions", quizID, questionID)
+// leaderboardKey is the sorted set of a session's scores. A default session
+// shares its quiz's ID, so it keeps the key the quiz had before sessions.
+func leaderboardKey(sessionID uuid.UUID) string {
+	return fmt.Sprintf("quiz:%s:leaderboard", sessionID)
+}
+
+func (r *leaderboardRepository) GetSubmissionRank(ctx context.Context, sessionID, questionID uuid.UUID) (int64, error) {
+	key := fmt.Sprintf("quiz:%s:question:%s:submissions", sessionID, questionID)
 	// INCR returns the new value. 1st submission gets 1, 2nd gets 2, etc.
 	// We might want to set expiry on this key if it doesn't exist?
 	// But simple INCR is enough for logic.
@@ -40,8 +51,8 @@ func (r *leaderboardRepository) GetSubmissionRank(ctx context.Context, quizID, q
 	return rank, nil
 }
 
-func (r *leaderboardRepository) UpdateScore(ctx context.Context, quizID uuid.UUID, userID uuid.UUID, points float64) error {
-	key := fmt.Sprintf("quiz:%s:leaderboard", quizID)
+func (r *leaderboardRepository) UpdateScore(ctx context.Context, sessionID uuid.UUID, userID uuid.UUID, points float64) error {
+	key := leaderboardKey(sessionID)
 	// ZINCRBY updates the score
 	err := r.rdb.ZIncrBy(ctx, key, points, userID.String()).Err()
 	if err != nil {
@@ -52,8 +63,8 @@ func (r *leaderboardRepository) UpdateScore(ctx context.Context, quizID uuid.UUI
 	return nil
 }
 
-func (r *leaderboardRepository) GetLeaderboard(ctx context.Context, quizID uuid.UUID, limit int64) ([]models.LeaderboardEntry, error) {
-	key := fmt.Sprintf("quiz:%s:leaderboard", quizID)
+func (r *leaderboardRepository) GetLeaderboard(ctx context.Context, sessionID uuid.UUID, limit int64) ([]models.LeaderboardEntry, error) {
+	key := leaderboardKey(sessionID)
 	// ZREVRANGE to get top scores (highest first). WithScores to get score.
 	results, err := r.rdb.ZRevRangeWithScores(ctx, key, 0, limit-1).Result()
 	if err != nil {
@@ -74,3 +85,47 @@ func (r *leaderboardRepository) GetLeaderboard(ctx context.Context, quizID uuid.
 	}
 	return entries, nil
 }
+
+// GetUserEntry returns the user's score and rank, or nil if the user has no
+// score in this session yet.
+func (r *leaderboardRepository) GetUserEntry(ctx context.Context, sessionID, userID uuid.UUID) (*models.LeaderboardEntry, error) {
+	key := leaderboardKey(sessionID)
+	member := userID.String()
+
+	rank, err := r.rdb.ZRevRank(ctx, key, member).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	score, err := r.rdb.ZScore(ctx, key, member).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LeaderboardEntry{
+		UserID: userID,
+		Score:  score,
+		Rank:   int(rank) + 1,
+	}, nil
+}
+
+func (r *leaderboardRepository) AddParticipant(ctx context.Context, sessionID, userID uuid.UUID) error {
+	key := fmt.Sprintf("quiz:%s:participants", sessionID)
+	if err := r.rdb.SAdd(ctx, key, userID.String()).Err(); err != nil {
+		return err
+	}
+	r.rdb.Expire(ctx, key, 24*time.Hour)
+	return nil
+}
+
+func (r *leaderboardRepository) CountParticipants(ctx context.Context, sessionID uuid.UUID) (int64, error) {
+	key := fmt.Sprintf("quiz:%s:participants", sessionID)
+	return r.rdb.SCard(ctx, key).Result()
+}
+
+func (r *leaderboardRepository) IsParticipant(ctx context.Context, sessionID, userID uuid.UUID) (bool, error) {
+	key := fmt.Sprintf("quiz:%s:participants", sessionID)
+	return r.rdb.SIsMember(ctx, key, userID.String()).Result()
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+type LiveRepository interface {
+	GetState(ctx context.Context, sessionID uuid.UUID) (*models.LiveState, error)
+	SetState(ctx context.Context, sessionID uuid.UUID, state *models.LiveState) error
+	ClearState(ctx context.Context, sessionID uuid.UUID) error
+	SetLobbyLocked(ctx context.Context, sessionID uuid.UUID, locked bool) error
+	IsLobbyLocked(ctx context.Context, sessionID uuid.UUID) (bool, error)
+	Kick(ctx context.Context, sessionID, userID uuid.UUID) error
+	IsKicked(ctx context.Context, sessionID, userID uuid.UUID) (bool, error)
+	RecordAnswer(ctx context.Context, sessionID, questionID uuid.UUID, answer string) (map[string]int64, error)
+	AcquireDriver(ctx context.Context, sessionID uuid.UUID, owner string, ttl time.Duration) (bool, error)
+	ReleaseDriver(ctx context.Context, sessionID uuid.UUID, owner string) error
+}
+
+type liveRepository struct {
+	rdb *redis.Client
+}
+
+func NewLiveRepository(rdb *redis.Client) LiveRepository {
+	return &liveRepository{rdb: rdb}
+}
+
+func liveStateKey(sessionID uuid.UUID) string {
+	return fmt.Sprintf("quiz:%s:live", sessionID)
+}
+
+// GetState returns the live state of the session, or nil if no question is open
+func (r *liveRepository) GetState(ctx context.Context, sessionID uuid.UUID) (*models.LiveState, error) {
+	data, err := r.rdb.Get(ctx, liveStateKey(sessionID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state models.LiveState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *liveRepository) SetState(ctx context.Context, sessionID uuid.UUID, state *models.LiveState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// Same lifetime as the leaderboard
+	return r.rdb.Set(ctx, liveStateKey(sessionID), data, 24*time.Hour).Err()
+}
+
+func (r *liveRepository) ClearState(ctx context.Context, sessionID uuid.UUID) error {
+	return r.rdb.Del(ctx, liveStateKey(sessionID)).Err()
+}
+
+func (r *liveRepository) SetLobbyLocked(ctx context.Context, sessionID uuid.UUID, locked bool) error {
+	key := fmt.Sprintf("quiz:%s:locked", sessionID)
+	if !locked {
+		return r.rdb.Del(ctx, key).Err()
+	}
+	return r.rdb.Set(ctx, key, 1, 24*time.Hour).Err()
+}
+
+func (r *liveRepository) IsLobbyLocked(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	key := fmt.Sprintf("quiz:%s:locked", sessionID)
+	n, err := r.rdb.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *liveRepository) Kick(ctx context.Context, sessionID, userID uuid.UUID) error {
+	key := fmt.Sprintf("quiz:%s:kicked", sessionID)
+	if err := r.rdb.SAdd(ctx, key, userID.String()).Err(); err != nil {
+		return err
+	}
+	r.rdb.Expire(ctx, key, 24*time.Hour)
+	return nil
+}
+
+func (r *liveRepository) IsKicked(ctx context.Context, sessionID, userID uuid.UUID) (bool, error) {
+	key := fmt.Sprintf("quiz:%s:kicked", sessionID)
+	return r.rdb.SIsMember(ctx, key, userID.String()).Result()
+}
+
+// RecordAnswer counts a submission towards the question's answer
+// distribution and returns the updated counts per option.
+func (r *liveRepository) RecordAnswer(ctx context.Context, sessionID, questionID uuid.UUID, answer string) (map[string]int64, error) {
+	key := fmt.Sprintf("quiz:%s:question:%s:distribution", sessionID, questionID)
+
+	pipe := r.rdb.TxPipeline()
+	pipe.HIncrBy(ctx, key, answer, 1)
+	pipe.Expire(ctx, key, 24*time.Hour)
+	all := pipe.HGetAll(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(all.Val()))
+	for option, value := range all.Val() {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		counts[option] = n
+	}
+	return counts, nil
+}
+
+func driverKey(sessionID uuid.UUID) string {
+	return fmt.Sprintf("quiz:%s:scheduler", sessionID)
+}
+
+// Takes the lock if it is free, or renews it if owner already holds it
+var acquireDriverScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// Deletes the lock only if owner holds it
+var releaseDriverScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireDriver elects owner as the one replica driving a scheduled session.
+// The lock lapses after ttl unless owner renews it by calling again, so
+// another replica takes over if this one dies.
+func (r *liveRepository) AcquireDriver(ctx context.Context, sessionID uuid.UUID, owner string, ttl time.Duration) (bool, error) {
+	n, err := acquireDriverScript.Run(ctx, r.rdb, []string{driverKey(sessionID)}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *liveRepository) ReleaseDriver(ctx context.Context, sessionID uuid.UUID, owner string) error {
+	return releaseDriverScript.Run(ctx, r.rdb, []string{driverKey(sessionID)}, owner).Err()
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LockoutRepository tracks failed logins per account and the locks they
+// lead to
+type LockoutRepository interface {
+	LockedFor(ctx context.Context, account string) (time.Duration, error)
+	AddFailure(ctx context.Context, account string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, account string, memory time.Duration, lockFor func(n int) time.Duration) (time.Duration, error)
+	Reset(ctx context.Context, account string) error
+}
+
+type lockoutRepository struct {
+	rdb *redis.Client
+}
+
+func NewLockoutRepository(rdb *redis.Client) LockoutRepository {
+	return &lockoutRepository{rdb: rdb}
+}
+
+func lockoutKey(kind, account string) string {
+	return fmt.Sprintf("auth:%s:%s", kind, account)
+}
+
+// LockedFor returns how much longer the account is locked, or 0
+func (r *lockoutRepository) LockedFor(ctx context.Context, account string) (time.Duration, error) {
+	ttl, err := r.rdb.PTTL(ctx, lockoutKey("lockout", account)).Result()
+	if err != nil || ttl < 0 {
+		// -2 for no lock; -1 cannot happen as locks are always set to expire
+		return 0, err
+	}
+	return ttl, nil
+}
+
+// AddFailure counts a failed login and returns the failures in the window,
+// which starts at the first of them
+func (r *lockoutRepository) AddFailure(ctx context.Context, account string, window time.Duration) (int64, error) {
+	key := lockoutKey("failures", account)
+	pipe := r.rdb.TxPipeline()
+	pipe.SetNX(ctx, key, 0, window)
+	count := pipe.Incr(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// Lock locks the account and clears its failures. The lock lasts
+// lockFor(n) for the nth lock in a row; the count is forgotten after memory
+// passes without a lock.
+func (r *lockoutRepository) Lock(ctx context.Context, account string, memory time.Duration, lockFor func(n int) time.Duration) (time.Duration, error) {
+	countKey := lockoutKey("lockouts", account)
+	pipe := r.rdb.TxPipeline()
+	n := pipe.Incr(ctx, countKey)
+	pipe.Expire(ctx, countKey, memory)
+	pipe.Del(ctx, lockoutKey("failures", account))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	lock := lockFor(int(n.Val()))
+	if err := r.rdb.Set(ctx, lockoutKey("lockout", account), 1, lock).Err(); err != nil {
+		return 0, err
+	}
+	return lock, nil
+}
+
+// Reset forgets the account's failures and earlier locks after a
+// successful login
+func (r *lockoutRepository) Reset(ctx context.Context, account string) error {
+	return r.rdb.Del(ctx, lockoutKey("failures", account), lockoutKey("lockouts", account)).Err()
+}
@@ -8,29 +8,74 @@ import (
 // Repository is the interface for the repository manager
 type Repository interface {
 	User() UserRepository
+	Token() TokenRepository
+	UserToken() UserTokenRepository
+	Identity() IdentityRepository
+	OIDCState() OIDCStateRepository
+	RateLimit() RateLimitRepository
+	Lockout() LockoutRepository
+	Denylist() DenylistRepository
+	Org() OrgRepository
+	Invitation() InvitationRepository
 	Quiz() QuizRepository
+	Session() SessionRepository
 	Question() QuestionRepository
 	Leaderboard() LeaderboardRepository
 	Answer() AnswerRepository
+	Live() LiveRepository
+	Report() ReportRepository
+	Bank() BankRepository
+	Attempt() AttemptRepository
+	Practice() PracticeRepository
 }
 
 // repositoryImpl is the concrete implementation of Repository
 type repositoryImpl struct {
 	user        UserRepository
+	token       TokenRepository
+	userToken   UserTokenRepository
+	identity    IdentityRepository
+	oidcState   OIDCStateRepository
+	rateLimit   RateLimitRepository
+	lockout     LockoutRepository
+	denylist    DenylistRepository
+	org         OrgRepository
+	invitation  InvitationRepository
 	quiz        QuizRepository
+	session     SessionRepository
 	question    QuestionRepository
 	leaderboard LeaderboardRepository
 	answer      AnswerRepository
+	live        LiveRepository
+	report      ReportRepository
+	bank        BankRepository
+	attempt     AttemptRepository
+	practice    PracticeRepository
 }
 
 // NewRepository creates a new instance of Repository
 func NewRepository(db *gorm.DB, rdb *redis.Client) Repository {
 	return &repositoryImpl{
 		user:        NewUserRepository(db),
+		token:       NewTokenRepository(db),
+		userToken:   NewUserTokenRepository(db),
+		identity:    NewIdentityRepository(db),
+		oidcState:   NewOIDCStateRepository(rdb),
+		rateLimit:   NewRateLimitRepository(rdb),
+		lockout:     NewLockoutRepository(rdb),
+		denylist:    NewDenylistRepository(rdb),
+		org:         NewOrgRepository(db),
+		invitation:  NewInvitationRepository(db),
 		quiz:        NewQuizRepository(db),
+		session:     NewSessionRepository(db),
 		question:    NewQuestionRepository(db),
 		leaderboard: NewLeaderboardRepository(rdb),
 		answer:      NewAnswerRepository(db),
+		live:        NewLiveRepository(rdb),
+		report:      NewReportRepository(rdb),
+		bank:        NewBankRepository(db),
+		attempt:     NewAttemptRepository(db),
+		practice:    NewPracticeRepository(db),
 	}
 }
 
@@ -38,10 +83,50 @@ func (r *repositoryImpl) User() UserRepository {
 	return r.user
 }
 
+func (r *repositoryImpl) Token() TokenRepository {
+	return r.token
+}
+
+func (r *repositoryImpl) UserToken() UserTokenRepository {
+	return r.userToken
+}
+
+func (r *repositoryImpl) Identity() IdentityRepository {
+	return r.identity
+}
+
+func (r *repositoryImpl) OIDCState() OIDCStateRepository {
+	return r.oidcState
+}
+
+func (r *repositoryImpl) RateLimit() RateLimitRepository {
+	return r.rateLimit
+}
+
+func (r *repositoryImpl) Lockout() LockoutRepository {
+	return r.lockout
+}
+
+func (r *repositoryImpl) Denylist() DenylistRepository {
+	return r.denylist
+}
+
+func (r *repositoryImpl) Org() OrgRepository {
+	return r.org
+}
+
+func (r *repositoryImpl) Invitation() InvitationRepository {
+	return r.invitation
+}
+
 func (r *repositoryImpl) Quiz() QuizRepository {
 	return r.quiz
 }
 
+func (r *repositoryImpl) Session() SessionRepository {
+	return r.session
+}
+
 func (r *repositoryImpl) Question() QuestionRepository {
 	return r.question
 }
@@ -53,3 +138,23 @@ func (r *repositoryImpl) Leaderboard() LeaderboardRepository {
 func (r *repositoryImpl) Answer() AnswerRepository {
 	return r.answer
 }
+
+func (r *repositoryImpl) Live() LiveRepository {
+	return r.live
+}
+
+func (r *repositoryImpl) Report() ReportRepository {
+	return r.report
+}
+
+func (r *repositoryImpl) Bank() BankRepository {
+	return r.bank
+}
+
+func (r *repositoryImpl) Attempt() AttemptRepository {
+	return r.attempt
+}
+
+func (r *repositoryImpl) Practice() PracticeRepository {
+	return r.practice
+}
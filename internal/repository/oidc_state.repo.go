@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// OIDCStateRepository keeps started OpenID Connect logins by their state
+// parameter. Each can be taken once, so a callback cannot be replayed.
+type OIDCStateRepository interface {
+	Save(ctx context.Context, state string, login *models.OIDCLogin, ttl time.Duration) error
+	Take(ctx context.Context, state string) (*models.OIDCLogin, error)
+}
+
+type oidcStateRepository struct {
+	rdb *redis.Client
+}
+
+func NewOIDCStateRepository(rdb *redis.Client) OIDCStateRepository {
+	return &oidcStateRepository{rdb: rdb}
+}
+
+func oidcStateKey(state string) string {
+	return fmt.Sprintf("auth:oidc:%s", state)
+}
+
+func (r *oidcStateRepository) Save(ctx context.Context, state string, login *models.OIDCLogin, ttl time.Duration) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(ctx, oidcStateKey(state), data, ttl).Err()
+}
+
+// Take returns and forgets the login, or returns nil if it is unknown or
+// expired
+func (r *oidcStateRepository) Take(ctx context.Context, state string) (*models.OIDCLogin, error) {
+	data, err := r.rdb.GetDel(ctx, oidcStateKey(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var login models.OIDCLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
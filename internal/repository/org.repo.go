@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"gorm.io/gorm"
+)
+
+type OrgRepository interface {
+	Create(ctx context.Context, org *models.Organization, adminID uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error)
+	GetPersonal(ctx context.Context, userID uuid.UUID) (*models.Organization, error)
+	Rename(ctx context.Context, id uuid.UUID, name string) error
+	GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*models.Membership, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Membership, error)
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.Membership, error)
+	SetRole(ctx context.Context, orgID, userID uuid.UUID, role models.OrgRole) error
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	CountAdmins(ctx context.Context, orgID uuid.UUID) (int64, error)
+	ListSoleAdmin(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+}
+
+type orgRepository struct {
+	db *gorm.DB
+}
+
+func NewOrgRepository(db *gorm.DB) OrgRepository {
+	return &orgRepository{db: db}
+}
+
+// Create saves the organisation with adminID as its first admin, in one
+// transaction
+func (r *orgRepository) Create(ctx context.Context, org *models.Organization, adminID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createOrg(tx, org, adminID)
+	})
+}
+
+func createOrg(tx *gorm.DB, org *models.Organization, adminID uuid.UUID) error {
+	if err := tx.Create(org).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.Membership{OrgID: org.ID, UserID: adminID, Role: models.OrgRoleAdmin}).Error
+}
+
+func (r *orgRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&org).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetPersonal returns the organisation made with the user's account
+func (r *orgRepository) GetPersonal(ctx context.Context, userID uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	if err := r.db.WithContext(ctx).Where("personal_user_id = ?", userID).First(&org).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *orgRepository) Rename(ctx context.Context, id uuid.UUID, name string) error {
+	return r.db.WithContext(ctx).Model(&models.Organization{}).Where("id = ?", id).Update("name", name).Error
+}
+
+func (r *orgRepository) GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*models.Membership, error) {
+	var membership models.Membership
+	if err := r.db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, userID).First(&membership).Error; err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// ListByUser returns the user's memberships with their organisations,
+// oldest first
+func (r *orgRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.WithContext(ctx).
+		Preload("Organization").
+		Where("user_id = ?", userID).
+		Order("created_at asc").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// ListMembers returns the organisation's memberships with their users,
+// oldest first
+func (r *orgRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("org_id = ?", orgID).
+		Order("created_at asc").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (r *orgRepository) SetRole(ctx context.Context, orgID, userID uuid.UUID, role models.OrgRole) error {
+	return r.db.WithContext(ctx).Model(&models.Membership{}).
+		Where("org_id = ? AND user_id = ?", orgID, userID).
+		Update("role", role).Error
+}
+
+func (r *orgRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&models.Membership{}).Error
+}
+
+func (r *orgRepository) CountAdmins(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Membership{}).
+		Where("org_id = ? AND role = ?", orgID, models.OrgRoleAdmin).
+		Count(&count).Error
+	return count, err
+}
+
+// ListSoleAdmin returns the organisations that other members share, where
+// the user is the only admin
+func (r *orgRepository) ListSoleAdmin(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	var orgs []models.Organization
+	admins := r.db.Model(&models.Membership{}).Select("org_id").
+		Where("role = ?", models.OrgRoleAdmin).
+		Group("org_id").
+		Having("COUNT(*) = 1")
+	others := r.db.Model(&models.Membership{}).Select("org_id").Where("user_id <> ?", userID)
+	err := r.db.WithContext(ctx).
+		Joins("JOIN memberships ON memberships.org_id = organizations.id").
+		Where("memberships.user_id = ? AND memberships.role = ?", userID, models.OrgRoleAdmin).
+		Where("organizations.id IN (?) AND organizations.id IN (?)", admins, others).
+		Find(&orgs).Error
+	if err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"gorm.io/gorm"
+)
+
+type PracticeRepository interface {
+	Record(ctx context.Context, answer *models.PracticeAnswer) (*models.Mastery, error)
+	ListMastery(ctx context.Context, quizID, userID uuid.UUID) ([]models.Mastery, error)
+}
+
+type practiceRepository struct {
+	db *gorm.DB
+}
+
+func NewPracticeRepository(db *gorm.DB) PracticeRepository {
+	return &practiceRepository{db: db}
+}
+
+// Record saves a practice answer and adds it to the user's mastery of the
+// question, returning the updated mastery
+func (r *practiceRepository) Record(ctx context.Context, answer *models.PracticeAnswer) (*models.Mastery, error) {
+	var mastery models.Mastery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(answer).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("user_id = ? AND question_id = ?", answer.UserID, answer.QuestionID).First(&mastery).Error
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			mastery = models.Mastery{
+				UserID:     answer.UserID,
+				QuestionID: answer.QuestionID,
+				QuizID:     answer.QuizID,
+			}
+		}
+		mastery.Record(answer.IsCorrect, answer.CreatedAt)
+		return tx.Save(&mastery).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mastery, nil
+}
+
+// ListMastery returns the user's mastery of each question of the quiz they
+// have practised, weakest first
+func (r *practiceRepository) ListMastery(ctx context.Context, quizID, userID uuid.UUID) ([]models.Mastery, error) {
+	var masteries []models.Mastery
+	err := r.db.WithContext(ctx).
+		Where("quiz_id = ? AND user_id = ?", quizID, userID).
+		Order("level asc, last_practiced_at asc").
+		Find(&masteries).Error
+	if err != nil {
+		return nil, err
+	}
+	return masteries, nil
+}
@@ -10,8 +10,12 @@ import (
 
 type QuestionRepository interface {
 	Create(ctx context.Context, question *models.Question) error
-	GetByQuizID(ctx context.Context, quizID uuid.UUID) ([]models.Question, error)
+	GetByQuizID(ctx context.Context, quizID uuid.UUID, version int) ([]models.Question, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Question, error)
+	GetRevision(ctx context.Context, quizID uuid.UUID, version int, previousID uuid.UUID) (*models.Question, error)
+	Update(ctx context.Context, question *models.Question) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	MaxOrder(ctx context.Context, quizID uuid.UUID, version int) (int, error)
 }
 
 type questionRepository struct {
@@ -26,9 +30,10 @@ func (r *questionRepository) Create(ctx context.Context, question *models.Questi
 	return r.db.WithContext(ctx).Create(question).Error
 }
 
-func (r *questionRepository) GetByQuizID(ctx context.Context, quizID uuid.UUID) ([]models.Question, error) {
+// GetByQuizID returns the questions of one version of the quiz, in order
+func (r *questionRepository) GetByQuizID(ctx context.Context, quizID uuid.UUID, version int) ([]models.Question, error) {
 	var questions []models.Question
-	if err := r.db.WithContext(ctx).Where("quiz_id = ?", quizID).Order("item_order asc").Find(&questions).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("quiz_id = ? AND version = ?", quizID, version).Order("item_order asc").Find(&questions).Error; err != nil {
 		return nil, err
 	}
 	return questions, nil
@@ -41,3 +46,35 @@ func (r *questionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 	}
 	return &question, nil
 }
+
+// GetRevision returns the question of the given version that was copied from
+// previousID
+func (r *questionRepository) GetRevision(ctx context.Context, quizID uuid.UUID, version int, previousID uuid.UUID) (*models.Question, error) {
+	var question models.Question
+	err := r.db.WithContext(ctx).
+		Where("quiz_id = ? AND version = ? AND previous_id = ?", quizID, version, previousID).
+		First(&question).Error
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+func (r *questionRepository) Update(ctx context.Context, question *models.Question) error {
+	return r.db.WithContext(ctx).Save(question).Error
+}
+
+func (r *questionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.Question{}, "id = ?", id).Error
+}
+
+// MaxOrder returns the highest order of the questions in a version of the
+// quiz, or 0 if it has none
+func (r *questionRepository) MaxOrder(ctx context.Context, quizID uuid.UUID, version int) (int, error) {
+	var order int
+	err := r.db.WithContext(ctx).Model(&models.Question{}).
+		Select("COALESCE(MAX(item_order), 0)").
+		Where("quiz_id = ? AND version = ?", quizID, version).
+		Scan(&order).Error
+	return order, err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nguyen1302/realtime-quiz/internal/models"
@@ -10,8 +11,13 @@ import (
 
 type QuizRepository interface {
 	Create(ctx context.Context, quiz *models.Quiz) error
+	CreateWithBank(ctx context.Context, quiz *models.Quiz, bank []models.BankQuestion) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Quiz, error)
-	GetByCode(ctx context.Context, code string) (*models.Quiz, error)
+	ListByOrg(ctx context.Context, orgID uuid.UUID) ([]models.Quiz, error)
+	SetAllowClone(ctx context.Context, id uuid.UUID, allow bool) error
+	MarkPlayed(ctx context.Context, id uuid.UUID, version int) error
+	ForkVersion(ctx context.Context, id uuid.UUID, from int) (int, error)
+	AppendQuestions(ctx context.Context, quiz *models.Quiz, questions []*models.Question) error
 }
 
 type quizRepository struct {
@@ -26,18 +32,135 @@ func (r *quizRepository) Create(ctx context.Context, quiz *models.Quiz) error {
 	return r.db.WithContext(ctx).Create(quiz).Error
 }
 
+// CreateWithBank saves the bank questions, then the quiz with its questions
+// and bank items, in one transaction
+func (r *quizRepository) CreateWithBank(ctx context.Context, quiz *models.Quiz, bank []models.BankQuestion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(bank) > 0 {
+			if err := tx.Create(&bank).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(quiz).Error
+	})
+}
+
 func (r *quizRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Quiz, error) {
 	var quiz models.Quiz
-	if err := r.db.WithContext(ctx).Preload("Questions").Where("id = ?", id).First(&quiz).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&quiz).Error; err != nil {
+		return nil, err
+	}
+	// Only the latest version's questions
+	err := r.db.WithContext(ctx).
+		Where("quiz_id = ? AND version = ?", quiz.ID, quiz.Version).
+		Order("item_order asc").
+		Find(&quiz.Questions).Error
+	if err != nil {
 		return nil, err
 	}
 	return &quiz, nil
 }
 
-func (r *quizRepository) GetByCode(ctx context.Context, code string) (*models.Quiz, error) {
-	var quiz models.Quiz
-	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&quiz).Error; err != nil {
+// ListByOrg returns the organisation's quizzes, newest first, without their
+// questions
+func (r *quizRepository) ListByOrg(ctx context.Context, orgID uuid.UUID) ([]models.Quiz, error) {
+	var quizzes []models.Quiz
+	err := r.db.WithContext(ctx).
+		Where("org_id = ?", orgID).
+		Order("created_at desc").
+		Find(&quizzes).Error
+	if err != nil {
 		return nil, err
 	}
-	return &quiz, nil
+	return quizzes, nil
+}
+
+func (r *quizRepository) SetAllowClone(ctx context.Context, id uuid.UUID, allow bool) error {
+	return r.db.WithContext(ctx).Model(&models.Quiz{}).Where("id = ?", id).Update("allow_clone", allow).Error
+}
+
+// MarkPlayed records that a session started playing version, so that it is
+// no longer edited in place. Sessions playing an older version leave the
+// mark alone.
+func (r *quizRepository) MarkPlayed(ctx context.Context, id uuid.UUID, version int) error {
+	return r.db.WithContext(ctx).Model(&models.Quiz{}).
+		Where("id = ? AND played_version < ?", id, version).
+		Update("played_version", version).Error
+}
+
+// ForkVersion copies the questions of version from into a new version and
+// makes it the latest, in one transaction. Each copy points back at the
+// revision it came from. If another edit forked first, the version it made
+// is returned instead.
+func (r *quizRepository) ForkVersion(ctx context.Context, id uuid.UUID, from int) (int, error) {
+	var version int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		version, err = forkVersion(tx, id, from)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// AppendQuestions inserts the questions into the quiz's latest version,
+// forking it first if it has been played, all in one transaction. The quiz
+// and the questions are given the version they went to.
+func (r *quizRepository) AppendQuestions(ctx context.Context, quiz *models.Quiz, questions []*models.Question) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		version := quiz.Version
+		if quiz.Frozen() {
+			var err error
+			if version, err = forkVersion(tx, quiz.ID, quiz.Version); err != nil {
+				return err
+			}
+		}
+		for _, question := range questions {
+			question.Version = version
+		}
+		if err := tx.CreateInBatches(questions, 100).Error; err != nil {
+			return err
+		}
+		quiz.Version = version
+		return nil
+	})
+}
+
+func forkVersion(tx *gorm.DB, id uuid.UUID, from int) (int, error) {
+	version := from + 1
+	result := tx.Model(&models.Quiz{}).
+		Where("id = ? AND version = ?", id, from).
+		Update("version", version)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		var quiz models.Quiz
+		if err := tx.Select("version").Where("id = ?", id).First(&quiz).Error; err != nil {
+			return 0, err
+		}
+		return quiz.Version, nil
+	}
+
+	var questions []models.Question
+	if err := tx.Where("quiz_id = ? AND version = ?", id, from).Find(&questions).Error; err != nil {
+		return 0, err
+	}
+	if len(questions) == 0 {
+		return version, nil
+	}
+	for i := range questions {
+		previousID := questions[i].ID
+		questions[i].ID = uuid.Nil
+		questions[i].Version = version
+		questions[i].PreviousID = &previousID
+		questions[i].CreatedAt = time.Time{}
+		questions[i].UpdatedAt = time.Time{}
+	}
+	if err := tx.Create(&questions).Error; err != nil {
+		return 0, err
+	}
+	return version, nil
 }
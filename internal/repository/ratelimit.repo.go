@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitRepository counts requests in sliding windows shared by every
+// replica
+type RateLimitRepository interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error)
+}
+
+// slidingWindow keeps a sorted set of request times per key. It drops the
+// times that left the window and records the request if there is room,
+// returning 0; otherwise it returns how many milliseconds until the oldest
+// request leaves the window. Refused requests are not recorded, so waiting
+// that long always helps.
+var slidingWindow = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return 0
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return math.max(tonumber(oldest[2]) + window - now, 1)
+`)
+
+type rateLimitRepository struct {
+	rdb *redis.Client
+}
+
+func NewRateLimitRepository(rdb *redis.Client) RateLimitRepository {
+	return &rateLimitRepository{rdb: rdb}
+}
+
+// Allow records a request under the key if fewer than limit were made in
+// the window. Otherwise it returns how long until one more is allowed.
+func (r *rateLimitRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	now := time.Now().UnixMilli()
+	wait, err := slidingWindow.Run(ctx, r.rdb, []string{fmt.Sprintf("ratelimit:%s", key)},
+		now, window.Milliseconds(), limit, fmt.Sprintf("%d-%s", now, uuid.NewString())).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+type ReportRepository interface {
+	GetCached(ctx context.Context, sessionID uuid.UUID) (*models.QuizReport, error)
+	SetCached(ctx context.Context, sessionID uuid.UUID, report *models.QuizReport) error
+	Invalidate(ctx context.Context, sessionIDs []uuid.UUID) error
+}
+
+type reportRepository struct {
+	rdb *redis.Client
+}
+
+func NewReportRepository(rdb *redis.Client) ReportRepository {
+	return &reportRepository{rdb: rdb}
+}
+
+func reportKey(sessionID uuid.UUID) string {
+	return fmt.Sprintf("quiz:%s:report", sessionID)
+}
+
+// GetCached returns the cached report of the session, or nil if there is none
+func (r *reportRepository) GetCached(ctx context.Context, sessionID uuid.UUID) (*models.QuizReport, error) {
+	data, err := r.rdb.Get(ctx, reportKey(sessionID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var report models.QuizReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *reportRepository) SetCached(ctx context.Context, sessionID uuid.UUID, report *models.QuizReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(ctx, reportKey(sessionID), data, 24*time.Hour).Err()
+}
+
+// Invalidate drops the cached reports of the sessions
+func (r *reportRepository) Invalidate(ctx context.Context, sessionIDs []uuid.UUID) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, len(sessionIDs))
+	for i, id := range sessionIDs {
+		keys[i] = reportKey(id)
+	}
+	return r.rdb.Del(ctx, keys...).Err()
+}
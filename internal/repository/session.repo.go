@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	GetByCode(ctx context.Context, code string) (*models.Session, error)
+	ListByQuiz(ctx context.Context, quizID uuid.UUID) ([]models.Session, error)
+	ListDue(ctx context.Context, now time.Time) ([]models.Session, error)
+	SetSchedule(ctx context.Context, id uuid.UUID, scheduledAt, lobbyOpensAt *time.Time) error
+	MarkStarted(ctx context.Context, id uuid.UUID, version int) (bool, error)
+	MarkFinished(ctx context.Context, id uuid.UUID) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByCode returns the session with the join code, and its quiz
+func (r *sessionRepository) GetByCode(ctx context.Context, code string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).Preload("Quiz").Where("code = ?", code).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListByQuiz returns the sessions of a quiz, oldest first
+func (r *sessionRepository) ListByQuiz(ctx context.Context, quizID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("quiz_id = ?", quizID).
+		Order("created_at asc").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListDue returns the scheduled sessions that should be running: those past
+// their start time that have not finished
+func (r *sessionRepository) ListDue(ctx context.Context, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("scheduled_at IS NOT NULL AND scheduled_at <= ? AND status <> ?", now.UTC(), models.QuizStatusFinished).
+		Order("scheduled_at asc").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// SetSchedule sets when the session starts and its lobby opens; nil clears
+// the schedule
+func (r *sessionRepository) SetSchedule(ctx context.Context, id uuid.UUID, scheduledAt, lobbyOpensAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"scheduled_at":   scheduledAt,
+		"lobby_opens_at": lobbyOpensAt,
+	}).Error
+}
+
+// MarkStarted makes a draft session active and pins the version it plays,
+// reporting whether it was still a draft. Of two concurrent starts, only
+// one finds it so.
+func (r *sessionRepository) MarkStarted(ctx context.Context, id uuid.UUID, version int) (bool, error) {
+	return r.setStatus(ctx, id, models.QuizStatusDraft, models.QuizStatusActive, map[string]interface{}{
+		"version":    version,
+		"started_at": time.Now(),
+	})
+}
+
+func (r *sessionRepository) MarkFinished(ctx context.Context, id uuid.UUID) error {
+	_, err := r.setStatus(ctx, id, "", models.QuizStatusFinished, map[string]interface{}{
+		"ended_at": time.Now(),
+	})
+	return err
+}
+
+// setStatus updates the session, if it has status from (or any status when
+// from is empty), and, for a default session, the status its quiz shows. It
+// reports whether the session was updated.
+func (r *sessionRepository) setStatus(ctx context.Context, id uuid.UUID, from, status models.QuizStatus, fields map[string]interface{}) (bool, error) {
+	fields["status"] = status
+	updated := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Session{}).Where("id = ?", id)
+		if from != "" {
+			query = query.Where("status = ?", from)
+		}
+		result := query.Updates(fields)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		updated = true
+		// Matches no quiz unless this is a default session
+		return tx.Model(&models.Quiz{}).Where("id = ?", id).Update("status", status).Error
+	})
+	return updated, err
+}
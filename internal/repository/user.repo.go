@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/nguyen1302/realtime-quiz/internal/models"
 	"gorm.io/gorm"
 )
@@ -11,8 +13,15 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindByID(ctx context.Context, id string) (*models.User, error)
+	FindByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	UpdateProfile(ctx context.Context, user *models.User) error
+	UpdateEmail(ctx context.Context, id uuid.UUID, email string) error
+	Anonymise(ctx context.Context, user *models.User) error
+	ExportData(ctx context.Context, id uuid.UUID) (*models.PersonalData, error)
 }
 
 type userRepository struct {
@@ -23,8 +32,15 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// Create saves the user with their personal organisation, in one
+// transaction
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return createOrg(tx, &models.Organization{Name: user.Username, PersonalUserID: &user.ID}, user.ID)
+	})
 }
 
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
@@ -43,6 +59,17 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (*models.User,
 	return &user, nil
 }
 
+func (r *userRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *userRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int64
 	if err := r.db.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
@@ -58,3 +85,72 @@ func (r *userRepository) ExistsByUsername(ctx context.Context, username string)
 	}
 	return count > 0, nil
 }
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("email_verified_at", at).Error
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password_hash", passwordHash).Error
+}
+
+// UpdateProfile saves the user's username, display name and avatar
+func (r *userRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Model(user).Select("username", "display_name", "avatar_url").Updates(user).Error
+}
+
+// UpdateEmail changes the address, which is unverified until the user
+// follows a new link
+func (r *userRepository) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "email_verified_at": nil}).Error
+}
+
+// Anonymise saves the user's scrubbed fields and deletes what only concerns
+// them: their logins, links, identities, memberships and practice. Their answers and
+// attempts stay, under the scrubbed user, so other players' results and
+// leaderboards are unchanged.
+func (r *userRepository) Anonymise(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).
+			Select("username", "email", "password_hash", "email_verified_at", "display_name", "avatar_url", "deleted_at").
+			Updates(user).Error
+		if err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.RefreshToken{}, &models.UserToken{}, &models.UserIdentity{}, &models.Membership{}, &models.PracticeAnswer{}, &models.Mastery{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ExportData gathers everything kept about the user
+func (r *userRepository) ExportData(ctx context.Context, id uuid.UUID) (*models.PersonalData, error) {
+	data := &models.PersonalData{ExportedAt: time.Now()}
+	db := r.db.WithContext(ctx)
+	if err := db.Where("id = ?", id).First(&data.User).Error; err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		query *gorm.DB
+		dest  interface{}
+	}{
+		{db.Where("user_id = ?", id).Order("created_at"), &data.Identities},
+		{db.Preload("Organization").Where("user_id = ?", id).Order("created_at"), &data.Memberships},
+		{db.Model(&models.Quiz{}).Where("owner_id = ?", id).Order("created_at"), &data.Quizzes},
+		{db.Where("user_id = ?", id).Order("created_at"), &data.Answers},
+		{db.Where("user_id = ?", id).Order("started_at"), &data.Attempts},
+		{db.Where("user_id = ?", id).Order("created_at"), &data.PracticeAnswers},
+		{db.Where("user_id = ?", id).Order("last_practiced_at"), &data.Mastery},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
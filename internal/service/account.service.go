@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/mailer"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidLink   = errors.New("this link is invalid or has expired")
+	ErrEmailVerified = errors.New("email is already verified")
+)
+
+// AccountService emails links to verify an address and to reset a
+// password. Each link carries a random token whose hash names a record of
+// its purpose, which lets it be used once. Only the latest link of each
+// purpose works.
+type AccountService interface {
+	SendVerification(ctx context.Context, user *models.User) error
+	RequestVerification(ctx context.Context, userID uuid.UUID) error
+	VerifyEmail(ctx context.Context, token string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	// AccountLocked warns the user that failed logins locked their account
+	AccountLocked(ctx context.Context, email string, until time.Time) error
+	// EmailChanged tells the old address the account moved, and sends the
+	// new one a verification link
+	EmailChanged(ctx context.Context, user *models.User, oldEmail string) error
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type accountService struct {
+	userRepo      repository.UserRepository
+	userTokenRepo repository.UserTokenRepository
+	tokenRepo     repository.TokenRepository
+	denylistRepo  repository.DenylistRepository
+	mailer        mailer.Mailer
+	cfg           config.AccountConfig
+	jwtConfig     config.JWTConfig
+}
+
+func NewAccountService(userRepo repository.UserRepository, userTokenRepo repository.UserTokenRepository, tokenRepo repository.TokenRepository, denylistRepo repository.DenylistRepository, mailer mailer.Mailer, cfg config.AccountConfig, jwtConfig config.JWTConfig) AccountService {
+	return &accountService{
+		userRepo:      userRepo,
+		userTokenRepo: userTokenRepo,
+		tokenRepo:     tokenRepo,
+		denylistRepo:  denylistRepo,
+		mailer:        mailer,
+		cfg:           cfg,
+		jwtConfig:     jwtConfig,
+	}
+}
+
+// SendVerification emails the user a link to verify their address
+func (s *accountService) SendVerification(ctx context.Context, user *models.User) error {
+	link, err := s.link(ctx, user, models.TokenPurposeVerifyEmail, s.cfg.VerifyTTL(), "/verify-email")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm this is your email address by opening the link below:\n\n%s\n\nThe link works once and expires in %s.",
+			user.Username, link, lifetime(s.cfg.VerifyTTL())),
+	})
+}
+
+// RequestVerification sends the user a new verification link
+func (s *accountService) RequestVerification(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.FindByID(ctx, userID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailVerified
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// VerifyEmail marks the address verified, if it is still the one the link
+// was sent to
+func (s *accountService) VerifyEmail(ctx context.Context, token string) error {
+	user, record, err := s.use(ctx, token, models.TokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+	if user.Email != record.Email {
+		return ErrInvalidLink
+	}
+	return s.userRepo.MarkEmailVerified(ctx, user.ID, time.Now())
+}
+
+// ForgotPassword emails a reset link if an account has the address. It
+// succeeds either way, so it does not reveal which addresses have accounts.
+func (s *accountService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.DeletedAt != nil {
+		return nil
+	}
+
+	link, err := s.link(ctx, user, models.TokenPurposeResetPassword, s.cfg.ResetTTL(), "/reset-password")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, choose a new one here:\n\n%s\n\nThe link works once and expires in %s. If it was not you, ignore this email.",
+			user.Username, link, lifetime(s.cfg.ResetTTL())),
+	})
+}
+
+// ResetPassword sets the new password and logs the user out everywhere
+func (s *accountService) ResetPassword(ctx context.Context, token, password string) error {
+	user, _, err := s.use(ctx, token, models.TokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hash)); err != nil {
+		return err
+	}
+
+	return logOutEverywhere(ctx, s.tokenRepo, s.denylistRepo, user.ID, s.jwtConfig.AccessTTL())
+}
+
+func (s *accountService) AccountLocked(ctx context.Context, email string, until time.Time) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account was locked",
+		Body: fmt.Sprintf("Hi %s,\n\nAfter several failed attempts to log in to your account, we have locked it until %s.\n\nIf this was not you, someone may be guessing your password. Once the lock ends, consider choosing a new one with \"Forgot password\".",
+			user.Username, until.UTC().Format("15:04 MST, 2 Jan 2006")),
+	})
+}
+
+func (s *accountService) EmailChanged(ctx context.Context, user *models.User, oldEmail string) error {
+	err := s.mailer.Send(ctx, mailer.Message{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s.\n\nIf this was not you, someone else knows your password; contact an administrator to get your account back.",
+			user.Username, user.Email),
+	})
+	if err != nil {
+		return err
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// lifetime writes how long a link works, e.g. "48 hours"
+func lifetime(d time.Duration) string {
+	if d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	return fmt.Sprintf("%d minutes", d/time.Minute)
+}
+
+// link records a token for the user and returns the front end link that
+// carries it
+func (s *accountService) link(ctx context.Context, user *models.User, purpose models.TokenPurpose, ttl time.Duration, path string) (string, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	record := &models.UserToken{
+		TokenHash: hash,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.userTokenRepo.Create(ctx, record); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(s.cfg.BaseURL, "/") + path + "?token=" + url.QueryEscape(token), nil
+}
+
+// use checks the token's purpose and expiry and uses up its record,
+// returning the user it was sent to
+func (s *accountService) use(ctx context.Context, token string, purpose models.TokenPurpose) (*models.User, *models.UserToken, error) {
+	record, err := s.userTokenRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidLink
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if record.Purpose != purpose || !now.Before(record.ExpiresAt) {
+		return nil, nil, ErrInvalidLink
+	}
+
+	used, err := s.userTokenRepo.Use(ctx, record.ID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		return nil, nil, ErrInvalidLink
+	}
+
+	user, err := s.userRepo.FindByID(ctx, record.UserID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidLink
+		}
+		return nil, nil, err
+	}
+	return user, record, nil
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+)
+
+var (
+	ErrNotSelfPaced     = errors.New("session is not self-paced")
+	ErrOutsideWindow    = errors.New("the quiz is not open for attempts now")
+	ErrNoAttemptsLeft   = errors.New("you have no attempts left")
+	ErrAttemptConflict  = errors.New("another attempt was started at the same time")
+	ErrAttemptNotFound  = errors.New("you have not started this quiz")
+	ErrAttemptFinished  = errors.New("your attempt is finished")
+	ErrNotCurrentAnswer = errors.New("answer the current question of your attempt")
+)
+
+// AttemptService runs self-paced sessions, where each player moves through
+// the questions on their own. A question's timer starts when it is served;
+// a question left past its timer scores nothing and the next one is served.
+type AttemptService interface {
+	StartAttempt(ctx context.Context, sessionID, userID uuid.UUID) (*models.AttemptState, error)
+	CurrentAttempt(ctx context.Context, sessionID, userID uuid.UUID) (*models.AttemptState, error)
+	SubmitAnswer(ctx context.Context, session *models.Session, question *models.Question, input SubmitAnswerInput) (*models.Answer, error)
+	GetLeaderboard(ctx context.Context, sessionID uuid.UUID) ([]models.LeaderboardEntry, error)
+}
+
+type attemptService struct {
+	attemptRepo  repository.AttemptRepository
+	sessionRepo  repository.SessionRepository
+	quizRepo     repository.QuizRepository
+	questionRepo repository.QuestionRepository
+	liveRepo     repository.LiveRepository
+}
+
+func NewAttemptService(attemptRepo repository.AttemptRepository, sessionRepo repository.SessionRepository, quizRepo repository.QuizRepository, questionRepo repository.QuestionRepository, liveRepo repository.LiveRepository) AttemptService {
+	return &attemptService{
+		attemptRepo:  attemptRepo,
+		sessionRepo:  sessionRepo,
+		quizRepo:     quizRepo,
+		questionRepo: questionRepo,
+		liveRepo:     liveRepo,
+	}
+}
+
+// StartAttempt begins the player's next attempt, or resumes the one they
+// have not finished
+func (s *attemptService) StartAttempt(ctx context.Context, sessionID, userID uuid.UUID) (*models.AttemptState, error) {
+	session, questions, err := s.selfPacedSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !session.Available(now) {
+		return nil, ErrOutsideWindow
+	}
+
+	latest, err := s.attemptRepo.GetLatest(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && !latest.Finished() {
+		if err := s.expire(ctx, latest, questions, now); err != nil {
+			return nil, err
+		}
+		if !latest.Finished() {
+			return s.state(session, latest, questions, now), nil
+		}
+	}
+
+	count, err := s.attemptRepo.CountByUser(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.MaxAttempts > 0 && int(count) >= session.MaxAttempts {
+		return nil, ErrNoAttemptsLeft
+	}
+
+	attempt := &models.Attempt{
+		SessionID:         sessionID,
+		UserID:            userID,
+		Number:            int(count) + 1,
+		QuestionStartedAt: now,
+		StartedAt:         now,
+	}
+	// Of two concurrent starts, only one gets the number
+	created, err := s.attemptRepo.Create(ctx, attempt)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAttemptConflict
+	}
+	return s.state(session, attempt, questions, now), nil
+}
+
+// CurrentAttempt returns the player's latest attempt with the question they
+// are on
+func (s *attemptService) CurrentAttempt(ctx context.Context, sessionID, userID uuid.UUID) (*models.AttemptState, error) {
+	session, questions, err := s.selfPacedSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt, err := s.attemptRepo.GetLatest(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		return nil, ErrAttemptNotFound
+	}
+
+	now := time.Now()
+	if err := s.expire(ctx, attempt, questions, now); err != nil {
+		return nil, err
+	}
+	return s.state(session, attempt, questions, now), nil
+}
+
+// SubmitAnswer answers the current question of the player's attempt. Points
+// depend on how quickly the player answered within the question's own
+// timer, not on how others did.
+func (s *attemptService) SubmitAnswer(ctx context.Context, session *models.Session, question *models.Question, input SubmitAnswerInput) (*models.Answer, error) {
+	now := time.Now()
+	if !session.Available(now) {
+		return nil, ErrOutsideWindow
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, session.QuizID, session.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt, err := s.attemptRepo.GetLatest(ctx, session.ID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		return nil, ErrAttemptNotFound
+	}
+
+	if err := s.expire(ctx, attempt, questions, now); err != nil {
+		return nil, err
+	}
+	if attempt.Finished() {
+		return nil, ErrAttemptFinished
+	}
+	if questions[attempt.QuestionIndex].ID != question.ID {
+		return nil, ErrNotCurrentAnswer
+	}
+
+	elapsed := now.Sub(attempt.QuestionStartedAt)
+	responseTime := elapsed.Milliseconds()
+	answer := &models.Answer{
+		QuizID:         session.QuizID,
+		SessionID:      session.ID,
+		AttemptID:      &attempt.ID,
+		QuestionID:     question.ID,
+		UserID:         input.UserID,
+		Answer:         input.Answer,
+		IsCorrect:      question.CorrectAnswer == input.Answer,
+		ResponseTimeMs: &responseTime,
+	}
+	if answer.IsCorrect {
+		answer.Points = selfPacedPoints(question, elapsed)
+		attempt.Score += answer.Points
+		attempt.Correct++
+	}
+	attempt.TotalTimeMs += responseTime
+
+	// Moving the cursor only from the question answered turns a second
+	// submission into a conflict
+	from := attempt.QuestionIndex
+	s.next(attempt, len(questions), now)
+	moved, err := s.attemptRepo.Answer(ctx, attempt, from, answer)
+	if err != nil {
+		return nil, err
+	}
+	if !moved {
+		return nil, ErrAlreadyAnswered
+	}
+	return answer, nil
+}
+
+func (s *attemptService) GetLeaderboard(ctx context.Context, sessionID uuid.UUID) ([]models.LeaderboardEntry, error) {
+	return s.attemptRepo.GetLeaderboard(ctx, sessionID, 10)
+}
+
+// selfPacedSession returns the session with the questions it plays, for a
+// player who may take it
+func (s *attemptService) selfPacedSession(ctx context.Context, sessionID, userID uuid.UUID) (*models.Session, []models.Question, error) {
+	session, _, err := findSession(ctx, s.sessionRepo, s.quizRepo, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !session.SelfPaced() {
+		return nil, nil, ErrNotSelfPaced
+	}
+
+	kicked, err := s.liveRepo.IsKicked(ctx, sessionID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kicked {
+		return nil, nil, ErrKicked
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, session.QuizID, session.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, questions, nil
+}
+
+// expire moves an unfinished attempt past a question whose timer has run
+// out, counting the whole time limit. The next question's timer starts now.
+func (s *attemptService) expire(ctx context.Context, attempt *models.Attempt, questions []models.Question, now time.Time) error {
+	if attempt.Finished() {
+		return nil
+	}
+	limit := time.Duration(questions[attempt.QuestionIndex].TimeLimit) * time.Second
+	if now.Before(attempt.QuestionStartedAt.Add(limit)) {
+		return nil
+	}
+
+	from := attempt.QuestionIndex
+	attempt.TotalTimeMs += limit.Milliseconds()
+	s.next(attempt, len(questions), now)
+	moved, err := s.attemptRepo.Advance(ctx, attempt, from)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		// Another request got there first; use what it saved
+		latest, err := s.attemptRepo.GetLatest(ctx, attempt.SessionID, attempt.UserID)
+		if err != nil {
+			return err
+		}
+		*attempt = *latest
+	}
+	return nil
+}
+
+// next serves the attempt's next question, finishing it after the last
+func (s *attemptService) next(attempt *models.Attempt, total int, now time.Time) {
+	attempt.QuestionIndex++
+	attempt.QuestionStartedAt = now
+	if attempt.QuestionIndex >= total {
+		attempt.FinishedAt = &now
+	}
+}
+
+func (s *attemptService) state(session *models.Session, attempt *models.Attempt, questions []models.Question, now time.Time) *models.AttemptState {
+	state := &models.AttemptState{Attempt: attempt}
+	if session.MaxAttempts > 0 {
+		left := session.MaxAttempts - attempt.Number
+		state.AttemptsLeft = &left
+	}
+	if !attempt.Finished() {
+		question := &questions[attempt.QuestionIndex]
+		timer := &models.LiveState{
+			QuestionID:    question.ID,
+			QuestionIndex: attempt.QuestionIndex,
+			StartedAt:     attempt.QuestionStartedAt,
+			EndsAt:        attempt.QuestionStartedAt.Add(time.Duration(question.TimeLimit) * time.Second),
+		}
+		state.CurrentQuestion = models.NewLiveQuestion(question, timer, len(questions), now)
+	}
+	return state
+}
+
+// selfPacedPoints gives half the question's points for a correct answer and
+// the other half in proportion to the time left on its timer
+func selfPacedPoints(question *models.Question, elapsed time.Duration) int {
+	maxPoints := float64(question.Points)
+	if maxPoints == 0 {
+		maxPoints = 1000 // Same fallback as live play
+	}
+
+	limit := time.Duration(question.TimeLimit) * time.Second
+	left := 1.0
+	if limit > 0 {
+		left = 1 - float64(elapsed)/float64(limit)
+		if left < 0 {
+			left = 0
+		}
+	}
+	return int(maxPoints/2 + maxPoints/2*left)
+}
@@ -2,7 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,12 +24,23 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailExists        = errors.New("email already exists")
 	ErrUsernameExists     = errors.New("username already exists")
+	ErrInvalidRefresh     = errors.New("invalid or expired refresh token")
+	ErrRefreshReused      = errors.New("refresh token was already used, please log in again")
+	ErrTokenRevoked       = errors.New("token has been revoked")
 )
 
+// AuthService issues short-lived access tokens with a refresh token that is
+// exchanged for a new pair on every refresh. A login starts a token family;
+// logging out, or a used refresh token coming back, ends the family and
+// revokes its access tokens at once.
 type AuthService interface {
 	Register(ctx context.Context, req RegisterRequest) (*models.User, error)
-	Login(ctx context.Context, req LoginRequest) (string, *models.User, error)
-	ValidateToken(tokenString string) (*Claims, error)
+	Login(ctx context.Context, req LoginRequest) (*TokenPair, *models.User, error)
+	// LoginUser starts a token family for a user who logged in another way
+	LoginUser(ctx context.Context, user *models.User) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, claims *Claims) error
+	ValidateToken(ctx context.Context, tokenString string) (*Claims, error)
 }
 
 type RegisterRequest struct {
@@ -38,22 +54,45 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type Claims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
 	Email    string    `json:"email"`
+	FamilyID uuid.UUID `json:"fid"` // Token family of the login
 	jwt.RegisteredClaims
 }
 
+// TokenPair is what a login or refresh returns. The refresh token is shown
+// only here; the server keeps its hash.
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
 type authService struct {
-	userRepo  repository.UserRepository
-	jwtConfig config.JWTConfig
+	userRepo       repository.UserRepository
+	tokenRepo      repository.TokenRepository
+	denylistRepo   repository.DenylistRepository
+	keyService     KeyService
+	accountService AccountService
+	lockoutService LockoutService
+	jwtConfig      config.JWTConfig
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtConfig config.JWTConfig) AuthService {
+func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, denylistRepo repository.DenylistRepository, keyService KeyService, accountService AccountService, lockoutService LockoutService, jwtConfig config.JWTConfig) AuthService {
 	return &authService{
-		userRepo:  userRepo,
-		jwtConfig: jwtConfig,
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		denylistRepo:   denylistRepo,
+		keyService:     keyService,
+		accountService: accountService,
+		lockoutService: lockoutService,
+		jwtConfig:      jwtConfig,
 	}
 }
 
@@ -92,67 +131,225 @@ func (s *authService) Register(ctx context.Context, req RegisterRequest) (*model
 		return nil, err
 	}
 
+	// A mail failure must not undo the new account; the user can ask for
+	// another link
+	if err := s.accountService.SendVerification(ctx, user); err != nil {
+		slog.Error("Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, req LoginRequest) (string, *models.User, error) {
+// Login checks the password unless the account is locked out, which
+// returns a *LockoutError
+func (s *authService) Login(ctx context.Context, req LoginRequest) (*TokenPair, *models.User, error) {
+	// A locked account is refused before the costly password check
+	if err := s.lockoutService.Check(ctx, req.Email); err != nil {
+		return nil, nil, err
+	}
+
 	user, err := s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", nil, ErrInvalidCredentials
+			return nil, nil, s.failLogin(ctx, req.Email)
 		}
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return "", nil, ErrInvalidCredentials
+		return nil, nil, s.failLogin(ctx, req.Email)
+	}
+
+	if err := s.lockoutService.Succeed(ctx, req.Email); err != nil {
+		return nil, nil, err
 	}
 
-	// Generate JWT token
-	token, err := s.generateToken(user)
+	pair, err := s.LoginUser(ctx, user)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
+	}
+
+	return pair, user, nil
+}
+
+// failLogin counts the failure towards a lockout and returns the error to
+// answer with
+func (s *authService) failLogin(ctx context.Context, email string) error {
+	if err := s.lockoutService.Fail(ctx, email); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+func (s *authService) LoginUser(ctx context.Context, user *models.User) (*TokenPair, error) {
+	// Start a new token family
+	return s.issue(ctx, user, uuid.New())
+}
+
+// Refresh exchanges a refresh token for a new pair in the same family. A
+// token that was already exchanged means someone else holds a copy, so the
+// whole family is ended and both holders must log in again.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	token, err := s.tokenRepo.FindByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefresh
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil {
+		if err := s.endFamily(ctx, token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshReused
+	}
+	if !token.Usable(now) {
+		return nil, ErrInvalidRefresh
+	}
+
+	used, err := s.tokenRepo.Use(ctx, token.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		// Exchanged by a concurrent request, or the family just ended
+		if err := s.endFamily(ctx, token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshReused
+	}
+
+	user, err := s.userRepo.FindByID(ctx, token.UserID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefresh
+		}
+		return nil, err
 	}
+	return s.issue(ctx, user, token.FamilyID)
+}
 
-	return token, user, nil
+// Logout ends the token family of the access token, so neither it nor the
+// family's refresh token works again
+func (s *authService) Logout(ctx context.Context, claims *Claims) error {
+	if claims.FamilyID == uuid.Nil {
+		return nil
+	}
+	return s.endFamily(ctx, claims.FamilyID, time.Now())
 }
 
-func (s *authService) generateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(s.jwtConfig.ExpiryHours) * time.Hour)
+// endFamily revokes the family's refresh tokens and denies its access tokens
+// for as long as any of them can be valid
+func (s *authService) endFamily(ctx context.Context, familyID uuid.UUID, now time.Time) error {
+	if err := s.tokenRepo.RevokeFamily(ctx, familyID, now); err != nil {
+		return err
+	}
+	return s.denylistRepo.Deny(ctx, familyID, s.jwtConfig.AccessTTL())
+}
 
+// logOutEverywhere ends all of the user's token families
+func logOutEverywhere(ctx context.Context, tokenRepo repository.TokenRepository, denylistRepo repository.DenylistRepository, userID uuid.UUID, accessTTL time.Duration) error {
+	families, err := tokenRepo.RevokeUser(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, familyID := range families {
+		if err := denylistRepo.Deny(ctx, familyID, accessTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issue creates an access token and a refresh token in the family
+func (s *authService) issue(ctx context.Context, user *models.User, familyID uuid.UUID) (*TokenPair, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.jwtConfig.AccessTTL())
+	accessToken, err := s.generateToken(user, familyID, now, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokenRepo.Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: refreshHash,
+		ExpiresAt: now.Add(s.jwtConfig.RefreshTTL()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, ExpiresAt: expiresAt, RefreshToken: refreshToken}, nil
+}
+
+func (s *authService) generateToken(user *models.User, familyID uuid.UUID, now, expiresAt time.Time) (string, error) {
 	claims := &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Email:    user.Email,
+		FamilyID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   user.ID.String(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtConfig.Secret))
+	return s.keyService.Sign(claims)
+}
+
+// newOpaqueToken returns a random token and its hash. Refresh tokens and
+// emailed links are looked up by hash rather than verified by signature,
+// so they outlive any rotation of the signing keys.
+func newOpaqueToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashToken(token), nil
+}
+
+// hashToken is how opaque tokens are stored and looked up
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func (s *authService) ValidateToken(tokenString string) (*Claims, error) {
+// ValidateToken checks the access token's signature against its key and
+// its expiry, and that its family has not been ended
+func (s *authService) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.jwtConfig.Secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyService.Keyfunc)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if !token.Valid {
+	// Emailed links are signed by the same keys but are not access tokens
+	if !token.Valid || claims.UserID == uuid.Nil || len(claims.Audience) > 0 {
 		return nil, errors.New("invalid token")
 	}
 
+	if claims.FamilyID != uuid.Nil {
+		denied, err := s.denylistRepo.IsDenied(ctx, claims.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+		if denied {
+			return nil, ErrTokenRevoked
+		}
+	}
+
 	return claims, nil
 }
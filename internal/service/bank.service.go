@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBankQuestionNotFound   = errors.New("bank question not found")
+	ErrInvalidBankItem        = errors.New("invalid bank item")
+	ErrNotEnoughBankQuestions = errors.New("not enough bank questions match the draw")
+	ErrBankNotShared          = errors.New("bank questions come from the quiz creator's bank, so only they can add them")
+)
+
+const (
+	maxTagsPerQuestion = 20
+	maxTagLength       = 50
+	maxDrawCount       = 100
+	defaultBankPage    = 20
+	maxBankPage        = 100
+)
+
+type BankService interface {
+	CreateQuestion(ctx context.Context, ownerID uuid.UUID, input BankQuestionInput) (*models.BankQuestion, error)
+	GetQuestion(ctx context.Context, id, ownerID uuid.UUID) (*models.BankQuestion, error)
+	UpdateQuestion(ctx context.Context, id, ownerID uuid.UUID, input BankQuestionInput) (*models.BankQuestion, error)
+	DeleteQuestion(ctx context.Context, id, ownerID uuid.UUID) error
+	SearchQuestions(ctx context.Context, search models.BankSearch) ([]models.BankQuestion, int64, error)
+	AddToQuiz(ctx context.Context, quizID, ownerID uuid.UUID, input QuizBankItemInput) (*models.QuizBankItem, error)
+	ListQuizItems(ctx context.Context, quizID, userID uuid.UUID) ([]models.QuizBankItem, error)
+	ResolveQuiz(ctx context.Context, quiz *models.Quiz) error
+}
+
+type BankQuestionInput struct {
+	Text          string
+	Options       []string
+	CorrectAnswer string
+	Explanation   string
+	TimeLimit     int
+	Points        int
+	Difficulty    models.Difficulty
+	Tags          []string
+}
+
+// QuizBankItemInput adds either one bank question or a random draw
+type QuizBankItemInput struct {
+	Order          int
+	BankQuestionID *uuid.UUID
+	DrawCount      int
+	DrawTags       []string
+	DrawDifficulty models.Difficulty
+}
+
+type bankService struct {
+	bankRepo     repository.BankRepository
+	quizRepo     repository.QuizRepository
+	questionRepo repository.QuestionRepository
+	orgRepo      repository.OrgRepository
+}
+
+func NewBankService(bankRepo repository.BankRepository, quizRepo repository.QuizRepository, questionRepo repository.QuestionRepository, orgRepo repository.OrgRepository) BankService {
+	return &bankService{
+		bankRepo:     bankRepo,
+		quizRepo:     quizRepo,
+		questionRepo: questionRepo,
+		orgRepo:      orgRepo,
+	}
+}
+
+// newBankQuestion validates input with the same rules as quiz questions
+func newBankQuestion(ownerID uuid.UUID, input BankQuestionInput) (*models.BankQuestion, error) {
+	question, err := newQuestion(AddQuestionInput{
+		Text:          input.Text,
+		Options:       input.Options,
+		CorrectAnswer: input.CorrectAnswer,
+		Explanation:   input.Explanation,
+		TimeLimit:     input.TimeLimit,
+		Points:        input.Points,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Difficulty == "" {
+		input.Difficulty = models.DifficultyMedium
+	}
+	if !input.Difficulty.Valid() {
+		return nil, fmt.Errorf("%w: difficulty must be easy, medium or hard", ErrInvalidQuestion)
+	}
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	bankQuestion := &models.BankQuestion{
+		OwnerID:       ownerID,
+		Text:          question.Text,
+		Options:       question.Options,
+		CorrectAnswer: question.CorrectAnswer,
+		Explanation:   question.Explanation,
+		TimeLimit:     question.TimeLimit,
+		Points:        question.Points,
+		Difficulty:    input.Difficulty,
+	}
+	for _, tag := range tags {
+		bankQuestion.Tags = append(bankQuestion.Tags, models.BankQuestionTag{Tag: tag})
+	}
+	return bankQuestion, nil
+}
+
+// normalizeTags lower-cases, trims and de-duplicates tags
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tags are limited to %d characters", ErrInvalidQuestion, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTagsPerQuestion {
+		return nil, fmt.Errorf("%w: at most %d tags", ErrInvalidQuestion, maxTagsPerQuestion)
+	}
+	return normalized, nil
+}
+
+func (s *bankService) CreateQuestion(ctx context.Context, ownerID uuid.UUID, input BankQuestionInput) (*models.BankQuestion, error) {
+	question, err := newBankQuestion(ownerID, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.bankRepo.Create(ctx, question); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+func (s *bankService) GetQuestion(ctx context.Context, id, ownerID uuid.UUID) (*models.BankQuestion, error) {
+	question, err := s.bankRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBankQuestionNotFound
+		}
+		return nil, err
+	}
+	// Other users' questions are reported as missing
+	if question.OwnerID != ownerID {
+		return nil, ErrBankQuestionNotFound
+	}
+	return question, nil
+}
+
+// UpdateQuestion replaces a bank question. Quizzes that already started keep
+// the version they copied.
+func (s *bankService) UpdateQuestion(ctx context.Context, id, ownerID uuid.UUID, input BankQuestionInput) (*models.BankQuestion, error) {
+	existing, err := s.GetQuestion(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := newBankQuestion(ownerID, input)
+	if err != nil {
+		return nil, err
+	}
+	question.ID = existing.ID
+	question.CreatedAt = existing.CreatedAt
+
+	if err := s.bankRepo.Update(ctx, question); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+func (s *bankService) DeleteQuestion(ctx context.Context, id, ownerID uuid.UUID) error {
+	if _, err := s.GetQuestion(ctx, id, ownerID); err != nil {
+		return err
+	}
+	return s.bankRepo.Delete(ctx, id)
+}
+
+func (s *bankService) SearchQuestions(ctx context.Context, search models.BankSearch) ([]models.BankQuestion, int64, error) {
+	tags, err := normalizeTags(search.Tags)
+	if err != nil {
+		return nil, 0, err
+	}
+	search.Tags = tags
+	if search.Difficulty != "" && !search.Difficulty.Valid() {
+		return nil, 0, fmt.Errorf("%w: difficulty must be easy, medium or hard", ErrInvalidQuestion)
+	}
+	if search.Limit <= 0 {
+		search.Limit = defaultBankPage
+	}
+	search.Limit = min(search.Limit, maxBankPage)
+	search.Offset = max(search.Offset, 0)
+
+	return s.bankRepo.Search(ctx, search)
+}
+
+// draftQuiz returns the quiz if ownerID created it, may still edit it and
+// no session has played it. Its bank items are drawn from the creator's
+// bank, which other editors cannot see.
+func (s *bankService) draftQuiz(ctx context.Context, quizID, ownerID uuid.UUID) (*models.Quiz, error) {
+	quiz, err := s.quizRepo.GetByID(ctx, quizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	if err := authorizeQuiz(ctx, s.orgRepo, quiz, ownerID, models.OrgRoleEditor); err != nil {
+		return nil, err
+	}
+	if quiz.OwnerID != ownerID {
+		return nil, ErrBankNotShared
+	}
+	if quiz.PlayedVersion > 0 {
+		return nil, ErrQuizAlreadyStarted
+	}
+	return quiz, nil
+}
+
+// AddToQuiz places a bank question or a random draw in a draft quiz. Draws
+// are checked against the bank now so that mistakes surface before the quiz
+// starts, and again when it does.
+func (s *bankService) AddToQuiz(ctx context.Context, quizID, ownerID uuid.UUID, input QuizBankItemInput) (*models.QuizBankItem, error) {
+	if _, err := s.draftQuiz(ctx, quizID, ownerID); err != nil {
+		return nil, err
+	}
+
+	item := &models.QuizBankItem{QuizID: quizID, Order: input.Order}
+	switch {
+	case input.BankQuestionID != nil && input.DrawCount != 0:
+		return nil, fmt.Errorf("%w: give either a bank question or a draw, not both", ErrInvalidBankItem)
+
+	case input.BankQuestionID != nil:
+		if _, err := s.GetQuestion(ctx, *input.BankQuestionID, ownerID); err != nil {
+			return nil, err
+		}
+		item.BankQuestionID = input.BankQuestionID
+
+	case input.DrawCount > 0 && input.DrawCount <= maxDrawCount:
+		tags, err := normalizeTags(input.DrawTags)
+		if err != nil {
+			return nil, err
+		}
+		if input.DrawDifficulty != "" && !input.DrawDifficulty.Valid() {
+			return nil, fmt.Errorf("%w: difficulty must be easy, medium or hard", ErrInvalidBankItem)
+		}
+		available, err := s.bankRepo.CountMatching(ctx, ownerID, tags, input.DrawDifficulty)
+		if err != nil {
+			return nil, err
+		}
+		if available < int64(input.DrawCount) {
+			return nil, fmt.Errorf("%w: %d requested, %d available", ErrNotEnoughBankQuestions, input.DrawCount, available)
+		}
+		item.DrawCount = input.DrawCount
+		item.DrawTags = tags
+		item.DrawDifficulty = input.DrawDifficulty
+
+	default:
+		return nil, fmt.Errorf("%w: draw count must be between 1 and %d", ErrInvalidBankItem, maxDrawCount)
+	}
+
+	if err := s.bankRepo.AddItem(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *bankService) ListQuizItems(ctx context.Context, quizID, userID uuid.UUID) ([]models.QuizBankItem, error) {
+	quiz, err := s.quizRepo.GetByID(ctx, quizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	if err := authorizeQuiz(ctx, s.orgRepo, quiz, userID, models.OrgRoleEditor); err != nil {
+		return nil, err
+	}
+	return s.bankRepo.ListItems(ctx, quizID, false)
+}
+
+// ResolveQuiz turns the quiz's pending bank items into questions: chosen
+// questions are copied, then draws are filled at random without repeating a
+// question already in the quiz. Questions are renumbered 1..n in the order
+// of their slots, with authored questions before bank items on ties.
+func (s *bankService) ResolveQuiz(ctx context.Context, quiz *models.Quiz) error {
+	items, err := s.bankRepo.ListItems(ctx, quiz.ID, true)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	existing, err := s.questionRepo.GetByQuizID(ctx, quiz.ID, quiz.Version)
+	if err != nil {
+		return err
+	}
+
+	// Bank questions already in the quiz are not drawn again
+	used := make(map[uuid.UUID]bool)
+	for _, question := range existing {
+		if question.BankQuestionID != nil {
+			used[*question.BankQuestionID] = true
+		}
+	}
+
+	var chosenIDs []uuid.UUID
+	for _, item := range items {
+		if item.BankQuestionID != nil {
+			chosenIDs = append(chosenIDs, *item.BankQuestionID)
+			used[*item.BankQuestionID] = true
+		}
+	}
+	chosen, err := s.bankRepo.GetByIDs(ctx, quiz.OwnerID, chosenIDs)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]models.BankQuestion, len(chosen))
+	for _, question := range chosen {
+		byID[question.ID] = question
+	}
+
+	picked := make(map[uuid.UUID][]models.BankQuestion, len(items))
+	for _, item := range items {
+		if item.BankQuestionID != nil {
+			question, ok := byID[*item.BankQuestionID]
+			if !ok {
+				return ErrBankQuestionNotFound
+			}
+			picked[item.ID] = []models.BankQuestion{question}
+			continue
+		}
+
+		exclude := make([]uuid.UUID, 0, len(used))
+		for id := range used {
+			exclude = append(exclude, id)
+		}
+		drawn, err := s.bankRepo.Draw(ctx, quiz.OwnerID, item.DrawTags, item.DrawDifficulty, exclude, item.DrawCount)
+		if err != nil {
+			return err
+		}
+		if len(drawn) < item.DrawCount {
+			return fmt.Errorf("%w: %d requested, %d available", ErrNotEnoughBankQuestions, item.DrawCount, len(drawn))
+		}
+		for _, question := range drawn {
+			used[question.ID] = true
+		}
+		picked[item.ID] = drawn
+	}
+
+	type slot struct {
+		order    int
+		question *models.Question
+		item     *models.QuizBankItem
+	}
+	slots := make([]slot, 0, len(existing)+len(items))
+	for i := range existing {
+		slots = append(slots, slot{order: existing[i].Order, question: &existing[i]})
+	}
+	for i := range items {
+		slots = append(slots, slot{order: items[i].Order, item: &items[i]})
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].order < slots[j].order })
+
+	orders := make(map[uuid.UUID]int, len(existing))
+	var questions []*models.Question
+	order := 0
+	for _, slot := range slots {
+		if slot.question != nil {
+			order++
+			if slot.question.Order != order {
+				orders[slot.question.ID] = order
+			}
+			continue
+		}
+		for _, bankQuestion := range picked[slot.item.ID] {
+			order++
+			bankID := bankQuestion.ID
+			questions = append(questions, &models.Question{
+				QuizID:         quiz.ID,
+				Text:           bankQuestion.Text,
+				Options:        append(models.JSONB{}, bankQuestion.Options...),
+				CorrectAnswer:  bankQuestion.CorrectAnswer,
+				Explanation:    bankQuestion.Explanation,
+				TimeLimit:      bankQuestion.TimeLimit,
+				Points:         bankQuestion.Points,
+				Order:          order,
+				Version:        quiz.Version,
+				BankQuestionID: &bankID,
+			})
+		}
+	}
+
+	return s.bankRepo.Materialize(ctx, quiz.ID, questions, orders)
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnsupportedBundle = errors.New("not a quiz bundle, or from a newer version")
+	ErrInvalidBundle     = errors.New("invalid quiz bundle")
+)
+
+// mediaURL matches links in question text, options and explanations
+var mediaURL = regexp.MustCompile(`https?://[^\s"'<>()\[\]]+`)
+
+type BundleService interface {
+	ExportBundle(ctx context.Context, quizID, userID uuid.UUID) (*models.Bundle, error)
+	// ImportBundle adds the quiz to the organisation, or to the user's
+	// personal one if orgID is nil
+	ImportBundle(ctx context.Context, bundle *models.Bundle, userID, orgID uuid.UUID) (*models.Quiz, error)
+}
+
+type bundleService struct {
+	quizRepo     repository.QuizRepository
+	questionRepo repository.QuestionRepository
+	bankRepo     repository.BankRepository
+	orgRepo      repository.OrgRepository
+}
+
+func NewBundleService(quizRepo repository.QuizRepository, questionRepo repository.QuestionRepository, bankRepo repository.BankRepository, orgRepo repository.OrgRepository) BundleService {
+	return &bundleService{
+		quizRepo:     quizRepo,
+		questionRepo: questionRepo,
+		bankRepo:     bankRepo,
+		orgRepo:      orgRepo,
+	}
+}
+
+// ExportBundle returns the definition of a quiz to members of its
+// organisation
+func (s *bundleService) ExportBundle(ctx context.Context, quizID, userID uuid.UUID) (*models.Bundle, error) {
+	quiz, err := s.quizRepo.GetByID(ctx, quizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	if err := authorizeQuiz(ctx, s.orgRepo, quiz, userID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, quizID, quiz.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &models.Bundle{
+		Format:     models.BundleFormat,
+		Version:    models.BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Quiz: models.BundleQuiz{
+			Title:       quiz.Title,
+			Description: quiz.Description,
+			Settings:    models.BundleSettings{AllowClone: quiz.AllowClone},
+		},
+		Questions: make([]models.BundleQuestion, len(questions)),
+	}
+	for i, question := range questions {
+		bundle.Questions[i] = models.BundleQuestion{
+			Text:          question.Text,
+			Options:       question.Options,
+			CorrectAnswer: question.CorrectAnswer,
+			Explanation:   question.Explanation,
+			TimeLimit:     question.TimeLimit,
+			Points:        question.Points,
+			Order:         question.Order,
+		}
+		bundle.Media = append(bundle.Media, mediaIn(question.Text, question.Explanation, question.Options)...)
+	}
+	if err := s.exportBankItems(ctx, quiz, bundle); err != nil {
+		return nil, err
+	}
+	slices.Sort(bundle.Media)
+	bundle.Media = slices.Compact(bundle.Media)
+	return bundle, nil
+}
+
+// exportBankItems adds the quiz's unresolved bank items to the bundle, with
+// the creator's bank questions they choose or may draw
+func (s *bundleService) exportBankItems(ctx context.Context, quiz *models.Quiz, bundle *models.Bundle) error {
+	items, err := s.bankRepo.ListItems(ctx, quiz.ID, true)
+	if err != nil {
+		return err
+	}
+
+	indexes := make(map[uuid.UUID]int)
+	add := func(question *models.BankQuestion) int {
+		if i, ok := indexes[question.ID]; ok {
+			return i
+		}
+		tags := make([]string, len(question.Tags))
+		for i, tag := range question.Tags {
+			tags[i] = tag.Tag
+		}
+		slices.Sort(tags)
+		indexes[question.ID] = len(bundle.Bank)
+		bundle.Bank = append(bundle.Bank, models.BundleBankQuestion{
+			Text:          question.Text,
+			Options:       question.Options,
+			CorrectAnswer: question.CorrectAnswer,
+			Explanation:   question.Explanation,
+			TimeLimit:     question.TimeLimit,
+			Points:        question.Points,
+			Difficulty:    question.Difficulty,
+			Tags:          tags,
+		})
+		bundle.Media = append(bundle.Media, mediaIn(question.Text, question.Explanation, question.Options)...)
+		return indexes[question.ID]
+	}
+
+	for _, item := range items {
+		entry := models.BundleBankItem{Order: item.Order}
+		if item.BankQuestionID != nil {
+			question, err := s.bankRepo.GetByID(ctx, *item.BankQuestionID)
+			if err != nil {
+				return err
+			}
+			index := add(question)
+			entry.Question = &index
+		} else {
+			matches, err := s.bankRepo.ListMatching(ctx, quiz.OwnerID, item.DrawTags, item.DrawDifficulty)
+			if err != nil {
+				return err
+			}
+			for i := range matches {
+				add(&matches[i])
+			}
+			entry.DrawCount = item.DrawCount
+			entry.DrawTags = item.DrawTags
+			entry.DrawDifficulty = item.DrawDifficulty
+		}
+		bundle.BankItems = append(bundle.BankItems, entry)
+	}
+	return nil
+}
+
+// mediaIn returns the URLs linked from a question, without the punctuation
+// of the sentence around them
+func mediaIn(text, explanation string, options []string) []string {
+	var media []string
+	for _, field := range append([]string{text, explanation}, options...) {
+		for _, url := range mediaURL.FindAllString(field, -1) {
+			media = append(media, strings.TrimRight(url, ".,;:!?"))
+		}
+	}
+	return media
+}
+
+// ImportBundle creates a new DRAFT quiz for userID from a bundle, with fresh
+// IDs and join code. The bundle's bank questions are copied into the user's
+// bank for its bank items to choose and draw from. Questions are validated as
+// AddQuestion and AddToQuiz would and nothing is saved unless all of them
+// pass.
+func (s *bundleService) ImportBundle(ctx context.Context, bundle *models.Bundle, userID, orgID uuid.UUID) (*models.Quiz, error) {
+	if bundle.Format != models.BundleFormat || bundle.Version < 1 || bundle.Version > models.BundleVersion {
+		return nil, ErrUnsupportedBundle
+	}
+	if bundle.Quiz.Title == "" {
+		return nil, fmt.Errorf("%w: quiz title is required", ErrInvalidBundle)
+	}
+	orgID, err := quizOrg(ctx, s.orgRepo, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateQuizCode()
+	if err != nil {
+		return nil, err
+	}
+	quiz := &models.Quiz{
+		ID:          uuid.New(),
+		Title:       bundle.Quiz.Title,
+		Description: bundle.Quiz.Description,
+		Code:        code,
+		Status:      models.QuizStatusDraft,
+		OwnerID:     userID,
+		OrgID:       orgID,
+		AllowClone:  bundle.Quiz.Settings.AllowClone,
+	}
+
+	for i, item := range bundle.Questions {
+		question, err := newQuestion(AddQuestionInput{
+			QuizID:        quiz.ID,
+			Text:          item.Text,
+			Options:       item.Options,
+			CorrectAnswer: item.CorrectAnswer,
+			Explanation:   item.Explanation,
+			TimeLimit:     item.TimeLimit,
+			Points:        item.Points,
+			Order:         item.Order,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: question %d: %w", ErrInvalidBundle, i+1, err)
+		}
+		quiz.Questions = append(quiz.Questions, *question)
+	}
+
+	bank := make([]models.BankQuestion, len(bundle.Bank))
+	for i, item := range bundle.Bank {
+		question, err := newBankQuestion(userID, BankQuestionInput{
+			Text:          item.Text,
+			Options:       item.Options,
+			CorrectAnswer: item.CorrectAnswer,
+			Explanation:   item.Explanation,
+			TimeLimit:     item.TimeLimit,
+			Points:        item.Points,
+			Difficulty:    item.Difficulty,
+			Tags:          item.Tags,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: bank question %d: %w", ErrInvalidBundle, i+1, err)
+		}
+		question.ID = uuid.New()
+		bank[i] = *question
+	}
+	for i, item := range bundle.BankItems {
+		bankItem, err := newBundleBankItem(quiz.ID, item, bank)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bank item %d: %w", ErrInvalidBundle, i+1, err)
+		}
+		quiz.BankItems = append(quiz.BankItems, *bankItem)
+	}
+
+	// The bank questions, quiz, questions and bank items are inserted in one
+	// transaction
+	if err := s.quizRepo.CreateWithBank(ctx, quiz, bank); err != nil {
+		return nil, err
+	}
+	return quiz, nil
+}
+
+// newBundleBankItem validates a bundle's bank item against its bank
+// questions, which are all the importing user's bank is sure to hold
+func newBundleBankItem(quizID uuid.UUID, item models.BundleBankItem, bank []models.BankQuestion) (*models.QuizBankItem, error) {
+	bankItem := &models.QuizBankItem{QuizID: quizID, Order: item.Order}
+	switch {
+	case item.Question != nil && item.DrawCount != 0:
+		return nil, fmt.Errorf("%w: give either a bank question or a draw, not both", ErrInvalidBankItem)
+
+	case item.Question != nil:
+		if *item.Question < 0 || *item.Question >= len(bank) {
+			return nil, fmt.Errorf("%w: no bank question %d", ErrInvalidBankItem, *item.Question)
+		}
+		bankItem.BankQuestionID = &bank[*item.Question].ID
+
+	case item.DrawCount > 0 && item.DrawCount <= maxDrawCount:
+		tags, err := normalizeTags(item.DrawTags)
+		if err != nil {
+			return nil, err
+		}
+		if item.DrawDifficulty != "" && !item.DrawDifficulty.Valid() {
+			return nil, fmt.Errorf("%w: difficulty must be easy, medium or hard", ErrInvalidBankItem)
+		}
+		available := 0
+		for _, question := range bank {
+			if matchesDraw(&question, tags, item.DrawDifficulty) {
+				available++
+			}
+		}
+		if available < item.DrawCount {
+			return nil, fmt.Errorf("%w: %d requested, %d available", ErrNotEnoughBankQuestions, item.DrawCount, available)
+		}
+		bankItem.DrawCount = item.DrawCount
+		bankItem.DrawTags = tags
+		bankItem.DrawDifficulty = item.DrawDifficulty
+
+	default:
+		return nil, fmt.Errorf("%w: draw count must be between 1 and %d", ErrInvalidBankItem, maxDrawCount)
+	}
+	return bankItem, nil
+}
+
+// matchesDraw reports whether the question has every tag and, if set, the
+// difficulty
+func matchesDraw(question *models.BankQuestion, tags []string, difficulty models.Difficulty) bool {
+	if difficulty != "" && question.Difficulty != difficulty {
+		return false
+	}
+	for _, tag := range tags {
+		if !slices.ContainsFunc(question.Tags, func(t models.BankQuestionTag) bool { return t.Tag == tag }) {
+			return false
+		}
+	}
+	return true
+}
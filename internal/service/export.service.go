@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"github.com/xuri/excelize/v2"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format, use csv, xlsx or json")
+
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportXLSX ExportFormat = "xlsx"
+	ExportJSON ExportFormat = "json"
+)
+
+type ExportService interface {
+	OpenExport(ctx context.Context, sessionID, userID uuid.UUID, format ExportFormat) (*ResultExport, error)
+}
+
+type exportService struct {
+	quizRepo     repository.QuizRepository
+	sessionRepo  repository.SessionRepository
+	questionRepo repository.QuestionRepository
+	answerRepo   repository.AnswerRepository
+	orgRepo      repository.OrgRepository
+}
+
+func NewExportService(quizRepo repository.QuizRepository, sessionRepo repository.SessionRepository, questionRepo repository.QuestionRepository, answerRepo repository.AnswerRepository, orgRepo repository.OrgRepository) ExportService {
+	return &exportService{
+		quizRepo:     quizRepo,
+		sessionRepo:  sessionRepo,
+		questionRepo: questionRepo,
+		answerRepo:   answerRepo,
+		orgRepo:      orgRepo,
+	}
+}
+
+// ResultExport is a results export that has passed its checks and is ready to
+// be streamed. Nothing is read from the answers table until WriteTo.
+type ResultExport struct {
+	quiz       *models.Quiz
+	session    *models.Session
+	questions  []models.Question
+	format     ExportFormat
+	answerRepo repository.AnswerRepository
+}
+
+// OpenExport checks that userID is an editor of the session's quiz's
+// organisation, as exports name the players and their emails, and that the
+// format is supported, so that errors can still be reported before streaming starts
+func (s *exportService) OpenExport(ctx context.Context, sessionID, userID uuid.UUID, format ExportFormat) (*ResultExport, error) {
+	switch format {
+	case ExportCSV, ExportXLSX, ExportJSON:
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	session, quiz, err := authorizedSession(ctx, s.sessionRepo, s.quizRepo, s.orgRepo, sessionID, userID, models.OrgRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, quiz.ID, session.RunVersion(quiz.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultExport{
+		quiz:       quiz,
+		session:    session,
+		questions:  questions,
+		format:     format,
+		answerRepo: s.answerRepo,
+	}, nil
+}
+
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func (e *ResultExport) Filename() string {
+	name := strings.Trim(unsafeFilename.ReplaceAllString(e.quiz.Title, "_"), "_")
+	if name == "" {
+		name = e.quiz.Code
+	}
+	// Sessions of the same quiz export to different files
+	if !e.session.Default() {
+		name += "-" + e.session.Code
+	}
+	return fmt.Sprintf("%s-results.%s", name, e.format)
+}
+
+func (e *ResultExport) ContentType() string {
+	switch e.format {
+	case ExportCSV:
+		return "text/csv; charset=utf-8"
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// WriteTo streams one row per participant, ranked by score, to w
+func (e *ResultExport) WriteTo(ctx context.Context, w io.Writer) error {
+	switch e.format {
+	case ExportCSV:
+		return e.writeCSV(ctx, w)
+	case ExportXLSX:
+		return e.writeXLSX(ctx, w)
+	default:
+		return e.writeJSON(ctx, w)
+	}
+}
+
+// stream ranks participants as they arrive; equal scores share a rank
+func (e *ResultExport) stream(ctx context.Context, fn func(*models.ParticipantResult) error) error {
+	position, lastRank, lastScore := 0, 0, 0
+	return e.answerRepo.StreamResults(ctx, e.session.ID, func(result *models.ParticipantResult) error {
+		position++
+		if position == 1 || result.Score != lastScore {
+			lastRank = position
+		}
+		lastScore = result.Score
+		result.Rank = lastRank
+		return fn(result)
+	})
+}
+
+// header is the spreadsheet header: participant columns then an answer and
+// points column per question
+func (e *ResultExport) header() []string {
+	header := []string{"rank", "username", "email", "score", "correct"}
+	for i := range e.questions {
+		header = append(header, fmt.Sprintf("q%d_answer", i+1), fmt.Sprintf("q%d_points", i+1))
+	}
+	return header
+}
+
+func (e *ResultExport) row(result *models.ParticipantResult) []interface{} {
+	row := []interface{}{result.Rank, result.Username, result.Email, result.Score, result.Correct}
+	for _, question := range e.questions {
+		if answer, ok := result.Answers[question.ID]; ok {
+			row = append(row, answer.Answer, answer.Points)
+		} else {
+			row = append(row, "", 0)
+		}
+	}
+	return row
+}
+
+func (e *ResultExport) writeCSV(ctx context.Context, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(e.header()); err != nil {
+		return err
+	}
+
+	err := e.stream(ctx, func(result *models.ParticipantResult) error {
+		values := e.row(result)
+		record := make([]string, len(values))
+		for i, value := range values {
+			switch v := value.(type) {
+			case string:
+				record[i] = csvSafe(v)
+			case int:
+				record[i] = strconv.Itoa(v)
+			}
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvSafe keeps spreadsheet apps from evaluating a player-chosen username or
+// answer as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (e *ResultExport) writeXLSX(ctx context.Context, w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	header := e.header()
+	cells := make([]interface{}, len(header))
+	for i, title := range header {
+		cells[i] = title
+	}
+	if err := sw.SetRow("A1", cells); err != nil {
+		return err
+	}
+
+	line := 1
+	err = e.stream(ctx, func(result *models.ParticipantResult) error {
+		line++
+		cell, err := excelize.CoordinatesToCellName(1, line)
+		if err != nil {
+			return err
+		}
+		return sw.SetRow(cell, e.row(result))
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// writeJSON writes the quiz and its questions, then appends participants to
+// the array one at a time
+func (e *ResultExport) writeJSON(ctx context.Context, w io.Writer) error {
+	type exportQuestion struct {
+		ID            uuid.UUID `json:"id"`
+		Order         int       `json:"order"`
+		Text          string    `json:"text"`
+		CorrectAnswer string    `json:"correct_answer"`
+	}
+	questions := make([]exportQuestion, len(e.questions))
+	for i, question := range e.questions {
+		questions[i] = exportQuestion{
+			ID:            question.ID,
+			Order:         question.Order,
+			Text:          question.Text,
+			CorrectAnswer: question.CorrectAnswer,
+		}
+	}
+
+	head, err := json.Marshal(struct {
+		QuizID    uuid.UUID        `json:"quiz_id"`
+		SessionID uuid.UUID        `json:"session_id"`
+		Title     string           `json:"title"`
+		Questions []exportQuestion `json:"questions"`
+	}{e.quiz.ID, e.session.ID, e.quiz.Title, questions})
+	if err != nil {
+		return err
+	}
+
+	// Reopen the object to add the participants array
+	if _, err := w.Write(head[:len(head)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"participants":[`); err != nil {
+		return err
+	}
+
+	first := true
+	err = e.stream(ctx, func(result *models.ParticipantResult) error {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrQuizNotFound       = errors.New("quiz not found")
+	ErrQuizAlreadyStarted = errors.New("quiz has already started")
+	ErrQuizNotActive      = errors.New("quiz is not running")
+	ErrNoQuestions        = errors.New("quiz has no questions")
+	ErrNoOpenQuestion     = errors.New("no question is open")
+	ErrQuizPaused         = errors.New("quiz is paused")
+	ErrQuizNotPaused      = errors.New("quiz is not paused")
+	ErrInvalidDuration    = errors.New("seconds must be between 1 and 300")
+	ErrLobbyLocked        = errors.New("the lobby is locked")
+	ErrKicked             = errors.New("you have been removed from this quiz")
+	ErrQuestionClosed     = errors.New("question is not open for answers")
+	ErrUnknownCommand     = errors.New("unknown host command")
+)
+
+// Longest a host can extend a question's timer in one go
+const maxTimerExtension = 300
+
+type HostService interface {
+	AuthorizeHost(ctx context.Context, sessionID, hostID uuid.UUID) (*models.Session, error)
+	Start(ctx context.Context, sessionID, hostID uuid.UUID) error
+	Pause(ctx context.Context, sessionID, hostID uuid.UUID) error
+	Resume(ctx context.Context, sessionID, hostID uuid.UUID) error
+	SkipQuestion(ctx context.Context, sessionID, hostID uuid.UUID) error
+	ExtendTimer(ctx context.Context, sessionID, hostID uuid.UUID, seconds int) error
+	RevealAnswer(ctx context.Context, sessionID, hostID uuid.UUID) error
+	KickPlayer(ctx context.Context, sessionID, hostID, userID uuid.UUID) error
+	LockLobby(ctx context.Context, sessionID, hostID uuid.UUID, locked bool) error
+}
+
+type hostService struct {
+	quizRepo        repository.QuizRepository
+	sessionRepo     repository.SessionRepository
+	questionRepo    repository.QuestionRepository
+	leaderboardRepo repository.LeaderboardRepository
+	liveRepo        repository.LiveRepository
+	orgRepo         repository.OrgRepository
+	bankService     BankService
+	statsService    StatsService
+	realtimeService RealtimeService
+}
+
+func NewHostService(quizRepo repository.QuizRepository, sessionRepo repository.SessionRepository, questionRepo repository.QuestionRepository, leaderboardRepo repository.LeaderboardRepository, liveRepo repository.LiveRepository, orgRepo repository.OrgRepository, bankService BankService, statsService StatsService, realtimeService RealtimeService) HostService {
+	return &hostService{
+		quizRepo:        quizRepo,
+		sessionRepo:     sessionRepo,
+		questionRepo:    questionRepo,
+		leaderboardRepo: leaderboardRepo,
+		liveRepo:        liveRepo,
+		orgRepo:         orgRepo,
+		bankService:     bankService,
+		statsService:    statsService,
+		realtimeService: realtimeService,
+	}
+}
+
+// AuthorizeHost returns the session if hostID may host its quiz
+func (s *hostService) AuthorizeHost(ctx context.Context, sessionID, hostID uuid.UUID) (*models.Session, error) {
+	session, _, err := authorizedSession(ctx, s.sessionRepo, s.quizRepo, s.orgRepo, sessionID, hostID, models.OrgRoleHost)
+	return session, err
+}
+
+// Start runs the session on the quiz's latest version, which stays pinned
+// for the rest of the session
+func (s *hostService) Start(ctx context.Context, sessionID, hostID uuid.UUID) error {
+	session, quiz, err := authorizedSession(ctx, s.sessionRepo, s.quizRepo, s.orgRepo, sessionID, hostID, models.OrgRoleHost)
+	if err != nil {
+		return err
+	}
+	if session.Status != models.QuizStatusDraft {
+		return ErrQuizAlreadyStarted
+	}
+
+	// Bank questions are copied in now so later bank edits leave the quiz alone
+	if err := s.bankService.ResolveQuiz(ctx, quiz); err != nil {
+		return err
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, quiz.ID, quiz.Version)
+	if err != nil {
+		return err
+	}
+	if len(questions) == 0 {
+		return ErrNoQuestions
+	}
+
+	// The session keeps this version; later edits go to a new one
+	if err := s.quizRepo.MarkPlayed(ctx, quiz.ID, quiz.Version); err != nil {
+		return err
+	}
+	// Another host or the scheduler may have started it since it was read
+	started, err := s.sessionRepo.MarkStarted(ctx, sessionID, quiz.Version)
+	if err != nil {
+		return err
+	}
+	if !started {
+		return ErrQuizAlreadyStarted
+	}
+
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), realtime.EventQuizStarted, models.QuizStartedEvent{
+		QuizID:         quiz.ID,
+		SessionID:      sessionID,
+		TotalQuestions: len(questions),
+	})
+
+	return s.openQuestion(ctx, sessionID, questions, 0)
+}
+
+func (s *hostService) Pause(ctx context.Context, sessionID, hostID uuid.UUID) error {
+	_, state, err := s.liveState(ctx, sessionID, hostID)
+	if err != nil {
+		return err
+	}
+	if state.Paused {
+		return ErrQuizPaused
+	}
+
+	state.RemainingMs = state.TimeRemaining(time.Now()).Milliseconds()
+	state.Paused = true
+	return s.saveAndBroadcastTimer(ctx, sessionID, state, realtime.EventQuizPaused)
+}
+
+func (s *hostService) Resume(ctx context.Context, sessionID, hostID uuid.UUID) error {
+	_, state, err := s.liveState(ctx, sessionID, hostID)
+	if err != nil {
+		return err
+	}
+	if !state.Paused {
+		return ErrQuizNotPaused
+	}
+
+	state.EndsAt = time.Now().Add(time.Duration(state.RemainingMs) * time.Millisecond)
+	state.Paused = false
+	state.RemainingMs = 0
+	return s.saveAndBroadcastTimer(ctx, sessionID, state, realtime.EventQuizResumed)
+}
+
+// SkipQuestion closes the current question and opens the next one, ending the
+// quiz after the last question.
+func (s *hostService) SkipQuestion(ctx context.Context, sessionID, hostID uuid.UUID) error {
+	session, state, err := s.liveState(ctx, sessionID, hostID)
+	if err != nil {
+		return err
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, session.QuizID, session.Version)
+	if err != nil {
+		return err
+	}
+
+	next := state.QuestionIndex + 1
+	if next >= len(questions) {
+		return s.finish(ctx, sessionID)
+	}
+	return s.openQuestion(ctx, sessionID, questions, next)
+}
+
+func (s *hostService) ExtendTimer(ctx context.Context, sessionID, hostID uuid.UUID, seconds int) error {
+	if seconds < 1 || seconds > maxTimerExtension {
+		return ErrInvalidDuration
+	}
+
+	_, state, err := s.liveState(ctx, sessionID, hostID)
+	if err != nil {
+		return err
+	}
+
+	extension := time.Duration(seconds) * time.Second
+	if state.Paused {
+		state.RemainingMs += extension.Milliseconds()
+	} else {
+		// An expired question is reopened for the extension
+		now := time.Now()
+		if state.EndsAt.Before(now) {
+			state.EndsAt = now
+		}
+		state.EndsAt = state.EndsAt.Add(extension)
+	}
+	return s.saveAndBroadcastTimer(ctx, sessionID, state, realtime.EventTimerExtended)
+}
+
+// RevealAnswer closes the current question, shows its correct answer and
+// how the room answered
+func (s *hostService) RevealAnswer(ctx context.Context, sessionID, hostID uuid.UUID) error {
+	_, state, err := s.liveState(ctx, sessionID, hostID)
+	if err != nil {
+		return err
+	}
+
+	question, err := s.questionRepo.GetByID(ctx, state.QuestionID)
+	if err != nil {
+		return err
+	}
+
+	state.Revealed = true
+	if err := s.liveRepo.SetState(ctx, sessionID, state); err != nil {
+		return err
+	}
+
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), realtime.EventAnswerRevealed, models.AnswerRevealedEvent{
+		QuestionID:    question.ID,
+		CorrectAnswer: question.CorrectAnswer,
+	})
+
+	stats, err := s.statsService.GetQuestionStats(ctx, sessionID, question.ID)
+	if err != nil {
+		return err
+	}
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), realtime.EventQuestionStats, stats)
+	return nil
+}
+
+// KickPlayer removes a player from the quiz and keeps them from rejoining
+func (s *hostService) KickPlayer(ctx context.Context, sessionID, hostID, userID uuid.UUID) error {
+	if _, err := s.AuthorizeHost(ctx, sessionID, hostID); err != nil {
+		return err
+	}
+
+	if err := s.liveRepo.Kick(ctx, sessionID, userID); err != nil {
+		return err
+	}
+
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), realtime.EventPlayerKicked, models.PlayerKickedEvent{UserID: userID})
+	s.realtimeService.RemoveFromQuiz(sessionID.String(), userID.String())
+	return nil
+}
+
+// LockLobby stops (or allows again) new players joining the quiz
+func (s *hostService) LockLobby(ctx context.Context, sessionID, hostID uuid.UUID, locked bool) error {
+	if _, err := s.AuthorizeHost(ctx, sessionID, hostID); err != nil {
+		return err
+	}
+
+	if err := s.liveRepo.SetLobbyLocked(ctx, sessionID, locked); err != nil {
+		return err
+	}
+
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), realtime.EventLobbyLocked, models.LobbyLockedEvent{Locked: locked})
+	return nil
+}
+
+// liveState authorises the host and returns the state of the running session
+func (s *hostService) liveState(ctx context.Context, sessionID, hostID uuid.UUID) (*models.Session, *models.LiveState, error) {
+	session, err := s.AuthorizeHost(ctx, sessionID, hostID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.Status != models.QuizStatusActive {
+		return nil, nil, ErrQuizNotActive
+	}
+
+	state, err := s.liveRepo.GetState(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if state == nil {
+		return nil, nil, ErrNoOpenQuestion
+	}
+	return session, state, nil
+}
+
+func (s *hostService) openQuestion(ctx context.Context, sessionID uuid.UUID, questions []models.Question, index int) error {
+	question := &questions[index]
+	now := time.Now()
+	state := &models.LiveState{
+		QuestionID:    question.ID,
+		QuestionIndex: index,
+		StartedAt:     now,
+		EndsAt:        now.Add(time.Duration(question.TimeLimit) * time.Second),
+	}
+
+	if err := s.liveRepo.SetState(ctx, sessionID, state); err != nil {
+		return err
+	}
+
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), realtime.EventQuestion, models.NewLiveQuestion(question, state, len(questions), now))
+	return nil
+}
+
+func (s *hostService) finish(ctx context.Context, sessionID uuid.UUID) error {
+	if err := s.sessionRepo.MarkFinished(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.liveRepo.ClearState(ctx, sessionID); err != nil {
+		return err
+	}
+
+	rankings, err := s.leaderboardRepo.GetLeaderboard(ctx, sessionID, 10)
+	if err != nil {
+		return err
+	}
+
+	event := models.QuizEndedEvent{FinalRankings: rankings}
+	if len(rankings) > 0 {
+		event.Winner = &rankings[0]
+	}
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), realtime.EventQuizEnded, event)
+	return nil
+}
+
+func (s *hostService) saveAndBroadcastTimer(ctx context.Context, sessionID uuid.UUID, state *models.LiveState, eventType string) error {
+	if err := s.liveRepo.SetState(ctx, sessionID, state); err != nil {
+		return err
+	}
+
+	s.realtimeService.BroadcastToQuiz(sessionID.String(), eventType, models.TimerEvent{
+		QuestionID:      state.QuestionID,
+		EndsAt:          state.EndsAt,
+		TimeRemainingMs: state.TimeRemaining(time.Now()).Milliseconds(),
+		Paused:          state.Paused,
+	})
+	return nil
+}
+
+// hostController adapts HostService to realtime.HostController
+type hostController struct {
+	host HostService
+}
+
+func (c hostController) AuthorizeHost(ctx context.Context, sessionID, userID string) error {
+	sid, hostID, err := parseIDs(sessionID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = c.host.AuthorizeHost(ctx, sid, hostID)
+	return clientError(err)
+}
+
+func (c hostController) HandleHostCommand(ctx context.Context, userID, command string, payload realtime.HostCommandPayload) error {
+	sessionID, hostID, err := parseIDs(payload.Session(), userID)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case realtime.CommandHostStart:
+		err = c.host.Start(ctx, sessionID, hostID)
+	case realtime.CommandHostPause:
+		err = c.host.Pause(ctx, sessionID, hostID)
+	case realtime.CommandHostResume:
+		err = c.host.Resume(ctx, sessionID, hostID)
+	case realtime.CommandHostSkip:
+		err = c.host.SkipQuestion(ctx, sessionID, hostID)
+	case realtime.CommandHostExtendTimer:
+		err = c.host.ExtendTimer(ctx, sessionID, hostID, payload.Seconds)
+	case realtime.CommandHostReveal:
+		err = c.host.RevealAnswer(ctx, sessionID, hostID)
+	case realtime.CommandHostKick:
+		playerID, parseErr := uuid.Parse(payload.UserID)
+		if parseErr != nil {
+			return errors.New("host_kick requires a valid user_id")
+		}
+		err = c.host.KickPlayer(ctx, sessionID, hostID, playerID)
+	case realtime.CommandHostLockLobby:
+		locked := payload.Locked == nil || *payload.Locked
+		err = c.host.LockLobby(ctx, sessionID, hostID, locked)
+	default:
+		err = ErrUnknownCommand
+	}
+	return clientError(err)
+}
+
+func parseIDs(sessionID, userID string) (uuid.UUID, uuid.UUID, error) {
+	sid, err := uuid.Parse(sessionID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid quiz_id or session_id")
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid user")
+	}
+	return sid, uid, nil
+}
+
+// clientErrors are safe to show to socket clients as they are
+var clientErrors = []error{
+	ErrNotAllowed, ErrQuizNotFound, ErrSessionNotFound, ErrQuizAlreadyStarted, ErrQuizNotActive, ErrNoQuestions,
+	ErrNoOpenQuestion, ErrQuizPaused, ErrQuizNotPaused, ErrInvalidDuration, ErrLobbyLocked, ErrLobbyNotOpen,
+	ErrKicked, ErrLoginRequired, ErrUnknownCommand, ErrQuestionNotFound, ErrBankQuestionNotFound, ErrNotEnoughBankQuestions,
+}
+
+// clientError hides internal errors from socket clients
+func clientError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, known := range clientErrors {
+		if errors.Is(err, known) {
+			return known
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrQuizNotFound
+	}
+	slog.Error("realtime command failed", "error", err)
+	return errors.New("internal error")
+}
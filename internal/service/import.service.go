@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/importer"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidImport = errors.New("could not read import file")
+
+type ImportService interface {
+	ImportQuestions(ctx context.Context, input ImportQuestionsInput, userID uuid.UUID) (*models.ImportReport, error)
+}
+
+type ImportQuestionsInput struct {
+	QuizID uuid.UUID
+	Format importer.Format
+	Source io.Reader
+	DryRun bool
+}
+
+type importService struct {
+	quizRepo     repository.QuizRepository
+	questionRepo repository.QuestionRepository
+	orgRepo      repository.OrgRepository
+}
+
+func NewImportService(quizRepo repository.QuizRepository, questionRepo repository.QuestionRepository, orgRepo repository.OrgRepository) ImportService {
+	return &importService{
+		quizRepo:     quizRepo,
+		questionRepo: questionRepo,
+		orgRepo:      orgRepo,
+	}
+}
+
+// ImportQuestions parses the source and validates every item as AddQuestion
+// would, for a quiz that userID may edit. Valid questions are appended after
+// the quiz's existing ones in a single transaction; the others are listed in
+// the report with their line.
+func (s *importService) ImportQuestions(ctx context.Context, input ImportQuestionsInput, userID uuid.UUID) (*models.ImportReport, error) {
+	quiz, err := s.quizRepo.GetByID(ctx, input.QuizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	if err := authorizeQuiz(ctx, s.orgRepo, quiz, userID, models.OrgRoleEditor); err != nil {
+		return nil, err
+	}
+
+	items, err := importer.Parse(input.Format, input.Source)
+	if err != nil {
+		if errors.Is(err, importer.ErrUnsupportedFormat) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+
+	order, err := s.questionRepo.MaxOrder(ctx, input.QuizID, quiz.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{
+		Rejected: []models.ImportRejection{},
+		DryRun:   input.DryRun,
+	}
+	var questions []*models.Question
+	for _, item := range items {
+		if item.Err != nil {
+			report.Rejected = append(report.Rejected, models.ImportRejection{Line: item.Line, Text: item.Text, Reason: item.Err.Error()})
+			continue
+		}
+
+		question, err := newQuestion(AddQuestionInput{
+			QuizID:        input.QuizID,
+			Text:          item.Text,
+			Options:       item.Options,
+			CorrectAnswer: item.CorrectAnswer,
+			Explanation:   item.Explanation,
+			TimeLimit:     item.TimeLimit,
+			Points:        item.Points,
+			Order:         order + len(questions) + 1,
+		})
+		if err != nil {
+			report.Rejected = append(report.Rejected, models.ImportRejection{Line: item.Line, Text: item.Text, Reason: err.Error()})
+			continue
+		}
+		questions = append(questions, question)
+	}
+
+	report.Imported = len(questions)
+	if input.DryRun || len(questions) == 0 {
+		return report, nil
+	}
+
+	// Forking a played version and adding the questions succeed or fail
+	// together
+	if err := s.quizRepo.AppendQuestions(ctx, quiz, questions); err != nil {
+		return nil, err
+	}
+	report.Questions = questions
+	return report, nil
+}
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+)
+
+var (
+	ErrUnknownKey      = errors.New("token signed with an unknown key")
+	ErrUnsupportedKey  = errors.New("only RSA and Ed25519 keys are supported")
+	ErrSigningMismatch = errors.New("token algorithm does not match its key")
+)
+
+// KeyService holds the keys that sign and verify access tokens. The newest
+// private key signs, with its kid in the token header; every key verifies,
+// so tokens signed before a rotation stay valid until they expire. Other
+// services verify tokens with the public keys from JWKS.
+type KeyService interface {
+	Sign(claims jwt.Claims) (string, error)
+	// Keyfunc returns the key that verifies the token, for jwt.Parse
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	JWKS() *models.JWKS
+	// Run rereads the keys directory, rotates the signing key when due and
+	// drops keys no valid token can use until ctx is done
+	Run(ctx context.Context)
+}
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	private   crypto.Signer // Nil for keys that only verify
+	public    crypto.PublicKey
+	createdAt time.Time
+	path      string // File it was read from, if any
+	pinned    bool   // From the config, never dropped
+}
+
+type keyService struct {
+	cfg config.JWTConfig
+
+	mu        sync.RWMutex
+	pinned    []*signingKey // From the config, in order
+	rotated   []*signingKey // From the directory or generated, in kid order
+	signer    *signingKey   // Nil to sign with the HS256 secret
+	generated bool          // Whether keys are generated at all
+	readAt    time.Time     // When the directory was last read
+}
+
+// Least time between rereads of the directory for a kid not seen yet
+const keyRereadInterval = time.Second
+
+func NewKeyService(cfg config.JWTConfig) KeyService {
+	s := &keyService{
+		cfg:       cfg,
+		generated: cfg.Secret == "" || cfg.Algorithm != "" || cfg.RotateHours > 0,
+	}
+	for _, kc := range cfg.Keys {
+		key, err := parseKeyConfig(kc)
+		if err != nil {
+			slog.Error("Skipping JWT key", "kid", kc.ID, "error", err)
+			continue
+		}
+		key.pinned = true
+		s.pinned = append(s.pinned, key)
+	}
+	if err := s.refresh(time.Now()); err != nil {
+		slog.Error("Failed to load JWT keys", "error", err)
+	}
+	return s
+}
+
+func (s *keyService) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	signer := s.signer
+	s.mu.RUnlock()
+
+	if signer == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.cfg.Secret))
+	}
+	token := jwt.NewWithClaims(signer.method, claims)
+	token.Header["kid"] = signer.id
+	return token.SignedString(signer.private)
+}
+
+// Keyfunc accepts HS256 tokens only while a secret is configured, and
+// other tokens only with the algorithm of the key their kid names. A kid
+// not seen yet may be a key another replica just generated, so the
+// directory is read again before it is refused.
+func (s *keyService) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if s.cfg.Secret == "" || token.Method != jwt.SigningMethodHS256 {
+			return nil, ErrSigningMismatch
+		}
+		return []byte(s.cfg.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	public, err := s.lookup(kid, token.Method)
+	if !errors.Is(err, ErrUnknownKey) || s.cfg.KeysDir == "" {
+		return public, err
+	}
+
+	s.mu.Lock()
+	if time.Since(s.readAt) >= keyRereadInterval {
+		if err := s.readDir(); err != nil {
+			slog.Error("Failed to read JWT keys", "error", err)
+		}
+	}
+	s.mu.Unlock()
+	return s.lookup(kid, token.Method)
+}
+
+func (s *keyService) lookup(kid string, method jwt.SigningMethod) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys() {
+		if key.id != kid {
+			continue
+		}
+		if key.method.Alg() != method.Alg() {
+			return nil, ErrSigningMismatch
+		}
+		return key.public, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (s *keyService) JWKS() *models.JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := &models.JWKS{Keys: []models.JWK{}}
+	for _, key := range s.keys() {
+		jwk := models.JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+func (s *keyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.KeyPollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.refresh(time.Now()); err != nil {
+				slog.Error("Failed to refresh JWT keys", "error", err)
+			}
+		}
+	}
+}
+
+// keys returns every key, the config's first; the caller holds mu
+func (s *keyService) keys() []*signingKey {
+	return append(append([]*signingKey{}, s.pinned...), s.rotated...)
+}
+
+// refresh rereads the keys directory, generates a signing key when there is
+// none or the current one is due for rotation, and drops the keys that
+// rotated out longer ago than an access token lives
+func (s *keyService) refresh(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.KeysDir != "" {
+		if err := s.readDir(); err != nil {
+			return err
+		}
+	} else {
+		s.signer = lastSigner(s.keys())
+	}
+
+	due := s.signer == nil || (s.cfg.RotateHours > 0 && now.Sub(s.signer.createdAt) >= s.cfg.RotateInterval())
+	if s.generated && due {
+		key, err := s.generate(now)
+		if err != nil {
+			return err
+		}
+		s.rotated = append(s.rotated, key)
+		s.signer = key
+		slog.Info("Generated JWT signing key", "kid", key.id, "alg", key.method.Alg())
+	}
+
+	// Tokens from keys older than the signer expire within an access
+	// token's lifetime of the switch
+	if s.signer == nil || s.signer.pinned || now.Sub(s.signer.createdAt) < s.cfg.AccessTTL() {
+		return nil
+	}
+	kept := s.rotated[:0]
+	for _, key := range s.rotated {
+		if key.private == nil || key.id >= s.signer.id {
+			kept = append(kept, key)
+			continue
+		}
+		if key.path != "" {
+			if err := os.Remove(key.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		slog.Info("Dropped retired JWT key", "kid", key.id)
+	}
+	s.rotated = kept
+	return nil
+}
+
+// readDir loads every <kid>.pem file of the keys directory, in kid order,
+// and picks the signer again; the caller holds mu
+func (s *keyService) readDir() error {
+	paths, err := filepath.Glob(filepath.Join(s.cfg.KeysDir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	var keys []*signingKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // Dropped by another replica
+			}
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		key, err := parsePEM(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			slog.Error("Skipping JWT key file", "path", path, "error", err)
+			continue
+		}
+		key.createdAt = info.ModTime()
+		key.path = path
+		keys = append(keys, key)
+	}
+
+	s.rotated = keys
+	s.signer = lastSigner(s.keys())
+	s.readAt = time.Now()
+	return nil
+}
+
+// generate creates a key named by its creation time, so newer keys sort
+// last, and saves it to the keys directory
+func (s *keyService) generate(now time.Time) (*signingKey, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	key := &signingKey{
+		id:        now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		createdAt: now,
+	}
+
+	switch s.cfg.KeyAlgorithm() {
+	case "RS256":
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key.method, key.private, key.public = jwt.SigningMethodRS256, private, &private.PublicKey
+	case "EdDSA":
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, private, public
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", s.cfg.KeyAlgorithm())
+	}
+
+	if s.cfg.KeysDir == "" {
+		return nil, config.ErrKeysDirRequired
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.cfg.KeysDir, 0o700); err != nil {
+		return nil, err
+	}
+	key.path = filepath.Join(s.cfg.KeysDir, key.id+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(key.path, data, 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// lastSigner returns the last key that can sign, or nil
+func lastSigner(keys []*signingKey) *signingKey {
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i].private != nil {
+			return keys[i]
+		}
+	}
+	return nil
+}
+
+func parseKeyConfig(kc config.KeyConfig) (*signingKey, error) {
+	if kc.ID == "" {
+		return nil, errors.New("key has no kid")
+	}
+	data := kc.PrivateKey
+	if data == "" {
+		data = kc.PublicKey
+	}
+	key, err := parsePEM(kc.ID, []byte(data))
+	if err != nil {
+		return nil, err
+	}
+	key.createdAt = time.Now()
+	return key, nil
+}
+
+// parsePEM reads a PKCS#8 or PKCS#1 private key, or a PKIX public key
+func parsePEM(id string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{id: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	return key, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+)
+
+var ErrAccountLocked = errors.New("too many failed logins, the account is locked for a while")
+
+// lockoutMemory is how long earlier locks make the next one longer
+const lockoutMemory = 24 * time.Hour
+
+// LockoutError is ErrAccountLocked with how long the lock has left
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// LockoutNotifier is told when an account is locked, e.g. to warn its owner
+// that someone may be guessing their password
+type LockoutNotifier interface {
+	AccountLocked(ctx context.Context, email string, until time.Time) error
+}
+
+// LockoutService locks an account out after repeated failed logins, for
+// longer each time within a day. Accounts are named by the email tried, so
+// unknown addresses lock the same way and do not stand out.
+type LockoutService interface {
+	// Check returns a *LockoutError while the account is locked
+	Check(ctx context.Context, email string) error
+	// Fail counts a failed login, returning a *LockoutError if it locked
+	// the account
+	Fail(ctx context.Context, email string) error
+	// Succeed forgets the account's failures and earlier locks
+	Succeed(ctx context.Context, email string) error
+}
+
+type lockoutService struct {
+	lockoutRepo repository.LockoutRepository
+	cfg         config.LockoutConfig
+	notifiers   []LockoutNotifier
+}
+
+func NewLockoutService(lockoutRepo repository.LockoutRepository, cfg config.LockoutConfig, notifiers ...LockoutNotifier) LockoutService {
+	return &lockoutService{lockoutRepo: lockoutRepo, cfg: cfg, notifiers: notifiers}
+}
+
+func (s *lockoutService) Check(ctx context.Context, email string) error {
+	if s.cfg.MaxFailures <= 0 {
+		return nil
+	}
+	left, err := s.lockoutRepo.LockedFor(ctx, accountKey(email))
+	if err != nil {
+		return err
+	}
+	if left > 0 {
+		return &LockoutError{RetryAfter: left}
+	}
+	return nil
+}
+
+func (s *lockoutService) Fail(ctx context.Context, email string) error {
+	if s.cfg.MaxFailures <= 0 {
+		return nil
+	}
+	account := accountKey(email)
+	failures, err := s.lockoutRepo.AddFailure(ctx, account, s.cfg.Window())
+	if err != nil {
+		return err
+	}
+	if failures < int64(s.cfg.MaxFailures) {
+		return nil
+	}
+
+	lock, err := s.lockoutRepo.Lock(ctx, account, lockoutMemory, s.cfg.LockFor)
+	if err != nil {
+		return err
+	}
+	until := time.Now().Add(lock)
+	slog.Warn("Account locked after failed logins", "email", account, "failures", failures, "until", until)
+	for _, notifier := range s.notifiers {
+		if err := notifier.AccountLocked(ctx, email, until); err != nil {
+			slog.Error("Failed to notify account lockout", "email", account, "error", err)
+		}
+	}
+	return &LockoutError{RetryAfter: lock}
+}
+
+func (s *lockoutService) Succeed(ctx context.Context, email string) error {
+	if s.cfg.MaxFailures <= 0 {
+		return nil
+	}
+	return s.lockoutRepo.Reset(ctx, accountKey(email))
+}
+
+// accountKey names an account by its email, ignoring case and spaces
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
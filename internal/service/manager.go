@@ -2,30 +2,93 @@ package service
 
 import (
 	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/mailer"
 	"github.com/nguyen1302/realtime-quiz/internal/repository"
 )
 
 // Service is the interface for the service manager
 type Service interface {
 	Auth() AuthService
+	Keys() KeyService
+	Account() AccountService
+	RateLimit() RateLimitService
+	OIDC() OIDCService
+	Profile() ProfileService
+	Org() OrgService
 	Quiz() QuizService
+	Host() HostService
+	Session() SessionService
+	Attempt() AttemptService
+	Practice() PracticeService
+	Stats() StatsService
+	Export() ExportService
+	Import() ImportService
+	Bundle() BundleService
+	Bank() BankService
 	Realtime() RealtimeService
+	Scheduler() SchedulerService
 }
 
 // serviceImpl is the concrete implementation of Service
 type serviceImpl struct {
-	auth     AuthService
-	quiz     QuizService
-	realtime RealtimeService
+	auth      AuthService
+	keys      KeyService
+	account   AccountService
+	rateLimit RateLimitService
+	oidc      OIDCService
+	profile   ProfileService
+	org       OrgService
+	quiz      QuizService
+	host      HostService
+	session   SessionService
+	attempt   AttemptService
+	practice  PracticeService
+	stats     StatsService
+	export    ExportService
+	imports   ImportService
+	bundle    BundleService
+	bank      BankService
+	realtime  RealtimeService
+	scheduler SchedulerService
 }
 
 // NewService creates a new instance of Service
 func NewService(repo repository.Repository, cfg *config.Config) Service {
-	realtimeSvc := NewRealtimeService()
+	realtimeSvc := NewRealtimeService(cfg.Realtime)
+	keySvc := NewKeyService(cfg.JWT)
+	mail := mailer.New(cfg.Mail)
+	accountSvc := NewAccountService(repo.User(), repo.UserToken(), repo.Token(), repo.Denylist(), mail, cfg.Account, cfg.JWT)
+	lockoutSvc := NewLockoutService(repo.Lockout(), cfg.RateLimit.Lockout, accountSvc)
+	authSvc := NewAuthService(repo.User(), repo.Token(), repo.Denylist(), keySvc, accountSvc, lockoutSvc, cfg.JWT)
+	attemptSvc := NewAttemptService(repo.Attempt(), repo.Session(), repo.Quiz(), repo.Question(), repo.Live())
+	practiceSvc := NewPracticeService(repo.Practice(), repo.Session(), repo.Quiz(), repo.Question(), repo.Live())
+	quizSvc := NewQuizService(repo.Quiz(), repo.Session(), repo.Question(), repo.Leaderboard(), repo.Answer(), repo.Live(), repo.Bank(), repo.Org(), attemptSvc, practiceSvc, realtimeSvc)
+	statsSvc := NewStatsService(repo.Quiz(), repo.Session(), repo.Question(), repo.Answer(), repo.User(), repo.Report(), repo.Org())
+	bankSvc := NewBankService(repo.Bank(), repo.Quiz(), repo.Question(), repo.Org())
+	hostSvc := NewHostService(repo.Quiz(), repo.Session(), repo.Question(), repo.Leaderboard(), repo.Live(), repo.Org(), bankSvc, statsSvc, realtimeSvc)
+	realtimeSvc.SetStateProvider(quizStateProvider{quiz: quizSvc})
+	realtimeSvc.SetHostController(hostController{host: hostSvc})
+
 	return &serviceImpl{
-		auth:     NewAuthService(repo.User(), cfg.JWT),
-		quiz:     NewQuizService(repo.Quiz(), repo.Question(), repo.Leaderboard(), repo.Answer(), realtimeSvc),
-		realtime: realtimeSvc,
+		auth:      authSvc,
+		keys:      keySvc,
+		account:   accountSvc,
+		rateLimit: NewRateLimitService(repo.RateLimit(), cfg.RateLimit),
+		oidc:      NewOIDCService(repo.User(), repo.Identity(), repo.OIDCState(), repo.Token(), repo.Denylist(), authSvc, cfg.OIDC, cfg.JWT),
+		profile:   NewProfileService(repo.User(), repo.Answer(), repo.Report(), repo.Org(), repo.Token(), repo.Denylist(), authSvc, accountSvc, lockoutSvc, cfg.JWT),
+		org:       NewOrgService(repo.Org(), repo.Invitation(), repo.Quiz(), repo.User(), mail, cfg.Account),
+		quiz:      quizSvc,
+		host:      hostSvc,
+		session:   NewSessionService(repo.Session(), repo.Quiz(), repo.Question(), repo.Answer(), repo.Org(), bankSvc),
+		attempt:   attemptSvc,
+		practice:  practiceSvc,
+		stats:     statsSvc,
+		export:    NewExportService(repo.Quiz(), repo.Session(), repo.Question(), repo.Answer(), repo.Org()),
+		imports:   NewImportService(repo.Quiz(), repo.Question(), repo.Org()),
+		bundle:    NewBundleService(repo.Quiz(), repo.Question(), repo.Bank(), repo.Org()),
+		bank:      bankSvc,
+		realtime:  realtimeSvc,
+		scheduler: NewSchedulerService(repo.Session(), repo.Live(), hostSvc, cfg.Scheduler),
 	}
 }
 
@@ -33,10 +96,74 @@ func (s *serviceImpl) Auth() AuthService {
 	return s.auth
 }
 
+func (s *serviceImpl) Keys() KeyService {
+	return s.keys
+}
+
+func (s *serviceImpl) Account() AccountService {
+	return s.account
+}
+
+func (s *serviceImpl) RateLimit() RateLimitService {
+	return s.rateLimit
+}
+
+func (s *serviceImpl) OIDC() OIDCService {
+	return s.oidc
+}
+
+func (s *serviceImpl) Profile() ProfileService {
+	return s.profile
+}
+
+func (s *serviceImpl) Org() OrgService {
+	return s.org
+}
+
 func (s *serviceImpl) Quiz() QuizService {
 	return s.quiz
 }
 
+func (s *serviceImpl) Host() HostService {
+	return s.host
+}
+
+func (s *serviceImpl) Session() SessionService {
+	return s.session
+}
+
+func (s *serviceImpl) Attempt() AttemptService {
+	return s.attempt
+}
+
+func (s *serviceImpl) Practice() PracticeService {
+	return s.practice
+}
+
+func (s *serviceImpl) Stats() StatsService {
+	return s.stats
+}
+
+func (s *serviceImpl) Export() ExportService {
+	return s.export
+}
+
+func (s *serviceImpl) Import() ImportService {
+	return s.imports
+}
+
+func (s *serviceImpl) Bundle() BundleService {
+	return s.bundle
+}
+
+func (s *serviceImpl) Bank() BankService {
+	return s.bank
+}
+
 func (s *serviceImpl) Realtime() RealtimeService {
 	return s.realtime
 }
+
+func (s *serviceImpl) Scheduler() SchedulerService {
+	return s.scheduler
+}
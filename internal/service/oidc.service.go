@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/oidc"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownProvider     = errors.New("unknown login provider")
+	ErrProviderUnavailable = errors.New("the login provider is unavailable")
+	ErrInvalidOIDCState    = errors.New("this login is invalid or has expired, please start again")
+	ErrOIDCLoginFailed     = errors.New("the login provider did not confirm the login")
+	ErrOIDCEmailUnverified = errors.New("the login provider has not verified your email address")
+)
+
+// usernameAttempts is how many numbered usernames are tried when the one
+// from the provider's profile is taken
+const usernameAttempts = 5
+
+// OIDCService logs users in with OpenID Connect providers, using the
+// authorization code flow with PKCE, and then issues our own tokens as a
+// password login would.
+//
+// A provider's user is found by the identity linked to them. The first time
+// they log in, they are linked to the user with the same email, or a new
+// user is created, but only if the provider has verified the email.
+type OIDCService interface {
+	// Providers lists the names of the configured providers
+	Providers() []string
+	// Begin starts a login and returns the provider URL to send the user to
+	Begin(ctx context.Context, provider string) (string, error)
+	// Complete finishes the login the user came back from with a code
+	Complete(ctx context.Context, provider, code, state string) (*TokenPair, *models.User, error)
+}
+
+// OIDCCallbackRequest is what the provider adds to the redirect URL
+type OIDCCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"` // Set instead of a code when the user cancelled or the provider refused
+	ErrorDescription string `form:"error_description"`
+}
+
+type oidcService struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	stateRepo    repository.OIDCStateRepository
+	tokenRepo    repository.TokenRepository
+	denylistRepo repository.DenylistRepository
+	authService  AuthService
+	providers    map[string]*oidc.Provider
+	names        []string
+	cfg          config.OIDCConfig
+	jwtConfig    config.JWTConfig
+}
+
+func NewOIDCService(userRepo repository.UserRepository, identityRepo repository.IdentityRepository, stateRepo repository.OIDCStateRepository, tokenRepo repository.TokenRepository, denylistRepo repository.DenylistRepository, authService AuthService, cfg config.OIDCConfig, jwtConfig config.JWTConfig) OIDCService {
+	s := &oidcService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		tokenRepo:    tokenRepo,
+		denylistRepo: denylistRepo,
+		authService:  authService,
+		providers:    make(map[string]*oidc.Provider, len(cfg.Providers)),
+		names:        []string{},
+		cfg:          cfg,
+		jwtConfig:    jwtConfig,
+	}
+	for _, pc := range cfg.Providers {
+		if _, ok := s.providers[pc.Name]; ok || pc.Name == "" || pc.Issuer == "" || pc.ClientID == "" {
+			slog.Warn("Skipping OIDC provider without a unique name, an issuer and a client ID", "name", pc.Name, "issuer", pc.Issuer)
+			continue
+		}
+		s.providers[pc.Name] = oidc.NewProvider(pc)
+		s.names = append(s.names, pc.Name)
+	}
+	return s
+}
+
+func (s *oidcService) Providers() []string {
+	return s.names
+}
+
+func (s *oidcService) Begin(ctx context.Context, name string) (string, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		slog.Error("Failed to start OIDC login", "provider", name, "error", err)
+		return "", ErrProviderUnavailable
+	}
+
+	login := &models.OIDCLogin{Provider: name, Verifier: verifier, Nonce: nonce}
+	if err := s.stateRepo.Save(ctx, state, login, s.cfg.StateTTL()); err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+func (s *oidcService) Complete(ctx context.Context, name, code, state string) (*TokenPair, *models.User, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, nil, ErrUnknownProvider
+	}
+
+	// Taking the state up front makes each login usable once, even if the
+	// exchange fails
+	login, err := s.stateRepo.Take(ctx, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if login == nil || login.Provider != name {
+		return nil, nil, ErrInvalidOIDCState
+	}
+
+	claims, err := provider.Exchange(ctx, code, login.Verifier, login.Nonce)
+	if err != nil {
+		slog.Warn("OIDC login failed", "provider", name, "error", err)
+		if errors.Is(err, oidc.ErrDiscovery) {
+			return nil, nil, ErrProviderUnavailable
+		}
+		return nil, nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, name, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := s.authService.LoginUser(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// resolveUser finds the user linked to the provider's subject, linking or
+// creating one on their first login
+func (s *oidcService) resolveUser(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, error) {
+	identity, err := s.identityRepo.Find(ctx, provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if claims.Email != "" && claims.Email != identity.Email {
+			if err := s.identityRepo.UpdateEmail(ctx, identity, claims.Email); err != nil {
+				return nil, err
+			}
+		}
+		return s.userRepo.FindByID(ctx, identity.UserID.String())
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailUnverified
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if err := s.claimUnverified(ctx, user); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if user, err = s.createUser(ctx, claims); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	err = s.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// claimUnverified hands an account whose email was never verified to the
+// provider's user, who has proved they own the address. Whoever registered
+// it may be someone else, so its password is dropped and its logins ended;
+// the owner can set a password with the forgotten password flow.
+func (s *oidcService) claimUnverified(ctx context.Context, user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, ""); err != nil {
+		return err
+	}
+	if err := logOutEverywhere(ctx, s.tokenRepo, s.denylistRepo, user.ID, s.jwtConfig.AccessTTL()); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		return err
+	}
+	user.PasswordHash = ""
+	user.EmailVerifiedAt = &now
+	return nil
+}
+
+// createUser registers the provider's user without a password
+func (s *oidcService) createUser(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	username, err := s.freeUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:        username,
+		Email:           claims.Email,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// freeUsername picks a username from the provider's profile, adding a
+// number when it is taken
+func (s *oidcService) freeUsername(ctx context.Context, claims *oidc.Claims) (string, error) {
+	base := ""
+	local, _, _ := strings.Cut(claims.Email, "@")
+	for _, candidate := range []string{claims.PreferredUsername, local, claims.Name} {
+		if base = cleanUsername(candidate); len(base) >= 3 {
+			break
+		}
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	username := base
+	for i := 0; i <= usernameAttempts; i++ {
+		exists, err := s.userRepo.ExistsByUsername(ctx, username)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return username, nil
+		}
+		username = fmt.Sprintf("%s_%d", base, rand.Intn(10000))
+	}
+	return "", ErrUsernameExists
+}
+
+// cleanUsername keeps letters, digits and a few symbols, turning spaces
+// into underscores
+func cleanUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)), strings.ContainsRune("_.-", r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		}
+		if b.Len() >= 40 {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/mailer"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotAllowed         = errors.New("your role in the quiz's organisation does not allow this")
+	ErrOrgNotFound        = errors.New("organisation not found")
+	ErrNotOrgAdmin        = errors.New("only the organisation's admins can do this")
+	ErrInvalidOrgName     = errors.New("name must be 1 to 100 characters")
+	ErrInvalidRole        = errors.New("role must be viewer, host, editor or admin")
+	ErrLastAdmin          = errors.New("an organisation needs at least one admin")
+	ErrMemberNotFound     = errors.New("member not found")
+	ErrAlreadyMember      = errors.New("you are already a member of this organisation")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationEmail    = errors.New("this invitation is for another email address")
+)
+
+// OrgService manages organisations, which own quizzes on behalf of their
+// members. A member's role decides what they may do with the quizzes:
+// viewers see them with their results and leaderboards, hosts also run
+// sessions, editors also create and edit quizzes, and admins also manage
+// members and invitations. Organisations are hidden from non-members, as
+// are their quizzes.
+type OrgService interface {
+	CreateOrg(ctx context.Context, userID uuid.UUID, name string) (*models.Organization, error)
+	ListOrgs(ctx context.Context, userID uuid.UUID) ([]models.Membership, error)
+	RenameOrg(ctx context.Context, orgID, userID uuid.UUID, name string) (*models.Organization, error)
+	ListQuizzes(ctx context.Context, orgID, userID uuid.UUID) ([]models.Quiz, error)
+	ListMembers(ctx context.Context, orgID, userID uuid.UUID) ([]models.Membership, error)
+	SetRole(ctx context.Context, orgID, userID, memberID uuid.UUID, role models.OrgRole) (*models.Membership, error)
+	// RemoveMember lets admins remove anyone, and members leave
+	RemoveMember(ctx context.Context, orgID, userID, memberID uuid.UUID) error
+	// CreateInvitation returns a link to join the organisation, emailing it
+	// if the invitation names an address
+	CreateInvitation(ctx context.Context, orgID, userID uuid.UUID, input InvitationInput) (*InvitationLink, error)
+	ListInvitations(ctx context.Context, orgID, userID uuid.UUID) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, orgID, userID, invitationID uuid.UUID) error
+	AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*models.Membership, error)
+}
+
+type InvitationInput struct {
+	Role    models.OrgRole `json:"role" binding:"required"`
+	Email   string         `json:"email" binding:"omitempty,email"`
+	MaxUses int            `json:"max_uses" binding:"omitempty,min=1,max=1000"`
+}
+
+// InvitationLink is a new invitation with the link that accepts it, which
+// is only shown once
+type InvitationLink struct {
+	models.Invitation
+	Link string `json:"link"`
+}
+
+type orgService struct {
+	orgRepo        repository.OrgRepository
+	invitationRepo repository.InvitationRepository
+	quizRepo       repository.QuizRepository
+	userRepo       repository.UserRepository
+	mailer         mailer.Mailer
+	cfg            config.AccountConfig
+}
+
+func NewOrgService(orgRepo repository.OrgRepository, invitationRepo repository.InvitationRepository, quizRepo repository.QuizRepository, userRepo repository.UserRepository, mailer mailer.Mailer, cfg config.AccountConfig) OrgService {
+	return &orgService{
+		orgRepo:        orgRepo,
+		invitationRepo: invitationRepo,
+		quizRepo:       quizRepo,
+		userRepo:       userRepo,
+		mailer:         mailer,
+		cfg:            cfg,
+	}
+}
+
+func (s *orgService) CreateOrg(ctx context.Context, userID uuid.UUID, name string) (*models.Organization, error) {
+	name, err := orgName(name)
+	if err != nil {
+		return nil, err
+	}
+	org := &models.Organization{Name: name}
+	if err := s.orgRepo.Create(ctx, org, userID); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *orgService) ListOrgs(ctx context.Context, userID uuid.UUID) ([]models.Membership, error) {
+	return s.orgRepo.ListByUser(ctx, userID)
+}
+
+func (s *orgService) RenameOrg(ctx context.Context, orgID, userID uuid.UUID, name string) (*models.Organization, error) {
+	name, err := orgName(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.member(ctx, orgID, userID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.Rename(ctx, orgID, name); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.GetByID(ctx, orgID)
+}
+
+func (s *orgService) ListQuizzes(ctx context.Context, orgID, userID uuid.UUID) ([]models.Quiz, error) {
+	if _, err := s.member(ctx, orgID, userID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+	return s.quizRepo.ListByOrg(ctx, orgID)
+}
+
+func (s *orgService) ListMembers(ctx context.Context, orgID, userID uuid.UUID) ([]models.Membership, error) {
+	if _, err := s.member(ctx, orgID, userID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListMembers(ctx, orgID)
+}
+
+func (s *orgService) SetRole(ctx context.Context, orgID, userID, memberID uuid.UUID, role models.OrgRole) (*models.Membership, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if _, err := s.member(ctx, orgID, userID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	membership, err := s.findMember(ctx, orgID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.Role == models.OrgRoleAdmin && role != models.OrgRoleAdmin {
+		if err := s.checkOtherAdmins(ctx, orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.orgRepo.SetRole(ctx, orgID, memberID, role); err != nil {
+		return nil, err
+	}
+	membership.Role = role
+	return membership, nil
+}
+
+func (s *orgService) RemoveMember(ctx context.Context, orgID, userID, memberID uuid.UUID) error {
+	if userID == memberID {
+		if _, err := s.member(ctx, orgID, userID, models.OrgRoleViewer); err != nil {
+			return err
+		}
+	} else if _, err := s.member(ctx, orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	membership, err := s.findMember(ctx, orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if membership.Role == models.OrgRoleAdmin {
+		if err := s.checkOtherAdmins(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	return s.orgRepo.RemoveMember(ctx, orgID, memberID)
+}
+
+func (s *orgService) findMember(ctx context.Context, orgID, memberID uuid.UUID) (*models.Membership, error) {
+	membership, err := s.orgRepo.GetMembership(ctx, orgID, memberID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// checkOtherAdmins refuses to take away the role of the last admin
+func (s *orgService) checkOtherAdmins(ctx context.Context, orgID uuid.UUID) error {
+	admins, err := s.orgRepo.CountAdmins(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func (s *orgService) CreateInvitation(ctx context.Context, orgID, userID uuid.UUID, input InvitationInput) (*InvitationLink, error) {
+	if !input.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if _, err := s.member(ctx, orgID, userID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	inviter, err := s.userRepo.FindByID(ctx, userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.Invitation{
+		TokenHash: hash,
+		OrgID:     orgID,
+		Role:      input.Role,
+		Email:     input.Email,
+		MaxUses:   1,
+		CreatedBy: userID,
+		ExpiresAt: time.Now().Add(s.cfg.InviteTTL()),
+	}
+	if input.MaxUses > 0 {
+		invitation.MaxUses = input.MaxUses
+	}
+	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	link := strings.TrimRight(s.cfg.BaseURL, "/") + "/invitations?token=" + url.QueryEscape(token)
+
+	if invitation.Email != "" {
+		err := s.mailer.Send(ctx, mailer.Message{
+			To:      invitation.Email,
+			Subject: fmt.Sprintf("You are invited to join %s", org.Name),
+			Body: fmt.Sprintf("Hi,\n\n%s invited you to join %s as %s. Log in or sign up with this address, then open the link below:\n\n%s\n\nThe link expires in %s.",
+				inviter.Username, org.Name, invitation.Role, link, lifetime(s.cfg.InviteTTL())),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &InvitationLink{Invitation: *invitation, Link: link}, nil
+}
+
+func (s *orgService) ListInvitations(ctx context.Context, orgID, userID uuid.UUID) ([]models.Invitation, error) {
+	if _, err := s.member(ctx, orgID, userID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.invitationRepo.ListOpen(ctx, orgID, time.Now())
+}
+
+func (s *orgService) RevokeInvitation(ctx context.Context, orgID, userID, invitationID uuid.UUID) error {
+	if _, err := s.member(ctx, orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	revoked, err := s.invitationRepo.Revoke(ctx, orgID, invitationID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation makes the user a member if the invitation the link's
+// token names is still open
+func (s *orgService) AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*models.Membership, error) {
+	invitation, err := s.invitationRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidLink
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !invitation.Open(now) {
+		return nil, ErrInvalidLink
+	}
+
+	if invitation.Email != "" {
+		user, err := s.userRepo.FindByID(ctx, userID.String())
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(user.Email, invitation.Email) {
+			return nil, ErrInvitationEmail
+		}
+	}
+	if _, err := s.orgRepo.GetMembership(ctx, invitation.OrgID, userID); err == nil {
+		return nil, ErrAlreadyMember
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	accepted, err := s.invitationRepo.Accept(ctx, invitation, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidLink
+	}
+	membership, err := s.orgRepo.GetMembership(ctx, invitation.OrgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	membership.Organization, err = s.orgRepo.GetByID(ctx, invitation.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// member returns the user's membership if their role allows what needs
+// the role. Non-members are told the organisation does not exist.
+func (s *orgService) member(ctx context.Context, orgID, userID uuid.UUID, need models.OrgRole) (*models.Membership, error) {
+	membership, err := s.orgRepo.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrgNotFound
+		}
+		return nil, err
+	}
+	if !membership.Role.Allows(need) {
+		if need == models.OrgRoleAdmin {
+			return nil, ErrNotOrgAdmin
+		}
+		return nil, ErrNotAllowed
+	}
+	return membership, nil
+}
+
+func orgName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", ErrInvalidOrgName
+	}
+	return name, nil
+}
+
+// orgRole returns the user's role in the organisation, or "" if they are
+// not a member
+func orgRole(ctx context.Context, orgRepo repository.OrgRepository, orgID, userID uuid.UUID) (models.OrgRole, error) {
+	membership, err := orgRepo.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return membership.Role, nil
+}
+
+// authorizeQuiz checks that the user's role in the quiz's organisation
+// allows what needs the role. Quizzes of other organisations are hidden,
+// as if they did not exist.
+func authorizeQuiz(ctx context.Context, orgRepo repository.OrgRepository, quiz *models.Quiz, userID uuid.UUID, need models.OrgRole) error {
+	role, err := orgRole(ctx, orgRepo, quiz.OrgID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrQuizNotFound
+	}
+	if !role.Allows(need) {
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+// quizOrg returns the organisation a new quiz of the user's goes to: the
+// one asked for, if they may edit its quizzes, or else their personal one
+func quizOrg(ctx context.Context, orgRepo repository.OrgRepository, orgID, userID uuid.UUID) (uuid.UUID, error) {
+	if orgID == uuid.Nil {
+		org, err := orgRepo.GetPersonal(ctx, userID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return org.ID, nil
+	}
+
+	role, err := orgRole(ctx, orgRepo, orgID, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if role == "" {
+		return uuid.Nil, ErrOrgNotFound
+	}
+	if !role.Allows(models.OrgRoleEditor) {
+		return uuid.Nil, ErrNotAllowed
+	}
+	return orgID, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrNotPractice = errors.New("session is not a practice session")
+
+// PracticeService runs practice sessions. Players answer any question as
+// often as they like and see the correct answer and its explanation at
+// once; answers are kept apart from scored play and only build up each
+// player's mastery of the questions.
+type PracticeService interface {
+	SubmitAnswer(ctx context.Context, session *models.Session, question *models.Question, input SubmitAnswerInput) (*models.Answer, error)
+	GetPractice(ctx context.Context, sessionID, userID uuid.UUID) (*models.PracticeState, error)
+	GetMastery(ctx context.Context, quizID, userID uuid.UUID) ([]models.Mastery, error)
+}
+
+type practiceService struct {
+	practiceRepo repository.PracticeRepository
+	sessionRepo  repository.SessionRepository
+	quizRepo     repository.QuizRepository
+	questionRepo repository.QuestionRepository
+	liveRepo     repository.LiveRepository
+}
+
+func NewPracticeService(practiceRepo repository.PracticeRepository, sessionRepo repository.SessionRepository, quizRepo repository.QuizRepository, questionRepo repository.QuestionRepository, liveRepo repository.LiveRepository) PracticeService {
+	return &practiceService{
+		practiceRepo: practiceRepo,
+		sessionRepo:  sessionRepo,
+		quizRepo:     quizRepo,
+		questionRepo: questionRepo,
+		liveRepo:     liveRepo,
+	}
+}
+
+// SubmitAnswer records a practice answer to any question of the session's
+// version and returns it with the feedback. It scores no points.
+func (s *practiceService) SubmitAnswer(ctx context.Context, session *models.Session, question *models.Question, input SubmitAnswerInput) (*models.Answer, error) {
+	practice := &models.PracticeAnswer{
+		QuizID:     session.QuizID,
+		SessionID:  session.ID,
+		QuestionID: question.ID,
+		UserID:     input.UserID,
+		Answer:     input.Answer,
+		IsCorrect:  question.CorrectAnswer == input.Answer,
+		CreatedAt:  time.Now(),
+	}
+	mastery, err := s.practiceRepo.Record(ctx, practice)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Answer{
+		ID:         practice.ID,
+		QuizID:     practice.QuizID,
+		SessionID:  practice.SessionID,
+		QuestionID: practice.QuestionID,
+		UserID:     practice.UserID,
+		Answer:     practice.Answer,
+		IsCorrect:  practice.IsCorrect,
+		CreatedAt:  practice.CreatedAt,
+		Feedback: &models.AnswerFeedback{
+			CorrectAnswer: question.CorrectAnswer,
+			Explanation:   question.Explanation,
+			Mastery:       mastery,
+		},
+	}, nil
+}
+
+// GetPractice returns the session's questions, without their answers, and
+// the player's mastery of the quiz so far
+func (s *practiceService) GetPractice(ctx context.Context, sessionID, userID uuid.UUID) (*models.PracticeState, error) {
+	session, _, err := findSession(ctx, s.sessionRepo, s.quizRepo, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !session.Practice() {
+		return nil, ErrNotPractice
+	}
+
+	kicked, err := s.liveRepo.IsKicked(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if kicked {
+		return nil, ErrKicked
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, session.QuizID, session.Version)
+	if err != nil {
+		return nil, err
+	}
+	mastery, err := s.practiceRepo.ListMastery(ctx, session.QuizID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &models.PracticeState{
+		SessionID: session.ID,
+		Questions: make([]models.PublicQuestion, len(questions)),
+		Mastery:   mastery,
+	}
+	for i := range questions {
+		state.Questions[i] = questions[i].Public()
+	}
+	return state, nil
+}
+
+// GetMastery returns the user's mastery of each question of the quiz they
+// have practised in any session, weakest first
+func (s *practiceService) GetMastery(ctx context.Context, quizID, userID uuid.UUID) ([]models.Mastery, error) {
+	if _, err := s.quizRepo.GetByID(ctx, quizID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	return s.practiceRepo.ListMastery(ctx, quizID, userID)
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWrongPassword   = errors.New("current password is incorrect")
+	ErrPasswordNotSet  = errors.New("this account has no password yet, set one with \"Forgot password\" first")
+	ErrInvalidUsername = errors.New("username must be 3 to 50 characters")
+	ErrInvalidAvatar   = errors.New("avatar must be an http or https URL")
+)
+
+// ProfileService lets users manage their own account. Changing the email or
+// password, or deleting the account, needs the current password, and wrong
+// passwords count towards a lockout as failed logins do.
+//
+// A deleted account is anonymised rather than removed: its personal data is
+// scrubbed and its logins and memberships ended, but its answers and
+// attempts stay under the scrubbed user, so other players' results and
+// leaderboards do not change and quizzes it created stay with their
+// organisations. The last admin of an organisation shared with others must
+// hand the role on first.
+type ProfileService interface {
+	GetProfile(ctx context.Context, userID uuid.UUID) (*models.User, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, req UpdateProfileRequest) (*models.User, error)
+	// ChangeEmail moves the account to an unverified address, emailing the
+	// old one a notice and the new one a verification link
+	ChangeEmail(ctx context.Context, userID uuid.UUID, req ChangeEmailRequest) (*models.User, error)
+	// ChangePassword logs the user out everywhere and back in here
+	ChangePassword(ctx context.Context, userID uuid.UUID, req ChangePasswordRequest) (*TokenPair, error)
+	DeleteAccount(ctx context.Context, userID uuid.UUID, req DeleteAccountRequest) error
+	ExportData(ctx context.Context, userID uuid.UUID) (*models.PersonalData, error)
+}
+
+// UpdateProfileRequest changes the fields that are set. An empty display
+// name or avatar clears it.
+type UpdateProfileRequest struct {
+	Username    *string `json:"username" binding:"omitempty,max=50"`
+	DisplayName *string `json:"display_name" binding:"omitempty,max=100"`
+	AvatarURL   *string `json:"avatar_url" binding:"omitempty,max=2048"`
+}
+
+type ChangeEmailRequest struct {
+	Email           string `json:"email" binding:"required,email"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type DeleteAccountRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+type profileService struct {
+	userRepo       repository.UserRepository
+	answerRepo     repository.AnswerRepository
+	reportRepo     repository.ReportRepository
+	orgRepo        repository.OrgRepository
+	tokenRepo      repository.TokenRepository
+	denylistRepo   repository.DenylistRepository
+	authService    AuthService
+	accountService AccountService
+	lockoutService LockoutService
+	jwtConfig      config.JWTConfig
+}
+
+func NewProfileService(userRepo repository.UserRepository, answerRepo repository.AnswerRepository, reportRepo repository.ReportRepository, orgRepo repository.OrgRepository, tokenRepo repository.TokenRepository, denylistRepo repository.DenylistRepository, authService AuthService, accountService AccountService, lockoutService LockoutService, jwtConfig config.JWTConfig) ProfileService {
+	return &profileService{
+		userRepo:       userRepo,
+		answerRepo:     answerRepo,
+		reportRepo:     reportRepo,
+		orgRepo:        orgRepo,
+		tokenRepo:      tokenRepo,
+		denylistRepo:   denylistRepo,
+		authService:    authService,
+		accountService: accountService,
+		lockoutService: lockoutService,
+		jwtConfig:      jwtConfig,
+	}
+}
+
+func (s *profileService) GetProfile(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *profileService) UpdateProfile(ctx context.Context, userID uuid.UUID, req UpdateProfileRequest) (*models.User, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		username := strings.TrimSpace(*req.Username)
+		if len(username) < 3 || len(username) > 50 {
+			return nil, ErrInvalidUsername
+		}
+		exists, err := s.userRepo.ExistsByUsername(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrUsernameExists
+		}
+		user.Username = username
+	}
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.AvatarURL != nil {
+		avatar := strings.TrimSpace(*req.AvatarURL)
+		if avatar != "" {
+			u, err := url.Parse(avatar)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, ErrInvalidAvatar
+			}
+		}
+		user.AvatarURL = avatar
+	}
+
+	if err := s.userRepo.UpdateProfile(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *profileService) ChangeEmail(ctx context.Context, userID uuid.UUID, req ChangeEmailRequest) (*models.User, error) {
+	user, err := s.reauthenticate(ctx, userID, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+	if req.Email == user.Email {
+		return user, nil
+	}
+
+	exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrEmailExists
+	}
+
+	oldEmail := user.Email
+	if err := s.userRepo.UpdateEmail(ctx, user.ID, req.Email); err != nil {
+		return nil, err
+	}
+	user.Email = req.Email
+	user.EmailVerifiedAt = nil
+
+	// As on registering, a mail failure must not undo the change
+	if err := s.accountService.EmailChanged(ctx, user, oldEmail); err != nil {
+		slog.Error("Failed to send email change notices", "user_id", user.ID, "error", err)
+	}
+	return user, nil
+}
+
+func (s *profileService) ChangePassword(ctx context.Context, userID uuid.UUID, req ChangePasswordRequest) (*TokenPair, error) {
+	user, err := s.reauthenticate(ctx, userID, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hash)); err != nil {
+		return nil, err
+	}
+
+	if err := logOutEverywhere(ctx, s.tokenRepo, s.denylistRepo, user.ID, s.jwtConfig.AccessTTL()); err != nil {
+		return nil, err
+	}
+	return s.authService.LoginUser(ctx, user)
+}
+
+func (s *profileService) DeleteAccount(ctx context.Context, userID uuid.UUID, req DeleteAccountRequest) error {
+	user, err := s.reauthenticate(ctx, userID, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	// Shared organisations must keep an admin
+	orgs, err := s.orgRepo.ListSoleAdmin(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(orgs) > 0 {
+		return fmt.Errorf("%w: make another member of %s an admin first", ErrLastAdmin, orgs[0].Name)
+	}
+
+	// Reports cached before the deletion still name the user
+	sessionIDs, err := s.answerRepo.ListSessionsByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := logOutEverywhere(ctx, s.tokenRepo, s.denylistRepo, user.ID, s.jwtConfig.AccessTTL()); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	id := strings.ReplaceAll(user.ID.String(), "-", "")
+	user.Username = "deleted_" + id[:12]
+	user.Email = "deleted+" + id + "@invalid"
+	user.PasswordHash = ""
+	user.EmailVerifiedAt = nil
+	user.DisplayName = ""
+	user.AvatarURL = ""
+	user.DeletedAt = &now
+	if err := s.userRepo.Anonymise(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.reportRepo.Invalidate(ctx, sessionIDs); err != nil {
+		slog.Error("Failed to drop cached reports of a deleted user", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+func (s *profileService) ExportData(ctx context.Context, userID uuid.UUID) (*models.PersonalData, error) {
+	if _, err := s.GetProfile(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.userRepo.ExportData(ctx, userID)
+}
+
+// reauthenticate checks the user's current password before a sensitive
+// change. Users who only log in with a provider must set one first.
+func (s *profileService) reauthenticate(ctx context.Context, userID uuid.UUID, password string) (*models.User, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordHash == "" {
+		return nil, ErrPasswordNotSet
+	}
+
+	if err := s.lockoutService.Check(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		if err := s.lockoutService.Fail(ctx, user.Email); err != nil {
+			return nil, err
+		}
+		return nil, ErrWrongPassword
+	}
+	if err := s.lockoutService.Succeed(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -4,38 +4,64 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"fmt"
+	"log/slog"
 	"math"
 	"math/big"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nguyen1302/realtime-quiz/internal/models"
 	"github.com/nguyen1302/realtime-quiz/internal/realtime"
 	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
 )
 
-var ErrAlreadyAnswered = errors.New("you have already answered this question")
+var (
+	ErrAlreadyAnswered = errors.New("you have already answered this question")
+	ErrInvalidQuestion = errors.New("invalid question")
+	ErrCloneNotAllowed = errors.New("the quiz owner does not allow cloning")
+	ErrCloneBankItems  = errors.New("the quiz draws from its owner's question bank and can only be cloned by them")
+	ErrOldRevision     = errors.New("question belongs to an older version of the quiz")
+	ErrVersionNotFound = errors.New("quiz version not found")
+	ErrLoginRequired   = errors.New("log in to follow the quiz")
+)
 
 type QuizService interface {
-	CreateQuiz(ctx context.Context, title, description string, ownerID uuid.UUID) (*models.Quiz, error)
+	// CreateQuiz adds a quiz to the organisation, or to the user's personal
+	// one if orgID is nil
+	CreateQuiz(ctx context.Context, title, description string, userID, orgID uuid.UUID) (*models.Quiz, error)
 	AddQuestion(ctx context.Context, input AddQuestionInput) (*models.Question, error)
-	GetQuiz(ctx context.Context, id uuid.UUID) (*models.Quiz, error)
-	JoinQuiz(ctx context.Context, code string) (*models.Quiz, error)
+	UpdateQuestion(ctx context.Context, questionID uuid.UUID, input AddQuestionInput) (*models.Question, error)
+	DeleteQuestion(ctx context.Context, quizID, questionID, userID uuid.UUID) error
+	GetVersion(ctx context.Context, quizID, userID uuid.UUID, version int) ([]models.Question, error)
+	GetQuiz(ctx context.Context, id, userID uuid.UUID) (*models.Quiz, error)
+	CloneQuiz(ctx context.Context, quizID, userID, orgID uuid.UUID, title string) (*models.Quiz, error)
+	SetAllowClone(ctx context.Context, quizID, userID uuid.UUID, allow bool) (*models.Quiz, error)
+	JoinQuiz(ctx context.Context, code string, userID uuid.UUID) (*models.Session, error)
 	SubmitAnswer(ctx context.Context, input SubmitAnswerInput) (*models.Answer, error)
-	GetLeaderboard(ctx context.Context, quizID uuid.UUID) ([]models.LeaderboardEntry, error)
+	// GetLeaderboard shows the session's leaderboard to its players and the
+	// members of its quiz's organisation
+	GetLeaderboard(ctx context.Context, sessionID, userID uuid.UUID) ([]models.LeaderboardEntry, error)
+	GetQuizState(ctx context.Context, sessionID, userID uuid.UUID) (*models.QuizState, error)
+	EnterQuiz(ctx context.Context, sessionID, userID uuid.UUID) error
 }
 
 type AddQuestionInput struct {
 	QuizID        uuid.UUID
+	UserID        uuid.UUID
 	Text          string
 	Options       []string
 	CorrectAnswer string
+	Explanation   string
 	TimeLimit     int
 	Points        int
 	Order         int
 }
 
 type SubmitAnswerInput struct {
-	QuizID     uuid.UUID
+	SessionID  uuid.UUID
 	QuestionID uuid.UUID
 	UserID     uuid.UUID
 	Answer     string
@@ -43,23 +69,39 @@ type SubmitAnswerInput struct {
 
 type quizService struct {
 	quizRepo        repository.QuizRepository
+	sessionRepo     repository.SessionRepository
 	questionRepo    repository.QuestionRepository
 	leaderboardRepo repository.LeaderboardRepository
 	answerRepo      repository.AnswerRepository
+	liveRepo        repository.LiveRepository
+	bankRepo        repository.BankRepository
+	orgRepo         repository.OrgRepository
+	attemptService  AttemptService
+	practiceService PracticeService
 	realtimeService RealtimeService
 }
 
-func NewQuizService(quizRepo repository.QuizRepository, questionRepo repository.QuestionRepository, leaderboardRepo repository.LeaderboardRepository, answerRepo repository.AnswerRepository, realtimeService RealtimeService) QuizService {
+func NewQuizService(quizRepo repository.QuizRepository, sessionRepo repository.SessionRepository, questionRepo repository.QuestionRepository, leaderboardRepo repository.LeaderboardRepository, answerRepo repository.AnswerRepository, liveRepo repository.LiveRepository, bankRepo repository.BankRepository, orgRepo repository.OrgRepository, attemptService AttemptService, practiceService PracticeService, realtimeService RealtimeService) QuizService {
 	return &quizService{
 		quizRepo:        quizRepo,
+		sessionRepo:     sessionRepo,
 		questionRepo:    questionRepo,
 		leaderboardRepo: leaderboardRepo,
 		answerRepo:      answerRepo,
+		liveRepo:        liveRepo,
+		bankRepo:        bankRepo,
+		orgRepo:         orgRepo,
+		attemptService:  attemptService,
+		practiceService: practiceService,
 		realtimeService: realtimeService,
 	}
 }
 
-func (s *quizService) CreateQuiz(ctx context.Context, title, description string, ownerID uuid.UUID) (*models.Quiz, error) {
+func (s *quizService) CreateQuiz(ctx context.Context, title, description string, userID, orgID uuid.UUID) (*models.Quiz, error) {
+	orgID, err := quizOrg(ctx, s.orgRepo, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
 	code, err := generateQuizCode()
 	if err != nil {
 		return nil, err
@@ -70,7 +112,8 @@ func (s *quizService) CreateQuiz(ctx context.Context, title, description string,
 		Description: description,
 		Code:        code,
 		Status:      models.QuizStatusDraft,
-		OwnerID:     ownerID,
+		OwnerID:     userID,
+		OrgID:       orgID,
 	}
 
 	if err := s.quizRepo.Create(ctx, quiz); err != nil {
@@ -80,7 +123,172 @@ func (s *quizService) CreateQuiz(ctx context.Context, title, description string,
 	return quiz, nil
 }
 
+// AddQuestion adds a question to the latest version of a quiz that
+// input.UserID may edit
 func (s *quizService) AddQuestion(ctx context.Context, input AddQuestionInput) (*models.Question, error) {
+	question, err := newQuestion(input)
+	if err != nil {
+		return nil, err
+	}
+
+	quiz, err := s.authorizedQuiz(ctx, input.QuizID, input.UserID, models.OrgRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	question.Version, err = editableVersion(ctx, s.quizRepo, quiz)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.questionRepo.Create(ctx, question); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// UpdateQuestion replaces a question of the quiz's latest version. If that
+// version has been played, the edit lands in a new version and the played
+// revision stays as it was.
+func (s *quizService) UpdateQuestion(ctx context.Context, questionID uuid.UUID, input AddQuestionInput) (*models.Question, error) {
+	updated, err := newQuestion(input)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := s.editableQuestion(ctx, input.QuizID, questionID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	question.Text = updated.Text
+	question.Options = updated.Options
+	question.CorrectAnswer = updated.CorrectAnswer
+	question.Explanation = updated.Explanation
+	question.TimeLimit = updated.TimeLimit
+	question.Points = updated.Points
+	question.Order = updated.Order
+
+	if err := s.questionRepo.Update(ctx, question); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// DeleteQuestion removes a question from the quiz's latest version, forking
+// a new version first if that one has been played
+func (s *quizService) DeleteQuestion(ctx context.Context, quizID, questionID, userID uuid.UUID) error {
+	question, err := s.editableQuestion(ctx, quizID, questionID, userID)
+	if err != nil {
+		return err
+	}
+	return s.questionRepo.Delete(ctx, question.ID)
+}
+
+// GetVersion returns the questions of one version of the quiz, as they were
+// when it was played
+func (s *quizService) GetVersion(ctx context.Context, quizID, userID uuid.UUID, version int) ([]models.Question, error) {
+	quiz, err := s.authorizedQuiz(ctx, quizID, userID, models.OrgRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if version < 1 || version > quiz.Version {
+		return nil, ErrVersionNotFound
+	}
+	return s.questionRepo.GetByQuizID(ctx, quizID, version)
+}
+
+// authorizedQuiz returns the quiz if the user's role in its organisation
+// allows what needs the role
+func (s *quizService) authorizedQuiz(ctx context.Context, quizID, userID uuid.UUID, need models.OrgRole) (*models.Quiz, error) {
+	quiz, err := s.quizRepo.GetByID(ctx, quizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	if err := authorizeQuiz(ctx, s.orgRepo, quiz, userID, need); err != nil {
+		return nil, err
+	}
+	return quiz, nil
+}
+
+// editableQuestion returns the revision of questionID that edits should
+// change, in a new version of the quiz if the latest one has been played.
+// Only questions of the latest version can be edited.
+func (s *quizService) editableQuestion(ctx context.Context, quizID, questionID, userID uuid.UUID) (*models.Question, error) {
+	quiz, err := s.authorizedQuiz(ctx, quizID, userID, models.OrgRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := s.questionRepo.GetByID(ctx, questionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	if question.QuizID != quiz.ID {
+		return nil, ErrQuestionNotFound
+	}
+	if question.Version != quiz.Version {
+		return nil, ErrOldRevision
+	}
+
+	version, err := editableVersion(ctx, s.quizRepo, quiz)
+	if err != nil {
+		return nil, err
+	}
+	if version == question.Version {
+		return question, nil
+	}
+	return s.questionRepo.GetRevision(ctx, quiz.ID, version, question.ID)
+}
+
+// editableVersion returns the version of the quiz that edits go to: the
+// latest one, or a copy of it if it has been played
+func editableVersion(ctx context.Context, quizRepo repository.QuizRepository, quiz *models.Quiz) (int, error) {
+	if !quiz.Frozen() {
+		return quiz.Version, nil
+	}
+	version, err := quizRepo.ForkVersion(ctx, quiz.ID, quiz.Version)
+	if err != nil {
+		return 0, err
+	}
+	quiz.Version = version
+	return version, nil
+}
+
+// newQuestion validates input and builds the question it describes, filling
+// in the default time limit and points. Every way of adding questions goes
+// through here.
+func newQuestion(input AddQuestionInput) (*models.Question, error) {
+	if strings.TrimSpace(input.Text) == "" {
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidQuestion)
+	}
+	if len(input.Options) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 options are required", ErrInvalidQuestion)
+	}
+	seen := make(map[string]bool, len(input.Options))
+	for _, option := range input.Options {
+		if strings.TrimSpace(option) == "" {
+			return nil, fmt.Errorf("%w: options cannot be empty", ErrInvalidQuestion)
+		}
+		if seen[option] {
+			return nil, fmt.Errorf("%w: duplicate option %q", ErrInvalidQuestion, option)
+		}
+		seen[option] = true
+	}
+	if input.CorrectAnswer == "" {
+		return nil, fmt.Errorf("%w: correct answer is required", ErrInvalidQuestion)
+	}
+	if !seen[input.CorrectAnswer] {
+		return nil, fmt.Errorf("%w: correct answer %q is not one of the options", ErrInvalidQuestion, input.CorrectAnswer)
+	}
+	if input.TimeLimit < 0 || input.Points < 0 {
+		return nil, fmt.Errorf("%w: time limit and points cannot be negative", ErrInvalidQuestion)
+	}
+
 	if input.TimeLimit == 0 {
 		input.TimeLimit = 30 // Default
 	}
@@ -88,37 +296,143 @@ func (s *quizService) AddQuestion(ctx context.Context, input AddQuestionInput) (
 		input.Points = 100 // Default
 	}
 
-	question := &models.Question{
+	return &models.Question{
 		QuizID:        input.QuizID,
 		Text:          input.Text,
 		Options:       input.Options,
 		CorrectAnswer: input.CorrectAnswer,
+		Explanation:   input.Explanation,
 		TimeLimit:     input.TimeLimit,
 		Points:        input.Points,
 		Order:         input.Order,
+	}, nil
+}
+
+// GetQuiz returns the quiz, with its answers, to members of its
+// organisation
+func (s *quizService) GetQuiz(ctx context.Context, id, userID uuid.UUID) (*models.Quiz, error) {
+	return s.authorizedQuiz(ctx, id, userID, models.OrgRoleViewer)
+}
+
+// CloneQuiz copies a quiz and its questions into a new DRAFT quiz created
+// by userID, in orgID or their personal organisation, with a fresh join
+// code. Members of the quiz's organisation can always clone it; other users
+// only when it allows cloning. Answers and results stay with the original.
+func (s *quizService) CloneQuiz(ctx context.Context, quizID, userID, orgID uuid.UUID, title string) (*models.Quiz, error) {
+	original, err := s.quizRepo.GetByID(ctx, quizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	role, err := orgRole(ctx, s.orgRepo, original.OrgID, userID)
+	if err != nil {
+		return nil, err
 	}
+	if role == "" && !original.AllowClone {
+		return nil, ErrCloneNotAllowed
+	}
+	orgID, err = quizOrg(ctx, s.orgRepo, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	sameOwner := original.OwnerID == userID
 
-	if err := s.questionRepo.Create(ctx, question); err != nil {
+	// Bank items still waiting to be resolved point into the owner's bank
+	items, err := s.bankRepo.ListItems(ctx, quizID, true)
+	if err != nil {
 		return nil, err
 	}
-	return question, nil
+	if len(items) > 0 && !sameOwner {
+		return nil, ErrCloneBankItems
+	}
+
+	code, err := generateQuizCode()
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(title) == "" {
+		title = original.Title
+	}
+	clone := &models.Quiz{
+		ID:          uuid.New(),
+		Title:       title,
+		Description: original.Description,
+		Code:        code,
+		Status:      models.QuizStatusDraft,
+		OwnerID:     userID,
+		OrgID:       orgID,
+		ClonedFrom:  &original.ID,
+	}
+
+	questions, err := s.questionRepo.GetByQuizID(ctx, quizID, original.Version)
+	if err != nil {
+		return nil, err
+	}
+	for _, question := range questions {
+		copied := models.Question{
+			QuizID:        clone.ID,
+			Text:          question.Text,
+			Options:       append(models.JSONB{}, question.Options...),
+			CorrectAnswer: question.CorrectAnswer,
+			Explanation:   question.Explanation,
+			TimeLimit:     question.TimeLimit,
+			Points:        question.Points,
+			Order:         question.Order,
+		}
+		if sameOwner {
+			copied.BankQuestionID = question.BankQuestionID
+		}
+		clone.Questions = append(clone.Questions, copied)
+	}
+	for _, item := range items {
+		clone.BankItems = append(clone.BankItems, models.QuizBankItem{
+			QuizID:         clone.ID,
+			Order:          item.Order,
+			BankQuestionID: item.BankQuestionID,
+			DrawCount:      item.DrawCount,
+			DrawTags:       item.DrawTags,
+			DrawDifficulty: item.DrawDifficulty,
+		})
+	}
+
+	// The quiz, its questions and bank items are inserted in one transaction
+	if err := s.quizRepo.Create(ctx, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
 }
 
-func (s *quizService) GetQuiz(ctx context.Context, id uuid.UUID) (*models.Quiz, error) {
-	// Get quiz info
-	quiz, err := s.quizRepo.GetByID(ctx, id)
+// SetAllowClone lets editors open or close the quiz to cloning by users
+// outside its organisation
+func (s *quizService) SetAllowClone(ctx context.Context, quizID, userID uuid.UUID, allow bool) (*models.Quiz, error) {
+	quiz, err := s.authorizedQuiz(ctx, quizID, userID, models.OrgRoleEditor)
 	if err != nil {
 		return nil, err
 	}
+
+	if err := s.quizRepo.SetAllowClone(ctx, quizID, allow); err != nil {
+		return nil, err
+	}
+	quiz.AllowClone = allow
 	return quiz, nil
 }
 
-func (s *quizService) JoinQuiz(ctx context.Context, code string) (*models.Quiz, error) {
-	quiz, err := s.quizRepo.GetByCode(ctx, code)
+// JoinQuiz adds the user to the session with the join code. The session is
+// returned with its quiz.
+func (s *quizService) JoinQuiz(ctx context.Context, code string, userID uuid.UUID) (*models.Session, error) {
+	session, err := s.sessionRepo.GetByCode(ctx, code)
 	if err != nil {
 		return nil, err
 	}
-	return quiz, nil
+	if _, err := s.checkCanJoin(ctx, session, session.Quiz, userID); err != nil {
+		return nil, err
+	}
+	if err := s.leaderboardRepo.AddParticipant(ctx, session.ID, userID); err != nil {
+		return nil, err
+	}
+	return session, nil
 }
 
 func (s *quizService) SubmitAnswer(ctx context.Context, input SubmitAnswerInput) (*models.Answer, error) {
@@ -128,18 +442,34 @@ func (s *quizService) SubmitAnswer(ctx context.Context, input SubmitAnswerInput)
 		return nil, err
 	}
 
-	// 2. Check correctness
+	// 2. Check the question is open for this player
+	session, live, err := s.checkCanAnswer(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	// Only the questions of the version the session pinned when it started
+	if question.QuizID != session.QuizID || question.Version != session.Version {
+		return nil, ErrQuestionNotFound
+	}
+	if session.SelfPaced() {
+		return s.attemptService.SubmitAnswer(ctx, session, question, input)
+	}
+	if session.Practice() {
+		return s.practiceService.SubmitAnswer(ctx, session, question, input)
+	}
+
+	// 3. Check correctness
 	isCorrect := question.CorrectAnswer == input.Answer
 	points := 0
 
 	if isCorrect {
-		// 3. Get rank from Redis (Atomic) if correct
-		rank, err := s.leaderboardRepo.GetSubmissionRank(ctx, input.QuizID, input.QuestionID)
+		// 4. Get rank from Redis (Atomic) if correct
+		rank, err := s.leaderboardRepo.GetSubmissionRank(ctx, input.SessionID, input.QuestionID)
 		if err != nil {
 			return nil, err
 		}
 
-		// 4. Calculate points
+		// 5. Calculate points
 		// Logic: MaxPoints * (0.9 ^ (rank-1))
 		// Example (Max 100):
 		// Rank 1: 100
@@ -166,17 +496,22 @@ func (s *quizService) SubmitAnswer(ctx context.Context, input SubmitAnswerInput)
 	}
 
 	answer := &models.Answer{
-		QuizID:     input.QuizID,
+		QuizID:     session.QuizID,
+		SessionID:  session.ID,
 		QuestionID: input.QuestionID,
 		UserID:     input.UserID,
 		Answer:     input.Answer,
 		IsCorrect:  isCorrect,
 		Points:     points,
 	}
+	if live != nil {
+		responseTime := time.Since(live.StartedAt).Milliseconds()
+		answer.ResponseTimeMs = &responseTime
+	}
 
-	// 5. Update DB (Transaction?)
+	// 6. Update DB (Transaction?)
 	// Check idempotency
-	hasAnswered, err := s.answerRepo.HasAnswered(ctx, input.QuizID, input.QuestionID, input.UserID)
+	hasAnswered, err := s.answerRepo.HasAnswered(ctx, input.SessionID, input.QuestionID, input.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -188,18 +523,119 @@ func (s *quizService) SubmitAnswer(ctx context.Context, input SubmitAnswerInput)
 		return nil, err
 	}
 
-	// 6. Update Leaderboard (Real-time)
-	if err := s.leaderboardRepo.UpdateScore(ctx, input.QuizID, input.UserID, float64(points)); err != nil {
+	// 7. Update Leaderboard (Real-time)
+	if err := s.leaderboardRepo.UpdateScore(ctx, input.SessionID, input.UserID, float64(points)); err != nil {
+		return nil, err
+	}
+	if err := s.leaderboardRepo.AddParticipant(ctx, input.SessionID, input.UserID); err != nil {
 		return nil, err
 	}
 
-	// 7. Broadcast Leaderboard Update
-	leaderboard, _ := s.leaderboardRepo.GetLeaderboard(ctx, input.QuizID, 10)
-	s.realtimeService.BroadcastToQuiz(input.QuizID.String(), realtime.EventLeaderboard, leaderboard)
+	// 8. Broadcast Leaderboard Update
+	leaderboard, _ := s.leaderboardRepo.GetLeaderboard(ctx, input.SessionID, 10)
+	s.realtimeService.BroadcastToQuiz(input.SessionID.String(), realtime.EventLeaderboard, leaderboard)
+
+	// 9. Send the result to the player, kept for replay if they reconnect
+	s.realtimeService.SendToQuizUser(input.SessionID.String(), input.UserID.String(), realtime.EventAnswerResult, answer)
+
+	// 10. Live answer distribution for the hosts. The answer is already
+	// scored, so a failure here must not make the player retry it.
+	counts, err := s.liveRepo.RecordAnswer(ctx, input.SessionID, input.QuestionID, input.Answer)
+	if err != nil {
+		slog.Error("Failed to record live answer count", "session", input.SessionID, "question", input.QuestionID, "error", err)
+		return answer, nil
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	s.realtimeService.BroadcastToHosts(input.SessionID.String(), realtime.EventAnswerCount, models.AnswerCountEvent{
+		QuestionID: input.QuestionID,
+		Counts:     counts,
+		Total:      total,
+	})
 
 	return answer, nil
 }
 
+// checkCanAnswer rejects submissions from kicked players, to sessions that
+// have not started or have finished, and, once the host runs the session
+// live, to any question that is not the open one. It returns the session and
+// its live state, if any.
+func (s *quizService) checkCanAnswer(ctx context.Context, input SubmitAnswerInput) (*models.Session, *models.LiveState, error) {
+	session, err := s.sessionRepo.GetByID(ctx, input.SessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrSessionNotFound
+		}
+		return nil, nil, err
+	}
+	if session.Status != models.QuizStatusActive {
+		return nil, nil, ErrQuizNotActive
+	}
+
+	kicked, err := s.liveRepo.IsKicked(ctx, session.ID, input.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kicked {
+		return nil, nil, ErrKicked
+	}
+
+	live, err := s.liveRepo.GetState(ctx, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if live != nil && (live.QuestionID != input.QuestionID || !live.AcceptsAnswers(time.Now())) {
+		return nil, nil, ErrQuestionClosed
+	}
+	return session, live, nil
+}
+
+// checkCanJoin keeps kicked players out of the session, everyone out of a
+// scheduled session whose lobby has not opened and, once the lobby is locked,
+// anyone who is not already a participant. Members of the quiz's
+// organisation who may host can always join, and it reports whether the
+// user is one of them.
+func (s *quizService) checkCanJoin(ctx context.Context, session *models.Session, quiz *models.Quiz, userID uuid.UUID) (bool, error) {
+	role, err := orgRole(ctx, s.orgRepo, quiz.OrgID, userID)
+	if err != nil {
+		return false, err
+	}
+	if role.Allows(models.OrgRoleHost) {
+		return true, nil
+	}
+	if !session.LobbyOpen(time.Now()) {
+		return false, ErrLobbyNotOpen
+	}
+	sessionID := session.ID
+
+	kicked, err := s.liveRepo.IsKicked(ctx, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	if kicked {
+		return false, ErrKicked
+	}
+
+	locked, err := s.liveRepo.IsLobbyLocked(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if !locked {
+		return false, nil
+	}
+
+	participant, err := s.leaderboardRepo.IsParticipant(ctx, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	if !participant {
+		return false, ErrLobbyLocked
+	}
+	return false, nil
+}
+
 func generateQuizCode() (string, error) {
 	const charset = "0123456789"
 	code := make([]byte, 6)
@@ -213,7 +649,147 @@ func generateQuizCode() (string, error) {
 	return string(code), nil
 }
 
-func (s *quizService) GetLeaderboard(ctx context.Context, quizID uuid.UUID) ([]models.LeaderboardEntry, error) {
-	// Get top 10
-	return s.leaderboardRepo.GetLeaderboard(ctx, quizID, 10)
+func (s *quizService) GetLeaderboard(ctx context.Context, sessionID, userID uuid.UUID) ([]models.LeaderboardEntry, error) {
+	session, quiz, err := findSession(ctx, s.sessionRepo, s.quizRepo, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	participant, err := s.leaderboardRepo.IsParticipant(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !participant {
+		if err := authorizeQuiz(ctx, s.orgRepo, quiz, userID, models.OrgRoleViewer); err != nil {
+			if errors.Is(err, ErrQuizNotFound) {
+				return nil, ErrSessionNotFound
+			}
+			return nil, err
+		}
+	}
+	return s.leaderboard(ctx, session)
+}
+
+// leaderboard returns the session's top 10. Self-paced sessions rank
+// attempts, which outlive the live leaderboard.
+func (s *quizService) leaderboard(ctx context.Context, session *models.Session) ([]models.LeaderboardEntry, error) {
+	if session.SelfPaced() {
+		return s.attemptService.GetLeaderboard(ctx, session.ID)
+	}
+	return s.leaderboardRepo.GetLeaderboard(ctx, session.ID, 10)
+}
+
+// GetQuizState builds a snapshot of the session for a (re)connecting client.
+// It does not check access: the client must have entered the session with
+// EnterQuiz, or be one of its hosts.
+func (s *quizService) GetQuizState(ctx context.Context, sessionID, userID uuid.UUID) (*models.QuizState, error) {
+	session, quiz, err := findSession(ctx, s.sessionRepo, s.quizRepo, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &models.QuizState{
+		QuizID:     quiz.ID,
+		SessionID:  session.ID,
+		Status:     session.Status,
+		ServerTime: now,
+	}
+
+	state.LobbyLocked, err = s.liveRepo.IsLobbyLocked(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Current question, without its answer
+	live, err := s.liveRepo.GetState(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if live != nil {
+		// The session's questions, which may differ from the latest version
+		questions, err := s.questionRepo.GetByQuizID(ctx, quiz.ID, session.RunVersion(quiz.Version))
+		if err != nil {
+			return nil, err
+		}
+		for i := range questions {
+			if questions[i].ID == live.QuestionID {
+				state.CurrentQuestion = models.NewLiveQuestion(&questions[i], live, len(questions), now)
+				break
+			}
+		}
+	}
+
+	state.Leaderboard, err = s.leaderboard(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	state.ParticipantCount, err = s.leaderboardRepo.CountParticipants(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != uuid.Nil {
+		state.Player, err = s.getPlayerState(ctx, sessionID, userID, live)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// EnterQuiz admits a player to the session over a socket, whether joining
+// or resuming: it applies the same checks as JoinQuiz and records the
+// participant.
+func (s *quizService) EnterQuiz(ctx context.Context, sessionID, userID uuid.UUID) error {
+	session, quiz, err := findSession(ctx, s.sessionRepo, s.quizRepo, sessionID)
+	if err != nil {
+		return err
+	}
+	staff, err := s.checkCanJoin(ctx, session, quiz, userID)
+	if err != nil {
+		return err
+	}
+	// Hosts are not players
+	if staff {
+		return nil
+	}
+	return s.leaderboardRepo.AddParticipant(ctx, sessionID, userID)
+}
+
+func (s *quizService) getPlayerState(ctx context.Context, sessionID, userID uuid.UUID, live *models.LiveState) (*models.PlayerState, error) {
+	player := &models.PlayerState{}
+
+	entry, err := s.leaderboardRepo.GetUserEntry(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		player.Score = entry.Score
+		player.Rank = entry.Rank
+	}
+
+	// Streak: consecutive correct answers counting back from the latest
+	answers, err := s.answerRepo.ListByUser(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, answer := range answers {
+		if !answer.IsCorrect {
+			break
+		}
+		player.Streak++
+	}
+
+	if live != nil {
+		for _, answer := range answers {
+			if answer.QuestionID == live.QuestionID {
+				player.Answered = true
+				break
+			}
+		}
+	}
+
+	return player, nil
 }
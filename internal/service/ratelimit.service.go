@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"github.com/nguyen1302/realtime-quiz/pkg/ratelimit"
+)
+
+// RateLimitService applies the configured rules, each a sliding window per
+// IP and per account, and throttles, each a token bucket per account
+type RateLimitService interface {
+	// Allow records a request under the rule from the IP, and for the
+	// account if one is known. It returns how long to wait if either is
+	// over its limit, or 0.
+	Allow(ctx context.Context, rule, ip, account string) (time.Duration, error)
+	// Throttle takes a token from the account's bucket for the throttle,
+	// returning how long to wait if it is empty, or 0
+	Throttle(throttle, account string) time.Duration
+}
+
+type rateLimitService struct {
+	rateLimitRepo repository.RateLimitRepository
+	rules         map[string]config.RateLimitRule
+	throttles     map[string]*ratelimit.Buckets
+}
+
+func NewRateLimitService(rateLimitRepo repository.RateLimitRepository, cfg config.RateLimitConfig) RateLimitService {
+	throttles := make(map[string]*ratelimit.Buckets, len(cfg.Throttles))
+	for name, bucket := range cfg.Throttles {
+		throttles[name] = ratelimit.NewBuckets(bucketRate(bucket))
+	}
+	return &rateLimitService{rateLimitRepo: rateLimitRepo, rules: cfg.Rules, throttles: throttles}
+}
+
+func (s *rateLimitService) Allow(ctx context.Context, rule, ip, account string) (time.Duration, error) {
+	r, ok := s.rules[rule]
+	if !ok {
+		return 0, nil
+	}
+
+	if r.PerIP > 0 {
+		wait, err := s.rateLimitRepo.Allow(ctx, fmt.Sprintf("%s:ip:%s", rule, ip), r.PerIP, r.Window())
+		if err != nil || wait > 0 {
+			return wait, err
+		}
+	}
+	if r.PerAccount > 0 && account != "" {
+		return s.rateLimitRepo.Allow(ctx, fmt.Sprintf("%s:account:%s", rule, accountKey(account)), r.PerAccount, r.Window())
+	}
+	return 0, nil
+}
+
+func (s *rateLimitService) Throttle(throttle, account string) time.Duration {
+	buckets, ok := s.throttles[throttle]
+	if !ok || account == "" {
+		return 0
+	}
+	return buckets.Take(account, time.Now())
+}
+
+func bucketRate(bucket config.TokenBucket) ratelimit.Rate {
+	return ratelimit.Rate{PerSecond: bucket.Rate, Burst: bucket.Burst}
+}
@@ -1,6 +1,10 @@
 package service
 
 import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
 	"github.com/nguyen1302/realtime-quiz/internal/realtime"
 )
 
@@ -8,6 +12,11 @@ type RealtimeService interface {
 	BroadcastToUser(userID string, messageType string, payload interface{})
 	BroadcastToQuiz(quizID string, messageType string, payload interface{})
 	BroadcastToAll(messageType string, payload interface{})
+	SendToQuizUser(quizID, userID string, messageType string, payload interface{})
+	BroadcastToHosts(quizID string, messageType string, payload interface{})
+	RemoveFromQuiz(quizID, userID string)
+	SetStateProvider(provider realtime.StateProvider)
+	SetHostController(controller realtime.HostController)
 	GetManager() *realtime.Manager
 }
 
@@ -15,9 +24,22 @@ type realtimeService struct {
 	manager *realtime.Manager
 }
 
-func NewRealtimeService() RealtimeService {
+func NewRealtimeService(cfg config.RealtimeConfig) RealtimeService {
+	limits := make(map[string]realtime.MessageLimit, len(cfg.Limits))
+	for kind, limit := range cfg.Limits {
+		limits[kind] = realtime.MessageLimit{
+			PerConnection: bucketRate(limit.PerConnection),
+			PerUser:       bucketRate(limit.PerUser),
+		}
+	}
+
 	return &realtimeService{
-		manager: realtime.NewManager(),
+		manager: realtime.NewManager(realtime.Options{
+			EnableCompression: cfg.EnableCompression,
+			CompressionLevel:  cfg.CompressionLevel,
+			Limits:            limits,
+			DisconnectAfter:   cfg.DisconnectAfter,
+		}),
 	}
 }
 
@@ -47,6 +69,77 @@ func (s *realtimeService) BroadcastToAll(messageType string, payload interface{}
 	s.manager.Broadcast(msg)
 }
 
+// SendToQuizUser sends a quiz event to a single participant. Unlike
+// BroadcastToUser the event is sequenced in the quiz stream, so it is replayed
+// if the user reconnects and resumes.
+func (s *realtimeService) SendToQuizUser(quizID, userID string, messageType string, payload interface{}) {
+	msg := &realtime.WSMessage{
+		Type:    messageType,
+		Payload: payload,
+		RoomID:  quizID,
+	}
+	s.manager.SendToQuizUser(quizID, userID, msg)
+}
+
+// BroadcastToHosts sends a host-only event to the quiz hosts' sockets
+func (s *realtimeService) BroadcastToHosts(quizID string, messageType string, payload interface{}) {
+	msg := &realtime.WSMessage{
+		Type:    messageType,
+		Payload: payload,
+		RoomID:  quizID,
+	}
+	s.manager.BroadcastToHosts(quizID, msg)
+}
+
+// RemoveFromQuiz stops delivering quiz events to the user's sockets
+func (s *realtimeService) RemoveFromQuiz(quizID, userID string) {
+	s.manager.RemoveUserFromQuiz(quizID, userID)
+}
+
+func (s *realtimeService) SetHostController(controller realtime.HostController) {
+	s.manager.SetHostController(controller)
+}
+
+func (s *realtimeService) SetStateProvider(provider realtime.StateProvider) {
+	s.manager.SetStateProvider(provider)
+}
+
 func (s *realtimeService) GetManager() *realtime.Manager {
 	return s.manager
 }
+
+// quizStateProvider adapts QuizService to realtime.StateProvider
+type quizStateProvider struct {
+	quiz QuizService
+}
+
+// Admit enters authenticated clients as players. Anonymous clients are
+// refused, since quiz streams carry the leaderboard that only participants
+// and the organisation's members may see.
+func (p quizStateProvider) Admit(ctx context.Context, quizID, userID string) error {
+	qid, err := uuid.Parse(quizID)
+	if err != nil {
+		return err
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrLoginRequired
+	}
+	return clientError(p.quiz.EnterQuiz(ctx, qid, uid))
+}
+
+func (p quizStateProvider) QuizState(ctx context.Context, quizID, userID string) (interface{}, error) {
+	qid, err := uuid.Parse(quizID)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrLoginRequired
+	}
+	state, err := p.quiz.GetQuizState(ctx, qid, uid)
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return state, nil
+}
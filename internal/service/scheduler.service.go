@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+)
+
+// SchedulerService runs scheduled sessions without a host: it starts each
+// one when it is due, reveals the answer when a question's timer runs out
+// and opens the next question after a short pause.
+//
+// Every replica runs a scheduler. A Redis lock per session elects the one
+// that drives it, so each step happens once.
+type SchedulerService interface {
+	// Run checks for due sessions until ctx is done
+	Run(ctx context.Context)
+	// Tick moves every due session on by at most one step
+	Tick(ctx context.Context)
+}
+
+type schedulerService struct {
+	sessionRepo repository.SessionRepository
+	liveRepo    repository.LiveRepository
+	hostService HostService
+	cfg         config.SchedulerConfig
+	id          string // Owner of this replica's locks
+}
+
+func NewSchedulerService(sessionRepo repository.SessionRepository, liveRepo repository.LiveRepository, hostService HostService, cfg config.SchedulerConfig) SchedulerService {
+	return &schedulerService{
+		sessionRepo: sessionRepo,
+		liveRepo:    liveRepo,
+		hostService: hostService,
+		cfg:         cfg,
+		id:          uuid.NewString(),
+	}
+}
+
+func (s *schedulerService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Tick(ctx)
+		}
+	}
+}
+
+func (s *schedulerService) Tick(ctx context.Context) {
+	now := time.Now()
+	sessions, err := s.sessionRepo.ListDue(ctx, now)
+	if err != nil {
+		slog.Error("Failed to list scheduled sessions", "error", err)
+		return
+	}
+
+	for i := range sessions {
+		if err := s.drive(ctx, &sessions[i], now); err != nil {
+			slog.Error("Failed to run scheduled session", "session_id", sessions[i].ID, "error", err)
+		}
+	}
+}
+
+// drive takes the session's next step if this replica holds its lock. The
+// steps are the host's own, taken on behalf of the session's host.
+func (s *schedulerService) drive(ctx context.Context, session *models.Session, now time.Time) error {
+	leader, err := s.liveRepo.AcquireDriver(ctx, session.ID, s.id, s.cfg.LockTTL())
+	if err != nil || !leader {
+		return err
+	}
+
+	if session.Status == models.QuizStatusDraft {
+		err := s.hostService.Start(ctx, session.ID, session.HostID)
+		switch {
+		case errors.Is(err, ErrQuizAlreadyStarted):
+			// The host got there first
+			return nil
+		case errors.Is(err, ErrNoQuestions), errors.Is(err, ErrNotAllowed), errors.Is(err, ErrSessionNotFound):
+			// Retrying will not help, as when its host has left the quiz's
+			// organisation; leave the session to its hosts
+			slog.Warn("Scheduled session cannot start, schedule cleared", "session_id", session.ID, "error", err)
+			return s.sessionRepo.SetSchedule(ctx, session.ID, nil, nil)
+		}
+		return err
+	}
+
+	state, err := s.liveRepo.GetState(ctx, session.ID)
+	if err != nil || state == nil || state.Paused {
+		return err
+	}
+
+	switch {
+	case !state.Revealed && !now.Before(state.EndsAt):
+		return s.hostService.RevealAnswer(ctx, session.ID, session.HostID)
+	case state.Revealed && !now.Before(state.EndsAt.Add(s.cfg.RevealDelay())):
+		if err := s.hostService.SkipQuestion(ctx, session.ID, session.HostID); err != nil {
+			return err
+		}
+		// Skipping the last question ends the session
+		next, err := s.liveRepo.GetState(ctx, session.ID)
+		if err != nil || next != nil {
+			return err
+		}
+		return s.liveRepo.ReleaseDriver(ctx, session.ID, s.id)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrScheduleInPast  = errors.New("scheduled_at must be in the future")
+	ErrLobbyNotOpen    = errors.New("the lobby is not open yet")
+	ErrInvalidWindow   = errors.New("closes_at must be after opens_at and in the future")
+	ErrUnhostedStart   = errors.New("self-paced and practice sessions start at once and cannot be scheduled")
+)
+
+const (
+	// Minutes a scheduled session's lobby opens before the start, unless set
+	defaultLobbyMinutes = 5
+	// Attempts each player gets at a self-paced session, unless set
+	defaultMaxAttempts = 1
+)
+
+type SessionService interface {
+	CreateSession(ctx context.Context, quizID, hostID uuid.UUID, input CreateSessionInput) (*models.Session, error)
+	ListSessions(ctx context.Context, quizID, userID uuid.UUID) ([]models.SessionSummary, error)
+	GetSession(ctx context.Context, sessionID, userID uuid.UUID) (*models.Session, error)
+	Schedule(ctx context.Context, sessionID, userID uuid.UUID, input ScheduleInput) (*models.Session, error)
+}
+
+type CreateSessionInput struct {
+	Label string
+	Mode  models.SessionMode
+	ScheduleInput
+	// Self-paced sessions only
+	OpensAt     *time.Time
+	ClosesAt    *time.Time
+	MaxAttempts *int
+}
+
+// ScheduleInput sets when a session starts by itself. A nil ScheduledAt
+// leaves the session to be started by its host.
+type ScheduleInput struct {
+	ScheduledAt  *time.Time
+	LobbyMinutes *int
+}
+
+// times returns when the session starts and its lobby opens
+func (in ScheduleInput) times(now time.Time) (*time.Time, *time.Time, error) {
+	if in.ScheduledAt == nil {
+		return nil, nil, nil
+	}
+	if !in.ScheduledAt.After(now) {
+		return nil, nil, ErrScheduleInPast
+	}
+	minutes := defaultLobbyMinutes
+	if in.LobbyMinutes != nil {
+		minutes = *in.LobbyMinutes
+	}
+	scheduledAt := in.ScheduledAt.UTC()
+	lobbyOpensAt := scheduledAt.Add(-time.Duration(minutes) * time.Minute)
+	return &scheduledAt, &lobbyOpensAt, nil
+}
+
+type sessionService struct {
+	sessionRepo  repository.SessionRepository
+	quizRepo     repository.QuizRepository
+	questionRepo repository.QuestionRepository
+	answerRepo   repository.AnswerRepository
+	orgRepo      repository.OrgRepository
+	bankService  BankService
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, quizRepo repository.QuizRepository, questionRepo repository.QuestionRepository, answerRepo repository.AnswerRepository, orgRepo repository.OrgRepository, bankService BankService) SessionService {
+	return &sessionService{
+		sessionRepo:  sessionRepo,
+		quizRepo:     quizRepo,
+		questionRepo: questionRepo,
+		answerRepo:   answerRepo,
+		orgRepo:      orgRepo,
+		bankService:  bankService,
+	}
+}
+
+// CreateSession opens a new DRAFT session of the quiz with its own join code.
+// Any number of sessions can run at once; each keeps its own players,
+// answers and leaderboard.
+//
+// Self-paced and practice sessions have no host to start them, so they
+// start now, pinning the quiz's latest version. A self-paced session takes
+// attempts within its window; a practice session is open until finished.
+func (s *sessionService) CreateSession(ctx context.Context, quizID, hostID uuid.UUID, input CreateSessionInput) (*models.Session, error) {
+	now := time.Now()
+	scheduledAt, lobbyOpensAt, err := input.times(now)
+	if err != nil {
+		return nil, err
+	}
+
+	quiz, err := s.quizRepo.GetByID(ctx, quizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	if err := authorizeQuiz(ctx, s.orgRepo, quiz, hostID, models.OrgRoleHost); err != nil {
+		return nil, err
+	}
+
+	code, err := generateQuizCode()
+	if err != nil {
+		return nil, err
+	}
+	session := &models.Session{
+		QuizID:       quizID,
+		HostID:       hostID,
+		Code:         code,
+		Label:        input.Label,
+		Status:       models.QuizStatusDraft,
+		Mode:         models.SessionModeLive,
+		ScheduledAt:  scheduledAt,
+		LobbyOpensAt: lobbyOpensAt,
+	}
+	if input.Mode == models.SessionModeSelfPaced || input.Mode == models.SessionModePractice {
+		if err := s.startUnhosted(ctx, quiz, session, input, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// startUnhosted sets up a self-paced or practice session as started on the
+// quiz's latest version
+func (s *sessionService) startUnhosted(ctx context.Context, quiz *models.Quiz, session *models.Session, input CreateSessionInput, now time.Time) error {
+	if input.ScheduledAt != nil {
+		return ErrUnhostedStart
+	}
+	selfPaced := input.Mode == models.SessionModeSelfPaced
+	if selfPaced && input.ClosesAt != nil && (!input.ClosesAt.After(now) || (input.OpensAt != nil && !input.ClosesAt.After(*input.OpensAt))) {
+		return ErrInvalidWindow
+	}
+
+	// As when a host starts a live session
+	if err := s.bankService.ResolveQuiz(ctx, quiz); err != nil {
+		return err
+	}
+	questions, err := s.questionRepo.GetByQuizID(ctx, quiz.ID, quiz.Version)
+	if err != nil {
+		return err
+	}
+	if len(questions) == 0 {
+		return ErrNoQuestions
+	}
+	if err := s.quizRepo.MarkPlayed(ctx, quiz.ID, quiz.Version); err != nil {
+		return err
+	}
+
+	session.Mode = input.Mode
+	session.Status = models.QuizStatusActive
+	session.Version = quiz.Version
+	session.StartedAt = &now
+	if !selfPaced {
+		return nil
+	}
+	session.OpensAt = input.OpensAt
+	session.ClosesAt = input.ClosesAt
+	session.MaxAttempts = defaultMaxAttempts
+	if input.MaxAttempts != nil {
+		session.MaxAttempts = *input.MaxAttempts
+	}
+	return nil
+}
+
+// ListSessions returns every session of the quiz, the default one first,
+// with the totals needed to compare their results
+func (s *sessionService) ListSessions(ctx context.Context, quizID, userID uuid.UUID) ([]models.SessionSummary, error) {
+	quiz, err := s.quizRepo.GetByID(ctx, quizID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuizNotFound
+		}
+		return nil, err
+	}
+	if err := authorizeQuiz(ctx, s.orgRepo, quiz, userID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessionRepo.ListByQuiz(ctx, quizID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.answerRepo.SummarizeSessions(ctx, quizID)
+	if err != nil {
+		return nil, err
+	}
+	bySession := make(map[uuid.UUID]repository.SessionTotals, len(totals))
+	for _, t := range totals {
+		bySession[t.SessionID] = t
+	}
+
+	summaries := make([]models.SessionSummary, 0, len(sessions))
+	for _, session := range sessions {
+		t := bySession[session.ID]
+		summary := models.SessionSummary{
+			Session:          session,
+			ParticipantCount: t.Participants,
+			AnswerCount:      t.Answers,
+			PercentCorrect:   percent(t.Correct, t.Answers),
+		}
+		if t.Participants > 0 {
+			summary.AverageScore = math.Round(float64(t.Points)*10/float64(t.Participants)) / 10
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// GetSession returns the session to members of its quiz's organisation
+func (s *sessionService) GetSession(ctx context.Context, sessionID, userID uuid.UUID) (*models.Session, error) {
+	session, _, err := authorizedSession(ctx, s.sessionRepo, s.quizRepo, s.orgRepo, sessionID, userID, models.OrgRoleViewer)
+	return session, err
+}
+
+// Schedule sets (or clears) when a session that has not started yet starts
+// by itself
+func (s *sessionService) Schedule(ctx context.Context, sessionID, userID uuid.UUID, input ScheduleInput) (*models.Session, error) {
+	session, _, err := authorizedSession(ctx, s.sessionRepo, s.quizRepo, s.orgRepo, sessionID, userID, models.OrgRoleHost)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.QuizStatusDraft {
+		return nil, ErrQuizAlreadyStarted
+	}
+
+	scheduledAt, lobbyOpensAt, err := input.times(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.SetSchedule(ctx, sessionID, scheduledAt, lobbyOpensAt); err != nil {
+		return nil, err
+	}
+	session.ScheduledAt = scheduledAt
+	session.LobbyOpensAt = lobbyOpensAt
+	return session, nil
+}
+
+// findSession returns the session and its quiz, with the quiz's latest
+// questions. It does not check access: members go through authorizedSession,
+// and players through the admission checks of the mode they play.
+func findSession(ctx context.Context, sessionRepo repository.SessionRepository, quizRepo repository.QuizRepository, sessionID uuid.UUID) (*models.Session, *models.Quiz, error) {
+	session, err := sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrSessionNotFound
+		}
+		return nil, nil, err
+	}
+	quiz, err := quizRepo.GetByID(ctx, session.QuizID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, quiz, nil
+}
+
+// authorizedSession is findSession for members of the quiz's organisation
+// whose role allows what needs the role. Sessions of other organisations'
+// quizzes are hidden, as if they did not exist.
+func authorizedSession(ctx context.Context, sessionRepo repository.SessionRepository, quizRepo repository.QuizRepository, orgRepo repository.OrgRepository, sessionID, userID uuid.UUID, need models.OrgRole) (*models.Session, *models.Quiz, error) {
+	session, quiz, err := findSession(ctx, sessionRepo, quizRepo, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := authorizeQuiz(ctx, orgRepo, quiz, userID, need); err != nil {
+		if errors.Is(err, ErrQuizNotFound) {
+			return nil, nil, ErrSessionNotFound
+		}
+		return nil, nil, err
+	}
+	return session, quiz, nil
+}
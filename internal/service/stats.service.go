@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrQuestionNotFound = errors.New("question not found")
+
+type StatsService interface {
+	GetQuestionStats(ctx context.Context, sessionID, questionID uuid.UUID) (*models.QuestionStats, error)
+	GetQuestionStatsForMember(ctx context.Context, sessionID, questionID, userID uuid.UUID) (*models.QuestionStats, error)
+	GetQuizReport(ctx context.Context, sessionID, userID uuid.UUID) (*models.QuizReport, error)
+}
+
+// Share of participants, by score, in each of the upper and lower groups
+// used for the discrimination index
+const discriminationGroupShare = 0.27
+
+type statsService struct {
+	quizRepo     repository.QuizRepository
+	sessionRepo  repository.SessionRepository
+	questionRepo repository.QuestionRepository
+	answerRepo   repository.AnswerRepository
+	userRepo     repository.UserRepository
+	reportRepo   repository.ReportRepository
+	orgRepo      repository.OrgRepository
+}
+
+func NewStatsService(quizRepo repository.QuizRepository, sessionRepo repository.SessionRepository, questionRepo repository.QuestionRepository, answerRepo repository.AnswerRepository, userRepo repository.UserRepository, reportRepo repository.ReportRepository, orgRepo repository.OrgRepository) StatsService {
+	return &statsService{
+		quizRepo:     quizRepo,
+		sessionRepo:  sessionRepo,
+		questionRepo: questionRepo,
+		answerRepo:   answerRepo,
+		userRepo:     userRepo,
+		reportRepo:   reportRepo,
+		orgRepo:      orgRepo,
+	}
+}
+
+// GetQuestionStatsForMember returns the statistics of a question in a
+// session if userID is a member of its quiz's organisation
+func (s *statsService) GetQuestionStatsForMember(ctx context.Context, sessionID, questionID, userID uuid.UUID) (*models.QuestionStats, error) {
+	if _, _, err := authorizedSession(ctx, s.sessionRepo, s.quizRepo, s.orgRepo, sessionID, userID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+	return s.GetQuestionStats(ctx, sessionID, questionID)
+}
+
+// GetQuestionStats aggregates a session's stored answers to a question:
+// distribution per option, share of correct answers, median response time
+// and the fastest correct player.
+func (s *statsService) GetQuestionStats(ctx context.Context, sessionID, questionID uuid.UUID) (*models.QuestionStats, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	question, err := s.questionRepo.GetByID(ctx, questionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	if question.QuizID != session.QuizID {
+		return nil, ErrQuestionNotFound
+	}
+
+	counts, err := s.answerRepo.CountByOption(ctx, sessionID, questionID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.QuestionStats{
+		QuestionID:    questionID,
+		CorrectAnswer: question.CorrectAnswer,
+	}
+	for _, n := range counts {
+		stats.TotalAnswers += n
+	}
+	stats.CorrectAnswers = counts[question.CorrectAnswer]
+	stats.PercentCorrect = percent(stats.CorrectAnswers, stats.TotalAnswers)
+
+	// Every option appears, in question order, followed by any free-form
+	// answers that match none of them
+	for _, option := range orderedOptions(question.Options, counts) {
+		stats.Distribution = append(stats.Distribution, models.OptionStat{
+			Option:    option,
+			Count:     counts[option],
+			Percent:   percent(counts[option], stats.TotalAnswers),
+			IsCorrect: option == question.CorrectAnswer,
+		})
+	}
+
+	median, err := s.answerRepo.MedianResponseTime(ctx, sessionID, questionID)
+	if err != nil {
+		return nil, err
+	}
+	if median != nil {
+		ms := int64(math.Round(*median))
+		stats.MedianResponseMs = &ms
+	}
+
+	fastest, err := s.answerRepo.FastestCorrect(ctx, sessionID, questionID)
+	if err != nil {
+		return nil, err
+	}
+	if fastest != nil {
+		stats.FastestCorrect = &models.FastestAnswer{
+			UserID:         fastest.UserID,
+			ResponseTimeMs: *fastest.ResponseTimeMs,
+		}
+		if user, err := s.userRepo.FindByID(ctx, fastest.UserID.String()); err == nil {
+			stats.FastestCorrect.Username = user.Username
+		}
+	}
+
+	return stats, nil
+}
+
+// GetQuizReport builds the analytics report of a session for members of its
+// quiz's organisation.
+// The answers of a finished session no longer change, so its report is
+// cached.
+func (s *statsService) GetQuizReport(ctx context.Context, sessionID, userID uuid.UUID) (*models.QuizReport, error) {
+	session, quiz, err := authorizedSession(ctx, s.sessionRepo, s.quizRepo, s.orgRepo, sessionID, userID, models.OrgRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	finished := session.Status == models.QuizStatusFinished
+	if finished {
+		cached, err := s.reportRepo.GetCached(ctx, sessionID)
+		if err != nil {
+			log.Printf("error reading cached report for session %s: %v", sessionID, err)
+		} else if cached != nil {
+			return cached, nil
+		}
+	}
+
+	report, err := s.buildReport(ctx, session, quiz)
+	if err != nil {
+		return nil, err
+	}
+
+	if finished {
+		if err := s.reportRepo.SetCached(ctx, sessionID, report); err != nil {
+			log.Printf("error caching report for session %s: %v", sessionID, err)
+		}
+	}
+	return report, nil
+}
+
+func (s *statsService) buildReport(ctx context.Context, session *models.Session, quiz *models.Quiz) (*models.QuizReport, error) {
+	questions, err := s.questionRepo.GetByQuizID(ctx, quiz.ID, session.RunVersion(quiz.Version))
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := s.answerRepo.SummarizeQuestions(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	optionCounts, err := s.answerRepo.CountOptionsByQuestion(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.answerRepo.SummarizeParticipants(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(totals))
+	for _, total := range totals {
+		userIDs = append(userIDs, total.UserID)
+	}
+	users, err := s.userRepo.FindByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	usernames := make(map[uuid.UUID]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	report := &models.QuizReport{
+		QuizID:           quiz.ID,
+		SessionID:        session.ID,
+		Title:            quiz.Title,
+		Status:           session.Status,
+		ParticipantCount: len(totals),
+		Questions:        make([]models.QuestionReport, 0, len(questions)),
+		Participants:     make([]models.ParticipantReport, 0, len(totals)),
+		GeneratedAt:      time.Now().UTC(),
+	}
+
+	// Participants come ranked, highest score first
+	totalScore := 0
+	byUser := make(map[uuid.UUID]*models.ParticipantReport, len(totals))
+	for _, total := range totals {
+		report.Participants = append(report.Participants, models.ParticipantReport{
+			UserID:   total.UserID,
+			Username: usernames[total.UserID],
+			Rank:     total.Rank,
+			Score:    total.Score,
+			Answered: total.Answered,
+			Correct:  total.Correct,
+			Accuracy: percent(total.Correct, int64(len(questions))),
+			Timeline: make([]models.ScorePoint, len(questions)),
+		})
+		totalScore += total.Score
+	}
+	for i := range report.Participants {
+		byUser[report.Participants[i].UserID] = &report.Participants[i]
+	}
+	if len(totals) > 0 {
+		report.AverageScore = math.Round(float64(totalScore)*10/float64(len(totals))) / 10
+	}
+
+	// Timelines follow question order; a skipped question scores nothing
+	questionIndex := make(map[uuid.UUID]int, len(questions))
+	for i, question := range questions {
+		questionIndex[question.ID] = i
+	}
+	err = s.answerRepo.StreamScorePoints(ctx, session.ID, func(userID uuid.UUID, point models.ScorePoint) error {
+		participant, ok := byUser[userID]
+		i, known := questionIndex[point.QuestionID]
+		if ok && known {
+			participant.Timeline[i] = point
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for p := range report.Participants {
+		participant := &report.Participants[p]
+		cumulative := 0
+		for i, question := range questions {
+			point := &participant.Timeline[i]
+			point.QuestionID = question.ID
+			point.Order = question.Order
+			cumulative += point.Points
+			point.CumulativeScore = cumulative
+		}
+	}
+
+	// Upper and lower groups by score, for discrimination and distractors
+	groupSize := 0
+	if n := len(totals); n >= 2 {
+		groupSize = max(1, int(math.Round(float64(n)*discriminationGroupShare)))
+	}
+	var groupCounts []repository.GroupOptionCount
+	if groupSize > 0 {
+		groupCounts, err = s.answerRepo.CountOptionsByGroup(ctx, session.ID, groupSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	groupsByQuestion := make(map[uuid.UUID]map[string]repository.GroupOptionCount)
+	for _, row := range groupCounts {
+		if groupsByQuestion[row.QuestionID] == nil {
+			groupsByQuestion[row.QuestionID] = make(map[string]repository.GroupOptionCount)
+		}
+		groupsByQuestion[row.QuestionID][row.Answer] = row
+	}
+
+	summaryByQuestion := make(map[uuid.UUID]repository.QuestionSummary, len(summaries))
+	for _, summary := range summaries {
+		summaryByQuestion[summary.QuestionID] = summary
+	}
+	countsByQuestion := make(map[uuid.UUID]map[string]int64)
+	for _, row := range optionCounts {
+		if countsByQuestion[row.QuestionID] == nil {
+			countsByQuestion[row.QuestionID] = make(map[string]int64)
+		}
+		countsByQuestion[row.QuestionID][row.Answer] = row.Count
+	}
+
+	for _, question := range questions {
+		summary := summaryByQuestion[question.ID]
+		questionReport := models.QuestionReport{
+			QuestionID:    question.ID,
+			Text:          question.Text,
+			Order:         question.Order,
+			CorrectAnswer: question.CorrectAnswer,
+			Responses:     summary.Responses,
+			Correct:       summary.Correct,
+			PValue:        ratio(summary.Correct, int64(len(totals))),
+		}
+		if summary.AvgResponseMs != nil {
+			avg := math.Round(*summary.AvgResponseMs)
+			questionReport.AvgResponseMs = &avg
+		}
+
+		groups := groupsByQuestion[question.ID]
+		if groupSize > 0 {
+			var upperCorrect, lowerCorrect int64
+			for _, group := range groups {
+				upperCorrect += group.UpperCorrect
+				lowerCorrect += group.LowerCorrect
+			}
+			index := math.Round((float64(upperCorrect)-float64(lowerCorrect))*100/float64(groupSize)) / 100
+			questionReport.DiscriminationIndex = &index
+		}
+
+		counts := countsByQuestion[question.ID]
+		for _, option := range orderedOptions(question.Options, counts) {
+			questionReport.Options = append(questionReport.Options, models.DistractorStat{
+				Option:     option,
+				IsCorrect:  option == question.CorrectAnswer,
+				Count:      counts[option],
+				Percent:    percent(counts[option], summary.Responses),
+				UpperCount: groups[option].UpperCount,
+				LowerCount: groups[option].LowerCount,
+			})
+		}
+		report.Questions = append(report.Questions, questionReport)
+	}
+
+	return report, nil
+}
+
+// orderedOptions lists a question's options in order, followed by any other
+// submitted answers, most popular first
+func orderedOptions(options []string, counts map[string]int64) []string {
+	ordered := make([]string, 0, len(options)+len(counts))
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		seen[option] = true
+		ordered = append(ordered, option)
+	}
+	var others []string
+	for answer := range counts {
+		if !seen[answer] {
+			others = append(others, answer)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		if counts[others[i]] != counts[others[j]] {
+			return counts[others[i]] > counts[others[j]]
+		}
+		return others[i] < others[j]
+	})
+	return append(ordered, others...)
+}
+
+// ratio returns part as a fraction of total, rounded to two decimals
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*100/float64(total)) / 100
+}
+
+// percent returns part as a percentage of total, rounded to one decimal
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
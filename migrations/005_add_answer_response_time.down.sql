@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_answers_question_response_time;
+ALTER TABLE answers DROP COLUMN IF EXISTS response_time_ms;
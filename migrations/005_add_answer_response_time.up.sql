@@ -0,0 +1,3 @@
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS response_time_ms BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_answers_question_response_time ON answers(question_id, response_time_ms);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_answers_quiz_user_created;
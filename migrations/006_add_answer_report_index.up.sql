@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_answers_quiz_user_created ON answers(quiz_id, user_id, created_at);
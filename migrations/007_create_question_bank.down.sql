@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_questions_bank_question_id;
+ALTER TABLE questions DROP COLUMN IF EXISTS bank_question_id;
+DROP TABLE IF EXISTS quiz_bank_items;
+DROP TABLE IF EXISTS bank_question_tags;
+DROP TABLE IF EXISTS bank_questions;
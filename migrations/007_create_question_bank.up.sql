@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS bank_questions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    options JSONB NOT NULL, -- Array of strings
+    correct_answer VARCHAR(255) NOT NULL,
+    time_limit INTEGER NOT NULL DEFAULT 30, -- Seconds
+    points INTEGER NOT NULL DEFAULT 100,
+    difficulty VARCHAR(10) NOT NULL DEFAULT 'medium', -- easy, medium, hard
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_questions_owner_difficulty ON bank_questions(owner_id, difficulty);
+
+CREATE TABLE IF NOT EXISTS bank_question_tags (
+    bank_question_id UUID NOT NULL REFERENCES bank_questions(id) ON DELETE CASCADE,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (bank_question_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_question_tags_tag ON bank_question_tags(tag);
+
+-- Either one bank question or a random draw, resolved when the quiz starts
+CREATE TABLE IF NOT EXISTS quiz_bank_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    item_order INTEGER NOT NULL DEFAULT 0,
+    bank_question_id UUID REFERENCES bank_questions(id) ON DELETE CASCADE,
+    draw_count INTEGER NOT NULL DEFAULT 0,
+    draw_tags JSONB,
+    draw_difficulty VARCHAR(10),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_bank_items_quiz_id ON quiz_bank_items(quiz_id);
+
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS bank_question_id UUID REFERENCES bank_questions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_questions_bank_question_id ON questions(bank_question_id);
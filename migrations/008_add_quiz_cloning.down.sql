@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_quizzes_cloned_from;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS cloned_from;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS allow_clone;
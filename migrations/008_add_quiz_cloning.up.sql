@@ -0,0 +1,4 @@
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS allow_clone BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS cloned_from UUID REFERENCES quizzes(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_quizzes_cloned_from ON quizzes(cloned_from);
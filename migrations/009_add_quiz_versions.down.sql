@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_questions_quiz_version;
+ALTER TABLE questions DROP COLUMN IF EXISTS previous_id;
+ALTER TABLE questions DROP COLUMN IF EXISTS version;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS played_version;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS version;
@@ -0,0 +1,10 @@
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS played_version INTEGER NOT NULL DEFAULT 0;
+
+-- Quizzes that already ran played their only version
+UPDATE quizzes SET played_version = 1 WHERE status <> 'DRAFT';
+
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS previous_id UUID REFERENCES questions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_questions_quiz_version ON questions(quiz_id, version, item_order);
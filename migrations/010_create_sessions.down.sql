@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_answers_session_user_created;
+CREATE INDEX IF NOT EXISTS idx_answers_quiz_user_created ON answers(quiz_id, user_id, created_at);
+DROP INDEX IF EXISTS idx_answers_session_id;
+ALTER TABLE answers DROP COLUMN IF EXISTS session_id;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    host_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(10) NOT NULL UNIQUE,
+    label VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'DRAFT', -- DRAFT, ACTIVE, FINISHED
+    version INTEGER NOT NULL DEFAULT 0, -- Quiz version pinned at start
+    started_at TIMESTAMP WITH TIME ZONE,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_quiz_id ON sessions(quiz_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_host_id ON sessions(host_id);
+
+-- Every quiz's default session shares its ID and code, so existing runs,
+-- leaderboards and clients carry on as they were
+INSERT INTO sessions (id, quiz_id, host_id, code, status, version, created_at, updated_at)
+SELECT id, id, owner_id, code, status, played_version, created_at, updated_at FROM quizzes;
+
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES sessions(id) ON DELETE CASCADE;
+UPDATE answers SET session_id = quiz_id;
+ALTER TABLE answers ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_answers_session_id ON answers(session_id);
+
+-- Player timelines are now read per session
+DROP INDEX IF EXISTS idx_answers_quiz_user_created;
+CREATE INDEX IF NOT EXISTS idx_answers_session_user_created ON answers(session_id, user_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sessions_scheduled_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS lobby_opens_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS scheduled_at;
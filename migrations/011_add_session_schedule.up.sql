@@ -0,0 +1,3 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS lobby_opens_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_sessions_scheduled_at ON sessions(scheduled_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_answers_attempt_id;
+ALTER TABLE answers DROP COLUMN IF EXISTS attempt_id;
+DROP TABLE IF EXISTS attempts;
+ALTER TABLE sessions DROP COLUMN IF EXISTS max_attempts;
+ALTER TABLE sessions DROP COLUMN IF EXISTS closes_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS opens_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS mode;
@@ -0,0 +1,29 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS mode VARCHAR(20) NOT NULL DEFAULT 'LIVE';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS opens_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS closes_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS max_attempts INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL, -- 1 for the player's first attempt
+    question_index INTEGER NOT NULL DEFAULT 0,
+    question_started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    score INTEGER NOT NULL DEFAULT 0,
+    correct INTEGER NOT NULL DEFAULT 0,
+    total_time_ms BIGINT NOT NULL DEFAULT 0,
+    best BOOLEAN NOT NULL DEFAULT FALSE, -- The player's attempt that counts
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (session_id, user_id, number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attempts_session_id ON attempts(session_id);
+CREATE INDEX IF NOT EXISTS idx_attempts_user_id ON attempts(user_id);
+CREATE INDEX IF NOT EXISTS idx_attempts_best ON attempts(best);
+
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS attempt_id UUID REFERENCES attempts(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_answers_attempt_id ON answers(attempt_id);
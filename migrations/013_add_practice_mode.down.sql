@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS masteries;
+DROP TABLE IF EXISTS practice_answers;
+ALTER TABLE bank_questions DROP COLUMN IF EXISTS explanation;
+ALTER TABLE questions DROP COLUMN IF EXISTS explanation;
@@ -0,0 +1,32 @@
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS explanation TEXT NOT NULL DEFAULT '';
+ALTER TABLE bank_questions ADD COLUMN IF NOT EXISTS explanation TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS practice_answers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    answer TEXT NOT NULL,
+    is_correct BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_practice_answers_quiz_id ON practice_answers(quiz_id);
+CREATE INDEX IF NOT EXISTS idx_practice_answers_session_id ON practice_answers(session_id);
+CREATE INDEX IF NOT EXISTS idx_practice_answers_question_id ON practice_answers(question_id);
+CREATE INDEX IF NOT EXISTS idx_practice_answers_user_id ON practice_answers(user_id);
+
+CREATE TABLE IF NOT EXISTS masteries (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    correct INTEGER NOT NULL DEFAULT 0,
+    streak INTEGER NOT NULL DEFAULT 0, -- Correct answers in a row
+    level DOUBLE PRECISION NOT NULL DEFAULT 0, -- From 0 to 1, recent answers weigh most
+    last_practiced_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (user_id, question_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_masteries_quiz_id ON masteries(quiz_id);
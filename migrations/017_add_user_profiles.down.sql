@@ -0,0 +1,10 @@
+ALTER TABLE attempts DROP CONSTRAINT IF EXISTS attempts_user_id_fkey;
+ALTER TABLE attempts ADD CONSTRAINT attempts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_host_id_fkey;
+ALTER TABLE sessions ADD CONSTRAINT sessions_host_id_fkey FOREIGN KEY (host_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE quizzes DROP CONSTRAINT IF EXISTS quizzes_owner_id_fkey;
+ALTER TABLE quizzes ADD CONSTRAINT quizzes_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
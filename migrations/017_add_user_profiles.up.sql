@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE; -- Set once the account is anonymised
+
+-- Deleted accounts are anonymised, never removed, and removing a user must
+-- not take other players' game history with their quizzes and sessions
+ALTER TABLE quizzes DROP CONSTRAINT IF EXISTS quizzes_owner_id_fkey;
+ALTER TABLE quizzes ADD CONSTRAINT quizzes_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE RESTRICT;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_host_id_fkey;
+ALTER TABLE sessions ADD CONSTRAINT sessions_host_id_fkey FOREIGN KEY (host_id) REFERENCES users(id) ON DELETE RESTRICT;
+ALTER TABLE attempts DROP CONSTRAINT IF EXISTS attempts_user_id_fkey;
+ALTER TABLE attempts ADD CONSTRAINT attempts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
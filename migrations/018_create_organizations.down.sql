@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_quizzes_org_id;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS organizations;
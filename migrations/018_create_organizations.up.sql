@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    personal_user_id UUID UNIQUE REFERENCES users(id) ON DELETE RESTRICT, -- Set on each user's personal organisation
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS memberships (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL, -- viewer, host, editor, admin
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships(user_id);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(), -- The jti of the link's token
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    email VARCHAR(255), -- Only this address may accept, if set
+    max_uses INTEGER NOT NULL DEFAULT 1,
+    uses INTEGER NOT NULL DEFAULT 0,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_org_id ON invitations(org_id);
+
+-- Every existing user gets a personal organisation holding their quizzes
+INSERT INTO organizations (name, personal_user_id)
+SELECT username, id FROM users
+ON CONFLICT (personal_user_id) DO NOTHING;
+
+INSERT INTO memberships (org_id, user_id, role)
+SELECT id, personal_user_id, 'admin' FROM organizations WHERE personal_user_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE quizzes ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations(id) ON DELETE RESTRICT;
+UPDATE quizzes SET org_id = o.id FROM organizations o WHERE o.personal_user_id = quizzes.owner_id AND quizzes.org_id IS NULL;
+ALTER TABLE quizzes ALTER COLUMN org_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_quizzes_org_id ON quizzes(org_id);
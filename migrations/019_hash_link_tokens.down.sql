@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_invitations_token_hash;
+ALTER TABLE invitations DROP COLUMN IF EXISTS token_hash;
+
+DROP INDEX IF EXISTS idx_user_tokens_token_hash;
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS token_hash;
@@ -0,0 +1,8 @@
+-- Emailed and invitation links carry a random token, stored hashed, rather
+-- than one signed with the rotating access token keys. Links sent before
+-- this migration stop working.
+ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64); -- SHA-256 of the token, in hex
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_tokens_token_hash ON user_tokens(token_hash);
+
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64); -- SHA-256 of the token, in hex
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations(token_hash);
@@ -0,0 +1,104 @@
+// Package ratelimit has in-memory token buckets, for limits checked too
+// often to ask Redis each time, such as every WebSocket message. Buckets
+// live on one replica, so a client spread over several replicas gets each
+// replica's allowance.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleAfter is how long a keyed bucket may go untouched before it is
+// dropped; any bucket refills well within it
+const idleAfter = 10 * time.Minute
+
+// Rate allows Burst tokens at once, refilling PerSecond tokens a second. A
+// rate of zero does not limit. Without a burst, one second's worth of
+// tokens, and at least one, may be taken at once.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+func (r Rate) Unlimited() bool {
+	return r.PerSecond <= 0
+}
+
+func (r Rate) burst() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return math.Max(1, math.Ceil(r.PerSecond))
+}
+
+// Bucket is a single token bucket. It is not safe for concurrent use.
+type Bucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full bucket
+func NewBucket(rate Rate, now time.Time) *Bucket {
+	return &Bucket{rate: rate, tokens: rate.burst(), last: now}
+}
+
+// Take takes a token, returning how long until one is free if the bucket is
+// empty, or 0
+func (b *Bucket) Take(now time.Time) time.Duration {
+	if b.rate.Unlimited() {
+		return 0
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.rate.burst(), b.tokens+elapsed.Seconds()*b.rate.PerSecond)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate.PerSecond * float64(time.Second))
+}
+
+// Buckets keeps a bucket per key, all at the same rate, dropping those that
+// have been idle for a while. It is safe for concurrent use.
+type Buckets struct {
+	rate Rate
+
+	mu       sync.Mutex
+	buckets  map[string]*Bucket
+	prunedAt time.Time
+}
+
+func NewBuckets(rate Rate) *Buckets {
+	return &Buckets{rate: rate, buckets: make(map[string]*Bucket), prunedAt: time.Now()}
+}
+
+// Take takes a token from the key's bucket, as Bucket.Take
+func (b *Buckets) Take(key string, now time.Time) time.Duration {
+	if b.rate.Unlimited() {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.prunedAt) > idleAfter {
+		for k, bucket := range b.buckets {
+			if now.Sub(bucket.last) > idleAfter {
+				delete(b.buckets, k)
+			}
+		}
+		b.prunedAt = now
+	}
+
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = NewBucket(b.rate, now)
+		b.buckets[key] = bucket
+	}
+	return bucket.Take(now)
+}
@@ -1,6 +1,13 @@
 package response
 
-import "github.com/gin-gonic/gin"
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
 
 type Response struct {
 	Success bool        `json:"success"`
@@ -24,3 +31,10 @@ func Error(c *gin.Context, statusCode int, message string, err interface{}) {
 		Error:   err,
 	})
 }
+
+// TooManyRequests refuses a request that may be retried after wait, rounded
+// up to whole seconds so clients never retry too early
+func TooManyRequests(c *gin.Context, message string, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	Error(c, http.StatusTooManyRequests, message, nil)
+}
@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.txt")
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.Mail.LogFile = outbox
+		cfg.Account.BaseURL = "https://quiz.example.com/"
+	})
+
+	// lastLink returns the token of the newest link to the path
+	lastLink := func(path string) string {
+		data, err := os.ReadFile(outbox)
+		require.NoError(t, err)
+		matches := regexp.MustCompile(`https://quiz\.example\.com`+path+`\?token=(\S+)`).FindAllStringSubmatch(string(data), -1)
+		require.NotEmpty(t, matches)
+		token, err := url.QueryUnescape(matches[len(matches)-1][1])
+		require.NoError(t, err)
+		return token
+	}
+	verify := func(token string) string {
+		return string(request(t, server, "POST", "/api/v1/auth/verify-email", fmt.Sprintf(`{"token":"%s"}`, token)))
+	}
+	reset := func(token, password string) string {
+		return string(request(t, server, "POST", "/api/v1/auth/password/reset", fmt.Sprintf(`{"token":"%s","password":"%s"}`, token, password)))
+	}
+	login := func(password string) []byte {
+		return request(t, server, "POST", "/api/v1/auth/login", fmt.Sprintf(`{"email":"forgetful@example.com","password":"%s"}`, password))
+	}
+
+	// Registering emails a verification link
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"forgetful","password":"password","email":"forgetful@example.com"}`)
+	firstLink := lastLink("/verify-email")
+	assert.Contains(t, string(login("password")), `"email_verified":false`)
+
+	// Asking again replaces the first link
+	token := getToken(t, login("password"))
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/auth/verify-email/resend", "", token)), "Verification email sent")
+	assert.Contains(t, verify(firstLink), "invalid or has expired")
+
+	// A verification link is not an access token, nor a reset link
+	link := lastLink("/verify-email")
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", link)), "Invalid or expired token")
+	assert.Contains(t, reset(link, "hijacked"), "invalid or has expired")
+
+	assert.Contains(t, verify(link), "Email verified")
+	assert.Contains(t, verify(link), "invalid or has expired")
+	assert.Contains(t, string(login("password")), `"email_verified":true`)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/auth/verify-email/resend", "", token)), "already verified")
+
+	// Unknown addresses get the same answer and no email
+	before, err := os.ReadFile(outbox)
+	require.NoError(t, err)
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/password/forgot", `{"email":"nobody@example.com"}`)), "If an account uses this address")
+	after, err := os.ReadFile(outbox)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// Resetting the password logs the user out everywhere
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/password/forgot", `{"email":"forgetful@example.com"}`)), "If an account uses this address")
+	resetLink := lastLink("/reset-password")
+	assert.Contains(t, reset(resetLink, "new-password"), "Password reset")
+	assert.Contains(t, reset(resetLink, "again-password"), "invalid or has expired")
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", token)), "revoked")
+	assert.Contains(t, string(login("password")), "Invalid email or password")
+	getToken(t, login("new-password"))
+}
+
+func TestLinksOutliveKeyRotation(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.txt")
+	keysDir := t.TempDir()
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.Mail.LogFile = outbox
+		cfg.Account.BaseURL = "https://quiz.example.com"
+		cfg.JWT.Secret = ""
+		cfg.JWT.Algorithm = "EdDSA"
+		cfg.JWT.KeysDir = keysDir
+		cfg.JWT.KeyPollSeconds = 1
+	})
+
+	linkToken := func(path string) string {
+		data, err := os.ReadFile(outbox)
+		require.NoError(t, err)
+		matches := regexp.MustCompile(`https://quiz\.example\.com`+path+`\?token=(\S+)`).FindAllStringSubmatch(string(data), -1)
+		require.NotEmpty(t, matches)
+		token, err := url.QueryUnescape(matches[len(matches)-1][1])
+		require.NoError(t, err)
+		return token
+	}
+
+	// Links are sent while the first key signs
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"patient","password":"password","email":"patient@example.com"}`)
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"invitee","password":"password","email":"invitee@example.com"}`)
+	verifyLink := linkToken("/verify-email")
+	request(t, server, "POST", "/api/v1/auth/password/forgot", `{"email":"invitee@example.com"}`)
+	resetLink := linkToken("/reset-password")
+	admin := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"patient@example.com","password":"password"}`))
+	var org struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/orgs", `{"name":"Patience"}`, admin), &org))
+	requestWithAuth(t, server, "POST", "/api/v1/orgs/"+org.Data.ID+"/invitations", `{"role":"viewer","email":"invitee@example.com"}`, admin)
+	inviteLink := linkToken("/invitations")
+
+	// A new key takes over and the old one is dropped, as after a rotation
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	old, err := filepath.Glob(filepath.Join(keysDir, "*.pem"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(keysDir, "zz-rotated.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	for _, path := range old {
+		require.NoError(t, os.Remove(path))
+	}
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(server.URL + "/.well-known/jwks.json")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var set models.JWKS
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&set))
+		return len(set.Keys) == 1 && set.Keys[0].Kid == "zz-rotated"
+	}, 3*time.Second, 100*time.Millisecond)
+
+	// Every link sent before still works
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/verify-email", fmt.Sprintf(`{"token":"%s"}`, verifyLink))), "Email verified")
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/password/reset", fmt.Sprintf(`{"token":"%s","password":"new-password"}`, resetLink))), "Password reset")
+	invitee := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"invitee@example.com","password":"new-password"}`))
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/invitations/accept", fmt.Sprintf(`{"token":"%s"}`, inviteLink), invitee)), `"role":"viewer"`)
+}
@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfPacedSessions(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"tutor","password":"password","email":"tutor@example.com"}`)
+	tutorToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"tutor@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"student_a","password":"password","email":"student_a@example.com"}`)
+	studentAToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"student_a@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"student_b","password":"password","email":"student_b@example.com"}`)
+	studentBToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"student_b@example.com","password":"password"}`))
+
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Homework"}`, tutorToken), &quiz))
+	quizID := quiz.Data.ID.String()
+	type questionResp struct {
+		Data models.Question `json:"data"`
+	}
+	var q1, q2 questionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"2+2?","options":["4","5"],"correct_answer":"4","time_limit":30,"points":100,"order":1}`, tutorToken), &q1))
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"3+3?","options":["6","7"],"correct_answer":"6","time_limit":1,"points":100,"order":2}`, tutorToken), &q2))
+
+	sessionsPath := fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID)
+	closesAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", sessionsPath, fmt.Sprintf(`{"mode":"SELF_PACED","scheduled_at":"%s"}`, closesAt), tutorToken)), "cannot be scheduled")
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", sessionsPath, `{"mode":"SELF_PACED","closes_at":"2020-01-01T00:00:00Z"}`, tutorToken)), "closes_at must be after")
+
+	// A self-paced session starts at once, on the latest version
+	type sessionResp struct {
+		Data models.Session `json:"data"`
+	}
+	var homework sessionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", sessionsPath, fmt.Sprintf(`{"label":"Week 1","mode":"SELF_PACED","closes_at":"%s","max_attempts":2}`, closesAt), tutorToken), &homework))
+	assert.Equal(t, models.SessionModeSelfPaced, homework.Data.Mode)
+	assert.Equal(t, models.QuizStatusActive, homework.Data.Status)
+	assert.Equal(t, 1, homework.Data.Version)
+	assert.Equal(t, 2, homework.Data.MaxAttempts)
+
+	attemptsPath := fmt.Sprintf("/api/v1/sessions/%s/attempts", homework.Data.ID)
+	submitPath := fmt.Sprintf("/api/v1/sessions/%s/submit", homework.Data.ID)
+	type stateResp struct {
+		Data models.AttemptState `json:"data"`
+	}
+	type answerResp struct {
+		Data models.Answer `json:"data"`
+	}
+	submit := func(token string, questionID fmt.Stringer, answer string) []byte {
+		return requestWithAuth(t, server, "POST", submitPath, fmt.Sprintf(`{"question_id":"%s","answer":"%s"}`, questionID, answer), token)
+	}
+
+	assert.Contains(t, string(submit(studentAToken, q1.Data.ID, "4")), "not started this quiz")
+
+	// Each student has their own cursor
+	var first stateResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", attemptsPath, "", studentAToken), &first))
+	assert.Equal(t, 1, first.Data.Attempt.Number)
+	require.NotNil(t, first.Data.CurrentQuestion)
+	assert.Equal(t, q1.Data.ID, first.Data.CurrentQuestion.ID)
+	require.NotNil(t, first.Data.AttemptsLeft)
+	assert.Equal(t, 1, *first.Data.AttemptsLeft)
+
+	// Starting again resumes the unfinished attempt
+	var resumed stateResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", attemptsPath, "", studentAToken), &resumed))
+	assert.Equal(t, first.Data.Attempt.ID, resumed.Data.Attempt.ID)
+
+	assert.Contains(t, string(submit(studentAToken, q2.Data.ID, "6")), "current question")
+	var answer answerResp
+	require.NoError(t, json.Unmarshal(submit(studentAToken, q1.Data.ID, "4"), &answer))
+	assert.True(t, answer.Data.IsCorrect)
+	assert.Greater(t, answer.Data.Points, 90) // Answered well within the timer
+	assert.NotNil(t, answer.Data.AttemptID)
+	assert.Contains(t, string(submit(studentAToken, q1.Data.ID, "4")), "current question")
+
+	// Left past its timer, the last question scores nothing and ends the attempt
+	time.Sleep(1100 * time.Millisecond)
+	var timedOut stateResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", attemptsPath+"/current", "", studentAToken), &timedOut))
+	assert.Nil(t, timedOut.Data.CurrentQuestion)
+	assert.NotNil(t, timedOut.Data.Attempt.FinishedAt)
+	assert.Equal(t, 1, timedOut.Data.Attempt.Correct)
+	assert.GreaterOrEqual(t, timedOut.Data.Attempt.TotalTimeMs, int64(1000))
+	assert.Contains(t, string(submit(studentAToken, q2.Data.ID, "6")), "attempt is finished")
+
+	// The second attempt does better and becomes the one that counts
+	var second stateResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", attemptsPath, "", studentAToken), &second))
+	assert.Equal(t, 2, second.Data.Attempt.Number)
+	assert.Equal(t, 0, *second.Data.AttemptsLeft)
+	submit(studentAToken, q1.Data.ID, "4")
+	submit(studentAToken, q2.Data.ID, "6")
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", attemptsPath, "", studentAToken)), "no attempts left")
+
+	// Student B gets one right at their own pace
+	requestWithAuth(t, server, "POST", attemptsPath, "", studentBToken)
+	submit(studentBToken, q1.Data.ID, "4")
+	submit(studentBToken, q2.Data.ID, "7")
+
+	// Ranked by the best attempt's score, then its time
+	var leaderboard struct {
+		Data []models.LeaderboardEntry `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/leaderboard", homework.Data.ID), "", tutorToken), &leaderboard))
+	require.Len(t, leaderboard.Data, 2)
+	assert.Equal(t, "student_a", leaderboard.Data[0].Username)
+	assert.Greater(t, leaderboard.Data[0].Score, float64(180))
+	assert.Equal(t, "student_b", leaderboard.Data[1].Username)
+	assert.Less(t, leaderboard.Data[1].Score, float64(101))
+
+	// Only the best attempt shows in the results
+	var report struct {
+		Data models.QuizReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/report", homework.Data.ID), "", tutorToken), &report))
+	require.Len(t, report.Data.Participants, 2)
+	assert.Equal(t, "student_a", report.Data.Participants[0].Username)
+	assert.Equal(t, int(leaderboard.Data[0].Score), report.Data.Participants[0].Score)
+
+	// Nothing can be attempted outside the window
+	opensAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	var later sessionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", sessionsPath, fmt.Sprintf(`{"mode":"SELF_PACED","opens_at":"%s"}`, opensAt), tutorToken), &later))
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/sessions/%s/attempts", later.Data.ID), "", studentBToken)), "not open for attempts")
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/sessions/%s/attempts", quizID), "", studentBToken)), "not self-paced")
+}
+
+func TestConcurrentAttemptStarts(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"tutor","password":"password","email":"tutor@example.com"}`)
+	tutorToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"tutor@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"student","password":"password","email":"student@example.com"}`)
+	studentToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"student@example.com","password":"password"}`))
+
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Homework"}`, tutorToken), &quiz))
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quiz.Data.ID), `{"text":"2+2?","options":["4","5"],"correct_answer":"4","time_limit":30}`, tutorToken)
+	var homework struct {
+		Data models.Session `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/sessions", quiz.Data.ID), `{"mode":"SELF_PACED"}`, tutorToken), &homework))
+
+	// A double click sends the same start twice, or more
+	const starts = 20
+	type result struct {
+		status int
+		body   string
+	}
+	results := make(chan result, starts)
+	attemptsPath := fmt.Sprintf("%s/api/v1/sessions/%s/attempts", server.URL, homework.Data.ID)
+	for i := 0; i < starts; i++ {
+		go func() {
+			req, _ := http.NewRequest("POST", attemptsPath, nil)
+			req.Header.Set("Authorization", "Bearer "+studentToken)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				results <- result{body: err.Error()}
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			results <- result{resp.StatusCode, string(body)}
+		}()
+	}
+
+	// Each gets the one attempt or a conflict, never a server error
+	for i := 0; i < starts; i++ {
+		r := <-results
+		if r.status == http.StatusConflict {
+			assert.Contains(t, r.body, "started at the same time")
+			continue
+		}
+		require.Equal(t, http.StatusOK, r.status, r.body)
+		assert.Contains(t, r.body, `"number":1,`)
+	}
+
+	var current struct {
+		Data models.AttemptState `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/attempts/current", homework.Data.ID), "", studentToken), &current))
+	assert.Equal(t, 1, current.Data.Attempt.Number)
+}
@@ -61,11 +61,19 @@ func TestWSAuthAndIdempotency(t *testing.T) {
 	}
 	err = conn.WriteJSON(joinMsg)
 	require.NoError(t, err)
-	time.Sleep(100 * time.Millisecond)
 
-	// 4. Test Idempotency
+	// Joining returns a snapshot of the quiz first
+	conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+	var stateMsg realtime.WSMessage
+	require.NoError(t, conn.ReadJSON(&stateMsg))
+	assert.Equal(t, "quiz_state", stateMsg.Type)
+
+	// Answers are only taken once the quiz runs
 	submitBody := fmt.Sprintf(`{"question_id":"%s","answer":"A"}`, questionID)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), submitBody, token)), "quiz is not running")
+	startQuiz(t, server, token, quizID)
 
+	// 4. Test Idempotency
 	// First Submission
 	submitResp1 := requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), submitBody, token)
 	assert.Contains(t, string(submitResp1), "points") // basic check for success
@@ -79,10 +87,5 @@ func TestWSAuthAndIdempotency(t *testing.T) {
 	assert.Contains(t, string(submitResp2), "already answered")
 
 	// Read WS message to verify One broadcast (optional, or check broadcast content)
-	conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-	_, msg, err := conn.ReadMessage()
-	require.NoError(t, err)
-	var wsMsg realtime.WSMessage
-	json.Unmarshal(msg, &wsMsg)
-	assert.Equal(t, "leaderboard_update", wsMsg.Type)
+	readWSUntil(t, conn, realtime.EventLeaderboard)
 }
@@ -0,0 +1,151 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuestionBank(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"teacher","password":"password","email":"teacher@example.com"}`)
+	token := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"teacher@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"other","password":"password","email":"other@example.com"}`)
+	otherToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"other@example.com","password":"password"}`))
+
+	var bankIDs []string
+	for _, body := range []string{
+		`{"text":"Capital of France?","options":["Paris","Rome"],"correct_answer":"Paris","difficulty":"easy","tags":["Geography"," europe ","geography"]}`,
+		`{"text":"Capital of Italy?","options":["Paris","Rome"],"correct_answer":"Rome","difficulty":"easy","tags":["geography","europe"]}`,
+		`{"text":"Capital of Bhutan?","options":["Thimphu","Kathmandu"],"correct_answer":"Thimphu","difficulty":"hard","tags":["geography"]}`,
+		`{"text":"Year of the Moon landing?","options":["1969","1972"],"correct_answer":"1969","tags":["history"]}`,
+	} {
+		var created struct {
+			Data models.BankQuestion `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/bank/questions", body, token), &created))
+		require.NotEmpty(t, created.Data.ID)
+		bankIDs = append(bankIDs, created.Data.ID.String())
+	}
+
+	// Tags are normalised and the difficulty defaults to medium
+	var first struct {
+		Data struct {
+			Tags       []string `json:"tags"`
+			Difficulty string   `json:"difficulty"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/bank/questions/"+bankIDs[0], "", token), &first))
+	assert.ElementsMatch(t, []string{"geography", "europe"}, first.Data.Tags)
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/bank/questions/"+bankIDs[3], "", token), &first))
+	assert.Equal(t, "medium", first.Data.Difficulty)
+
+	// Questions are validated like quiz questions
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/bank/questions", `{"text":"Bad","options":["A","B"],"correct_answer":"C"}`, token)), "correct answer")
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/bank/questions", `{"text":"Bad","options":["A","B"],"correct_answer":"A","difficulty":"extreme"}`, token)), "difficulty")
+
+	search := func(query, token string) (total int, texts []string) {
+		var resp struct {
+			Data struct {
+				Questions []models.BankQuestion `json:"questions"`
+				Total     int                   `json:"total"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/bank/questions"+query, "", token), &resp))
+		for _, question := range resp.Data.Questions {
+			texts = append(texts, question.Text)
+		}
+		return resp.Data.Total, texts
+	}
+
+	total, _ := search("?tags=geography", token)
+	assert.Equal(t, 3, total)
+	total, _ = search("?tags=geography,Europe", token)
+	assert.Equal(t, 2, total)
+	_, texts := search("?tags=geography&difficulty=hard", token)
+	assert.Equal(t, []string{"Capital of Bhutan?"}, texts)
+	_, texts = search("?q=moon", token)
+	assert.Equal(t, []string{"Year of the Moon landing?"}, texts)
+	total, texts = search("?tags=geography&limit=1&offset=1", token)
+	assert.Equal(t, 3, total)
+	assert.Len(t, texts, 1)
+
+	// Banks are private
+	total, _ = search("", otherToken)
+	assert.Equal(t, 0, total)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/bank/questions/"+bankIDs[0], "", otherToken)), "not found")
+
+	// A quiz mixing an authored question, a chosen bank question and a draw
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Mixed"}`, token), &quiz))
+	quizID := quiz.Data.ID.String()
+
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Authored","options":["A","B"],"correct_answer":"A","order":2}`, token)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", quizID), fmt.Sprintf(`{"order":1,"question_id":"%s"}`, bankIDs[3]), token)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", quizID), `{"order":3,"draw":{"count":2,"tags":["europe"]}}`, token)
+
+	// Draws larger than the matching bank, and other users' questions, are refused
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", quizID), `{"draw":{"count":5,"tags":["geography"]}}`, token)), "not enough")
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", quizID), `{"question_id":"`+bankIDs[0]+`","draw":{"count":1}}`, token)), "not both")
+
+	var otherQuiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Other"}`, otherToken), &otherQuiz))
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", otherQuiz.Data.ID), fmt.Sprintf(`{"question_id":"%s"}`, bankIDs[0]), otherToken)), "not found")
+
+	// Starting the quiz copies the bank questions in
+	host := dialWS(t, server, token)
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	started := readWSUntil(t, host, realtime.EventQuizStarted)
+	assert.Equal(t, float64(4), started.Payload.(map[string]interface{})["total_questions"])
+
+	var resolved struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/quizzes/"+quizID, "", token), &resolved))
+	questions := resolved.Data.Questions
+	require.Len(t, questions, 4)
+	sort.Slice(questions, func(i, j int) bool { return questions[i].Order < questions[j].Order })
+	for i, question := range questions {
+		assert.Equal(t, i+1, question.Order)
+	}
+	assert.Equal(t, "Year of the Moon landing?", questions[0].Text)
+	assert.Equal(t, bankIDs[3], questions[0].BankQuestionID.String())
+	assert.Equal(t, "Authored", questions[1].Text)
+	assert.Nil(t, questions[1].BankQuestionID)
+	assert.ElementsMatch(t, []string{"Capital of France?", "Capital of Italy?"}, []string{questions[2].Text, questions[3].Text})
+
+	var items struct {
+		Data []models.QuizBankItem `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", quizID), "", token), &items))
+	require.Len(t, items.Data, 2)
+	for _, item := range items.Data {
+		assert.NotNil(t, item.ResolvedAt)
+	}
+
+	// Later bank edits leave the started quiz alone
+	requestWithAuth(t, server, "PUT", "/api/v1/bank/questions/"+bankIDs[3], `{"text":"Edited","options":["1969","1972"],"correct_answer":"1969"}`, token)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/quizzes/"+quizID, "", token)), "Year of the Moon landing?")
+
+	// and so do deletes, while the bank forgets the question
+	requestWithAuth(t, server, "DELETE", "/api/v1/bank/questions/"+bankIDs[3], "", token)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/bank/questions/"+bankIDs[3], "", token)), "not found")
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/quizzes/"+quizID, "", token), &resolved))
+	assert.Len(t, resolved.Data.Questions, 4)
+
+	// Bank items can only be added before the quiz starts
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", quizID), fmt.Sprintf(`{"question_id":"%s"}`, bankIDs[0]), token)), "already")
+}
@@ -0,0 +1,120 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuizBundleRoundTrip(t *testing.T) {
+	db, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"staging","password":"password","email":"staging@example.com"}`)
+	stagingToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"staging@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"production","password":"password","email":"production@example.com"}`)
+	productionToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"production@example.com","password":"password"}`))
+
+	quizResp := requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Onboarding","description":"Week one"}`, stagingToken)
+	var original struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(quizResp, &original))
+
+	for _, body := range []string{
+		`{"text":"Q2","options":["A","B","C"],"correct_answer":"C","explanation":"See https://example.com/map.png","time_limit":15,"points":200,"order":5}`,
+		`{"text":"Q1","options":["Yes","No"],"correct_answer":"Yes","order":2}`,
+	} {
+		requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", original.Data.ID), body, stagingToken)
+	}
+
+	// Settings, and bank questions still to be chosen and drawn, travel too
+	requestWithAuth(t, server, "PUT", fmt.Sprintf("/api/v1/quizzes/%s/cloning", original.Data.ID), `{"allow_clone":true}`, stagingToken)
+	var bankIDs []string
+	for _, body := range []string{
+		`{"text":"Whose flag is https://cdn.example.com/flag.png?","options":["Japan","Peru"],"correct_answer":"Japan","tags":["flags"]}`,
+		`{"text":"Capital of France?","options":["Paris","Rome"],"correct_answer":"Paris","difficulty":"easy","tags":["europe"]}`,
+		`{"text":"Capital of Italy?","options":["Paris","Rome"],"correct_answer":"Rome","difficulty":"easy","tags":["europe","capitals"]}`,
+		`{"text":"Capital of Peru?","options":["Lima","Quito"],"correct_answer":"Lima","tags":["americas"]}`,
+	} {
+		var created struct {
+			Data models.BankQuestion `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/bank/questions", body, stagingToken), &created))
+		bankIDs = append(bankIDs, created.Data.ID.String())
+	}
+	itemsPath := fmt.Sprintf("/api/v1/quizzes/%s/bank-items", original.Data.ID)
+	requestWithAuth(t, server, "POST", itemsPath, fmt.Sprintf(`{"order":1,"question_id":"%s"}`, bankIDs[0]), stagingToken)
+	requestWithAuth(t, server, "POST", itemsPath, `{"order":3,"draw":{"count":2,"tags":["europe"],"difficulty":"easy"}}`, stagingToken)
+
+	exported := requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/bundle", original.Data.ID), "", stagingToken)
+	var bundle models.Bundle
+	require.NoError(t, json.Unmarshal(exported, &bundle))
+	assert.Equal(t, models.BundleFormat, bundle.Format)
+	assert.Equal(t, models.BundleVersion, bundle.Version)
+	require.Len(t, bundle.Questions, 2)
+	assert.Equal(t, "Q1", bundle.Questions[0].Text)
+	assert.True(t, bundle.Quiz.Settings.AllowClone)
+	require.Len(t, bundle.BankItems, 2)
+	require.Len(t, bundle.Bank, 3)
+	assert.Equal(t, "Capital of France?", bundle.Bank[1].Text)
+	assert.Equal(t, []string{"https://cdn.example.com/flag.png", "https://example.com/map.png"}, bundle.Media)
+
+	// Only the owner can export
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/bundle", original.Data.ID), "", productionToken)), "not found")
+
+	// Importing the file as is gives a new draft quiz with its own IDs and code
+	var imported struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes/import", string(exported), productionToken), &imported))
+	assert.NotEqual(t, original.Data.ID, imported.Data.ID)
+	assert.NotEqual(t, original.Data.Code, imported.Data.Code)
+	assert.NotEqual(t, original.Data.OwnerID, imported.Data.OwnerID)
+	assert.Equal(t, models.QuizStatusDraft, imported.Data.Status)
+	assert.True(t, imported.Data.AllowClone)
+
+	// The bank questions now belong to the importer
+	var bank struct {
+		Data struct {
+			Total int `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/bank/questions", "", productionToken), &bank))
+	assert.Equal(t, 3, bank.Data.Total)
+
+	// and exporting it again gives back an equivalent bundle
+	var roundTrip models.Bundle
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/bundle", imported.Data.ID), "", productionToken), &roundTrip))
+	bundle.ExportedAt, roundTrip.ExportedAt = time.Time{}, time.Time{}
+	assert.Equal(t, bundle, roundTrip)
+
+	// Bundles from a newer version, or with an invalid question, create nothing
+	var before int64
+	db.Model(&models.Quiz{}).Count(&before)
+
+	newer := bundle
+	newer.Version = models.BundleVersion + 1
+	body, _ := json.Marshal(newer)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/quizzes/import", string(body), productionToken)), "newer version")
+
+	broken := bundle
+	broken.Questions = append([]models.BundleQuestion{}, bundle.Questions...)
+	broken.Questions[1].CorrectAnswer = "D"
+	body, _ = json.Marshal(broken)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/quizzes/import", string(body), productionToken)), "question 2")
+
+	question := 7
+	unknown := bundle
+	unknown.BankItems = []models.BundleBankItem{{Order: 1, Question: &question}}
+	body, _ = json.Marshal(unknown)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/quizzes/import", string(body), productionToken)), "bank item 1")
+
+	var after int64
+	db.Model(&models.Quiz{}).Count(&after)
+	assert.Equal(t, before, after)
+}
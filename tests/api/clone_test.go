@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneQuiz(t *testing.T) {
+	db, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"trainer","password":"password","email":"trainer@example.com"}`)
+	trainerToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"trainer@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"colleague","password":"password","email":"colleague@example.com"}`)
+	colleagueToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"colleague@example.com","password":"password"}`))
+
+	var original struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Weekly","description":"Safety"}`, trainerToken), &original))
+	quizID := original.Data.ID.String()
+
+	var question struct {
+		Data models.Question `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q1","options":["A","B"],"correct_answer":"A","order":1}`, trainerToken), &question))
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q2","options":["C","D"],"correct_answer":"D","points":50,"order":2}`, trainerToken)
+
+	// Run the original so that it has answers and a leaderboard
+	host := dialWS(t, server, trainerToken)
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuestion)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), fmt.Sprintf(`{"question_id":"%s","answer":"A"}`, question.Data.ID), colleagueToken)
+
+	var answersBefore int64
+	db.Model(&models.Answer{}).Where("quiz_id = ?", quizID).Count(&answersBefore)
+	require.Equal(t, int64(1), answersBefore)
+
+	// The owner can always clone
+	var clone struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/clone", quizID), "", trainerToken), &clone))
+	assert.NotEqual(t, original.Data.ID, clone.Data.ID)
+	assert.NotEqual(t, original.Data.Code, clone.Data.Code)
+	assert.Equal(t, "Weekly", clone.Data.Title)
+	assert.Equal(t, "Safety", clone.Data.Description)
+	assert.Equal(t, models.QuizStatusDraft, clone.Data.Status)
+	assert.Equal(t, original.Data.ID, *clone.Data.ClonedFrom)
+	require.Len(t, clone.Data.Questions, 2)
+	for _, copied := range clone.Data.Questions {
+		assert.NotEqual(t, question.Data.ID, copied.ID)
+		assert.Equal(t, clone.Data.ID, copied.QuizID)
+	}
+
+	// The original keeps its answers, leaderboard and status, and the clone has none
+	var answersAfter int64
+	db.Model(&models.Answer{}).Where("quiz_id = ?", quizID).Count(&answersAfter)
+	assert.Equal(t, answersBefore, answersAfter)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/leaderboard", quizID), "", trainerToken)), `"score":100`)
+	assert.NotContains(t, string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/leaderboard", clone.Data.ID), "", trainerToken)), `"score"`)
+	var status models.QuizStatus
+	db.Model(&models.Quiz{}).Select("status").Where("id = ?", quizID).Scan(&status)
+	assert.Equal(t, models.QuizStatusActive, status)
+
+	// Other users need the owner's permission
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/clone", quizID), "", colleagueToken)), "does not allow")
+	assert.Contains(t, string(requestWithAuth(t, server, "PUT", fmt.Sprintf("/api/v1/quizzes/%s/cloning", quizID), `{"allow_clone":true}`, colleagueToken)), "not found")
+	requestWithAuth(t, server, "PUT", fmt.Sprintf("/api/v1/quizzes/%s/cloning", quizID), `{"allow_clone":true}`, trainerToken)
+
+	var shared struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/clone", quizID), `{"title":"Weekly (team B)"}`, colleagueToken), &shared))
+	assert.Equal(t, "Weekly (team B)", shared.Data.Title)
+	assert.NotEqual(t, original.Data.OwnerID, shared.Data.OwnerID)
+	assert.False(t, shared.Data.AllowClone)
+	assert.Len(t, shared.Data.Questions, 2)
+
+	// Pending bank draws only make sense in the owner's bank
+	requestWithAuth(t, server, "POST", "/api/v1/bank/questions", `{"text":"Bank","options":["A","B"],"correct_answer":"A","tags":["safety"]}`, trainerToken)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", clone.Data.ID), `{"order":3,"draw":{"count":1,"tags":["safety"]}}`, trainerToken)
+	requestWithAuth(t, server, "PUT", fmt.Sprintf("/api/v1/quizzes/%s/cloning", clone.Data.ID), `{"allow_clone":true}`, trainerToken)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/clone", clone.Data.ID), "", colleagueToken)), "question bank")
+
+	var again struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/clone", clone.Data.ID), "", trainerToken), &again))
+	var items struct {
+		Data []models.QuizBankItem `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/bank-items", again.Data.ID), "", trainerToken), &items))
+	require.Len(t, items.Data, 1)
+	assert.Equal(t, 1, items.Data[0].DrawCount)
+
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/quizzes/00000000-0000-0000-0000-000000000000/clone", "", trainerToken)), "not found")
+}
@@ -0,0 +1,75 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportResults(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+
+	quizResp := requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Export Quiz"}`, hostToken)
+	var quizObj QuizResponse
+	json.Unmarshal(quizResp, &quizObj)
+	quizID := quizObj.Data.ID
+
+	questionResp := requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q1","options":["A","B"],"correct_answer":"A"}`, hostToken)
+	var questionObj QuestionResponse
+	json.Unmarshal(questionResp, &questionObj)
+	questionID := questionObj.Data.ID
+
+	startQuiz(t, server, hostToken, quizID)
+
+	// The first player's name would run as a formula in a spreadsheet
+	tokens := map[string]string{}
+	for name, answer := range map[string]string{"=alice": "A", "bob": "B"} {
+		email := fmt.Sprintf("%s@example.com", name[len(name)-3:])
+		request(t, server, "POST", "/api/v1/auth/register", fmt.Sprintf(`{"username":"%s","password":"password","email":"%s"}`, name, email))
+		tokens[name] = getToken(t, request(t, server, "POST", "/api/v1/auth/login", fmt.Sprintf(`{"email":"%s","password":"password"}`, email)))
+		body := fmt.Sprintf(`{"question_id":"%s","answer":"%s"}`, questionID, answer)
+		requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), body, tokens[name])
+	}
+
+	exportPath := fmt.Sprintf("/api/v1/quizzes/%s/export", quizID)
+
+	records, err := csv.NewReader(bytes.NewReader(requestWithAuth(t, server, "GET", exportPath+"?format=csv", "", hostToken))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"rank", "username", "email", "score", "correct", "q1_answer", "q1_points"}, records[0])
+	assert.Equal(t, "1", records[1][0])
+	assert.Equal(t, "'=alice", records[1][1])
+	assert.Equal(t, "A", records[1][5])
+	assert.Equal(t, []string{"2", "bob", "bob@example.com", "0", "0", "B", "0"}, records[2])
+
+	var exported struct {
+		Title        string `json:"title"`
+		Participants []struct {
+			Rank     int    `json:"rank"`
+			Username string `json:"username"`
+		} `json:"participants"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", exportPath+"?format=json", "", hostToken), &exported))
+	assert.Equal(t, "Export Quiz", exported.Title)
+	require.Len(t, exported.Participants, 2)
+	assert.Equal(t, "bob", exported.Participants[1].Username)
+
+	workbook, err := excelize.OpenReader(bytes.NewReader(requestWithAuth(t, server, "GET", exportPath+"?format=xlsx", "", hostToken)))
+	require.NoError(t, err)
+	rows, err := workbook.GetRows(workbook.GetSheetName(0))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "=alice", rows[1][1])
+
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", exportPath+"?format=pdf", "", hostToken)), "unsupported export format")
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", exportPath, "", tokens["bob"])), "not found")
+}
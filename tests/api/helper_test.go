@@ -20,10 +20,15 @@ import (
 
 // Common setup for tests
 func setupTest(t *testing.T) (*gorm.DB, *redis.Client, *httptest.Server) {
+	return setupTestWith(t, nil)
+}
+
+// setupTestWith lets a test change the config before the server starts
+func setupTestWith(t *testing.T, configure func(cfg *config.Config)) (*gorm.DB, *redis.Client, *httptest.Server) {
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.User{}, &models.Quiz{}, &models.Question{}, &models.Answer{})
+	err = db.AutoMigrate(&models.User{}, &models.Quiz{}, &models.Session{}, &models.Question{}, &models.Answer{}, &models.BankQuestion{}, &models.BankQuestionTag{}, &models.QuizBankItem{}, &models.Attempt{}, &models.PracticeAnswer{}, &models.Mastery{}, &models.RefreshToken{}, &models.UserToken{}, &models.UserIdentity{}, &models.Organization{}, &models.Membership{}, &models.Invitation{})
 	require.NoError(t, err)
 
 	rdb := redis.NewClient(&redis.Options{
@@ -36,16 +41,28 @@ func setupTest(t *testing.T) (*gorm.DB, *redis.Client, *httptest.Server) {
 
 	cfg := &config.Config{
 		JWT: config.JWTConfig{
-			Secret:      "test-secret",
-			ExpiryHours: 1,
+			Secret:        "test-secret",
+			AccessMinutes: 60,
+		},
+		Scheduler: config.SchedulerConfig{
+			PollIntervalMs: 50,
+			RevealSeconds:  1,
 		},
 	}
 
+	if configure != nil {
+		configure(cfg)
+	}
+
 	app := bootstrap.NewRouter(db, rdb, cfg)
 	server := httptest.NewServer(app.Engine())
+	ctx, stopBackground := context.WithCancel(context.Background())
+	go app.RunScheduler(ctx)
+	go app.RunKeyRotation(ctx)
 
 	// Ensure cleanup
 	t.Cleanup(func() {
+		stopBackground()
 		server.Close()
 		rdb.FlushAll(context.Background())
 	})
@@ -0,0 +1,258 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostControls(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	// Host with a quiz of one question, and a player
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"player","password":"password","email":"player@example.com"}`)
+	playerToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"player@example.com","password":"password"}`))
+
+	quizResp := requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Hosted Quiz"}`, hostToken)
+	var quizObj struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(quizResp, &quizObj)
+	quizID := quizObj.Data.ID
+
+	questionResp := requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q1","options":["A","B"],"correct_answer":"A","time_limit":30}`, hostToken)
+	var questionObj struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(questionResp, &questionObj)
+	questionID := questionObj.Data.ID
+
+	host := dialWS(t, server, hostToken)
+	player := dialWS(t, server, playerToken)
+
+	// Players cannot issue host commands
+	sendWS(t, player, "host_start", map[string]interface{}{"quiz_id": quizID})
+	errMsg := readWSUntil(t, player, realtime.EventError)
+	assert.Contains(t, fmt.Sprint(errMsg.Payload), "not found")
+
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, player, "join_quiz", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, player, realtime.EventQuizState)
+
+	// Starting opens the first question without leaking its answer
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, player, realtime.EventQuizStarted)
+	question := readWSUntil(t, player, realtime.EventQuestion)
+	payload := question.Payload.(map[string]interface{})
+	assert.Equal(t, questionID, payload["id"])
+	assert.NotContains(t, payload, "correct_answer")
+
+	// Hosts see answer counts as they arrive
+	submitBody := fmt.Sprintf(`{"question_id":"%s","answer":"B"}`, questionID)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), submitBody, playerToken)
+	counts := readWSUntil(t, host, realtime.EventAnswerCount)
+	assert.Equal(t, float64(1), counts.Payload.(map[string]interface{})["counts"].(map[string]interface{})["B"])
+
+	sendWS(t, host, "host_reveal", map[string]interface{}{"quiz_id": quizID})
+	revealed := readWSUntil(t, player, realtime.EventAnswerRevealed)
+	assert.Equal(t, "A", revealed.Payload.(map[string]interface{})["correct_answer"])
+
+	// Revealing also shows how the room answered
+	stats := readWSUntil(t, player, realtime.EventQuestionStats).Payload.(map[string]interface{})
+	assert.Equal(t, float64(1), stats["total_answers"])
+	assert.Equal(t, float64(0), stats["percent_correct"])
+	assert.NotNil(t, stats["median_response_ms"])
+
+	statsPath := fmt.Sprintf("/api/v1/quizzes/%s/questions/%s/stats", quizID, questionID)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", statsPath, "", hostToken)), `"option":"B","count":1,"percent":100`)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", statsPath, "", playerToken)), "not found")
+
+	// Skipping past the last question ends the quiz
+	sendWS(t, host, "host_skip", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, player, realtime.EventQuizEnded)
+}
+
+func TestConcurrentStartsStartOnce(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"player","password":"password","email":"player@example.com"}`)
+	playerToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"player@example.com","password":"password"}`))
+
+	quizResp := requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Raced Quiz"}`, hostToken)
+	var quizObj struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(quizResp, &quizObj)
+	quizID := quizObj.Data.ID
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q1","options":["A","B"],"correct_answer":"A","time_limit":30}`, hostToken)
+
+	// Two host sockets, say two tabs, both press start at once
+	hosts := []*websocket.Conn{dialWS(t, server, hostToken), dialWS(t, server, hostToken)}
+	for _, host := range hosts {
+		sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+		readWSUntil(t, host, realtime.EventQuizState)
+	}
+	player := dialWS(t, server, playerToken)
+	sendWS(t, player, "join_quiz", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, player, realtime.EventQuizState)
+
+	for _, host := range hosts {
+		sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	}
+
+	// Exactly one start goes through; the other is refused
+	refused := 0
+	for _, host := range hosts {
+		for _, msg := range readWSFor(host, time.Second) {
+			if msg.Type == realtime.EventError && strings.Contains(fmt.Sprint(msg.Payload), "already started") {
+				refused++
+			}
+		}
+	}
+	assert.Equal(t, 1, refused)
+
+	counts := map[string]int{}
+	for _, msg := range readWSFor(player, 500*time.Millisecond) {
+		counts[msg.Type]++
+	}
+	assert.Equal(t, 1, counts[realtime.EventQuizStarted])
+	assert.Equal(t, 1, counts[realtime.EventQuestion])
+}
+
+// readWSFor collects the messages that arrive within d
+func readWSFor(conn *websocket.Conn, d time.Duration) []*realtime.WSMessage {
+	conn.SetReadDeadline(time.Now().Add(d))
+	var msgs []*realtime.WSMessage
+	for {
+		var msg realtime.WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return msgs
+		}
+		msgs = append(msgs, &msg)
+	}
+}
+
+// startQuiz starts the quiz's default session as its host and waits for the
+// first question, returning the host's socket
+func startQuiz(t *testing.T, server *httptest.Server, hostToken, quizID string) *websocket.Conn {
+	host := dialWS(t, server, hostToken)
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuestion)
+	return host
+}
+
+func dialWS(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws?token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func sendWS(t *testing.T, conn *websocket.Conn, msgType string, payload interface{}) {
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"type": msgType, "payload": payload}))
+}
+
+// readWSUntil skips messages until one of the given type arrives
+func readWSUntil(t *testing.T, conn *websocket.Conn, msgType string) *realtime.WSMessage {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg realtime.WSMessage
+		require.NoError(t, conn.ReadJSON(&msg), "waiting for %s", msgType)
+		if msg.Type == msgType {
+			return &msg
+		}
+	}
+}
+
+func TestKickedPlayerCannotResume(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"player","password":"password","email":"player@example.com"}`)
+	playerToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"player@example.com","password":"password"}`))
+	playerID := memberID(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", playerToken)))
+
+	var quizObj struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Kick Quiz"}`, hostToken), &quizObj)
+	quizID := quizObj.Data.ID
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q1","options":["A","B"],"correct_answer":"A"}`, hostToken)
+
+	host := dialWS(t, server, hostToken)
+	player := dialWS(t, server, playerToken)
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, player, "join_quiz", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, player, realtime.EventQuizState)
+
+	sendWS(t, host, "host_kick", map[string]interface{}{"quiz_id": quizID, "user_id": playerID})
+	kicked := readWSUntil(t, player, realtime.EventPlayerKicked)
+
+	// Resuming from where the stream left off, on a new socket, is refused
+	rejoined := dialWS(t, server, playerToken)
+	sendWS(t, rejoined, "resume", map[string]interface{}{"quiz_id": quizID, "last_seq": kicked.Seq})
+	errMsg := readWSUntil(t, rejoined, realtime.EventError)
+	assert.Contains(t, fmt.Sprint(errMsg.Payload), "removed from this quiz")
+
+	// and it hears nothing more of the quiz
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuestion)
+	rejoined.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	_, _, err := rejoined.ReadMessage()
+	assert.Error(t, err)
+}
+
+func TestLockedLobbyRefusesResume(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"latecomer","password":"password","email":"latecomer@example.com"}`)
+	lateToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"latecomer@example.com","password":"password"}`))
+
+	var quizObj struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Locked Quiz"}`, hostToken), &quizObj)
+	quizID := quizObj.Data.ID
+
+	host := dialWS(t, server, hostToken)
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, host, "host_lock_lobby", map[string]interface{}{"quiz_id": quizID})
+	locked := readWSUntil(t, host, realtime.EventLobbyLocked)
+
+	// A player who never joined cannot slip in by resuming
+	late := dialWS(t, server, lateToken)
+	sendWS(t, late, "resume", map[string]interface{}{"quiz_id": quizID, "last_seq": locked.Seq})
+	errMsg := readWSUntil(t, late, realtime.EventError)
+	assert.Contains(t, fmt.Sprint(errMsg.Payload), "the lobby is locked")
+}
@@ -0,0 +1,139 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const giftBank = `// Geography
+::Capital:: What is the capital of France? {
+	=Paris
+	~Lyon
+	~Marseille#Close, but no
+}
+
+The sun rises in the west. {FALSE}
+
+Name a primary colour. {=red =blue =yellow}
+
+::Broken:: Pick one {~a ~b}
+
+Match the sounds. {
+	=cat -> meow
+	=dog -> woof
+}
+`
+
+const csvBank = `text,correct_answer,option_1,option_2,option_3,time_limit,points
+2 + 2?,4,3,4,5,20,50
+Largest ocean?,Pacific,Atlantic,Pacific,,,
+Odd one out?,x,a,b,c,ten,
+`
+
+const jsonBank = `{
+  "questions": [
+    {"text": "Go was announced in?", "options": ["2007", "2009"], "correct_answer": "2009"},
+    {"text": "Typo", "options": ["a", "b"], "correct": "a"}
+  ]
+}`
+
+const moodleBank = `<?xml version="1.0" encoding="UTF-8"?>
+<quiz>
+  <question type="category"><category><text>$course$/Default</text></category></question>
+  <question type="multichoice">
+    <name><text>HTTP</text></name>
+    <questiontext format="html"><text><![CDATA[<p>Which status means <b>Not Found</b>?</p>]]></text></questiontext>
+    <answer fraction="100"><text>404</text></answer>
+    <answer fraction="0"><text>500</text></answer>
+  </question>
+  <question type="truefalse">
+    <questiontext format="html"><text>TCP is connectionless.</text></questiontext>
+    <answer fraction="0"><text>true</text></answer>
+    <answer fraction="100"><text>false</text></answer>
+  </question>
+  <question type="essay">
+    <questiontext format="html"><text>Explain DNS.</text></questiontext>
+  </question>
+</quiz>`
+
+func TestImportQuestions(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+
+	quizResp := requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Imported Quiz"}`, hostToken)
+	var quizObj QuizResponse
+	json.Unmarshal(quizResp, &quizObj)
+	quizID := quizObj.Data.ID
+	importPath := fmt.Sprintf("/api/v1/quizzes/%s/questions/import", quizID)
+
+	type importResponse struct {
+		Data models.ImportReport `json:"data"`
+	}
+	importBank := func(format, body string) models.ImportReport {
+		var resp importResponse
+		require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", importPath+"?format="+format, body, hostToken), &resp))
+		return resp.Data
+	}
+
+	gift := importBank("gift", giftBank)
+	assert.Equal(t, 2, gift.Imported)
+	require.Len(t, gift.Rejected, 3)
+	assert.Equal(t, 10, gift.Rejected[0].Line)
+	assert.Contains(t, gift.Rejected[0].Reason, "short answer")
+	assert.Equal(t, 12, gift.Rejected[1].Line)
+	assert.Contains(t, gift.Rejected[1].Reason, "no correct answer")
+	assert.Equal(t, 14, gift.Rejected[2].Line)
+	assert.Contains(t, gift.Rejected[2].Reason, "matching questions are not supported")
+	assert.Equal(t, "Paris", gift.Questions[0].CorrectAnswer)
+	assert.Equal(t, []string{"True", "False"}, []string(gift.Questions[1].Options))
+
+	csvReport := importBank("csv", csvBank)
+	assert.Equal(t, 2, csvReport.Imported)
+	require.Len(t, csvReport.Rejected, 1)
+	assert.Equal(t, 4, csvReport.Rejected[0].Line)
+	assert.Equal(t, 50, csvReport.Questions[0].Points)
+
+	jsonReport := importBank("json", jsonBank)
+	assert.Equal(t, 1, jsonReport.Imported)
+	require.Len(t, jsonReport.Rejected, 1)
+	assert.Equal(t, 4, jsonReport.Rejected[0].Line)
+	assert.Contains(t, jsonReport.Rejected[0].Reason, "unknown field")
+
+	moodle := importBank("xml", moodleBank)
+	assert.Equal(t, 2, moodle.Imported)
+	require.Len(t, moodle.Rejected, 1)
+	assert.Contains(t, moodle.Rejected[0].Reason, "essay")
+	assert.Equal(t, "Which status means Not Found?", moodle.Questions[0].Text)
+	assert.Equal(t, "False", moodle.Questions[1].CorrectAnswer)
+
+	// A dry run reports without saving, and imports keep appending in order
+	var resp importResponse
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", importPath+"?format=csv&dry_run=true", csvBank, hostToken), &resp))
+	assert.True(t, resp.Data.DryRun)
+	assert.Equal(t, 2, resp.Data.Imported)
+	assert.Empty(t, resp.Data.Questions)
+
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s", quizID), "", hostToken), &quiz))
+	require.Len(t, quiz.Data.Questions, 7)
+	for i, question := range quiz.Data.Questions {
+		assert.Equal(t, i+1, question.Order)
+	}
+
+	// Files that cannot be read at all, and other people's quizzes
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", importPath+"?format=xml", "<quiz><question>", hostToken)), "could not read import file")
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", importPath+"?format=docx", "", hostToken)), "unsupported import format")
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"other","password":"password","email":"other@example.com"}`)
+	otherToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"other@example.com","password":"password"}`))
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", importPath+"?format=gift", giftBank, otherToken)), "not found")
+}
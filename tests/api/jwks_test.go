@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSAndKeyRotation(t *testing.T) {
+	// Generated keys are kept in keys_dir, never only in memory
+	assert.ErrorIs(t, (&config.JWTConfig{Algorithm: "EdDSA"}).Validate(), config.ErrKeysDirRequired)
+	assert.ErrorIs(t, (&config.JWTConfig{Secret: "secret", RotateHours: 24}).Validate(), config.ErrKeysDirRequired)
+	assert.NoError(t, (&config.JWTConfig{Secret: "secret"}).Validate())
+
+	keysDir := t.TempDir()
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.JWT.Secret = ""
+		cfg.JWT.Algorithm = "EdDSA"
+		cfg.JWT.KeysDir = keysDir
+		cfg.JWT.KeyPollSeconds = 1
+	})
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"verifier","password":"password","email":"verifier@example.com"}`)
+	login := func() string {
+		return getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"verifier@example.com","password":"password"}`))
+	}
+	jwks := func() models.JWKS {
+		resp, err := http.Get(server.URL + "/.well-known/jwks.json")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var set models.JWKS
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&set))
+		return set
+	}
+
+	// A key is generated and saved on first start
+	files, err := filepath.Glob(filepath.Join(keysDir, "*.pem"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	first := login()
+	set := jwks()
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "OKP", set.Keys[0].Kty)
+	assert.Equal(t, "EdDSA", set.Keys[0].Alg)
+
+	// Another service verifies the token with the published key alone
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(first, claims, func(token *jwt.Token) (interface{}, error) {
+		assert.Equal(t, set.Keys[0].Kid, token.Header["kid"])
+		x, err := base64.RawURLEncoding.DecodeString(set.Keys[0].X)
+		require.NoError(t, err)
+		return ed25519.PublicKey(x), nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	require.NoError(t, err)
+	assert.True(t, parsed.Valid)
+	assert.Equal(t, "verifier", claims["username"])
+
+	// A key added to the directory, sorting last, takes over signing
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(keysDir, "zz-manual.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	assert.Eventually(t, func() bool { return len(jwks().Keys) == 2 }, 3*time.Second, 100*time.Millisecond)
+
+	second := login()
+	header, _, err := jwt.NewParser().ParseUnverified(second, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "zz-manual", header.Header["kid"])
+	assert.Equal(t, "RS256", header.Method.Alg())
+
+	// Tokens from either key are accepted
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", first)), "verifier")
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", second)), "verifier")
+
+	// but not one signed with a key the server does not know
+	_, stranger, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	forged.Header["kid"] = set.Keys[0].Kid
+	forgedToken, err := forged.SignedString(stranger)
+	require.NoError(t, err)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", forgedToken)), "Invalid or expired token")
+
+	// nor an HS256 token once there is no secret
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(""))
+	require.NoError(t, err)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", hmacToken)), "Invalid or expired token")
+}
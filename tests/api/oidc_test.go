@@ -0,0 +1,232 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockUser is who logs in at the mock provider
+type mockUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+	user      mockUser
+}
+
+// mockProvider is a minimal OpenID Connect provider that logs in whichever
+// user is set, checking the client's credentials and PKCE verifier
+type mockProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	user   mockUser
+	grants map[string]mockGrant
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p := &mockProvider{key: key, grants: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != "quiz-app" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		code := uuid.NewString()
+		p.mu.Lock()
+		p.grants[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), user: p.user}
+		p.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "quiz-app" || secret != "s3cret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		p.mu.Lock()
+		grant, ok := p.grants[r.FormValue("code")]
+		delete(p.grants, r.FormValue("code"))
+		p.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":                p.URL,
+			"sub":                grant.user.Subject,
+			"aud":                "quiz-app",
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"iat":                time.Now().Unix(),
+			"nonce":              grant.nonce,
+			"email":              grant.user.Email,
+			"email_verified":     grant.user.EmailVerified,
+			"preferred_username": grant.user.Username,
+		})
+		token.Header["kid"] = "mock-key"
+		signed, err := token.SignedString(p.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "unused", "token_type": "Bearer", "id_token": signed})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *mockProvider) logInAs(user mockUser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = user
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newMockProvider(t)
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.OIDC.Providers = []config.OIDCProviderConfig{{
+			Name:         "corp",
+			Issuer:       provider.URL,
+			ClientID:     "quiz-app",
+			ClientSecret: "s3cret",
+			RedirectURL:  "https://quiz.example.com/login/corp",
+		}}
+	})
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	follow := func(link string) *url.URL {
+		resp, err := noRedirect.Get(link)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		return location
+	}
+	// start logs in at the provider and returns what it sends back
+	start := func() (code, state string) {
+		authURL := follow(server.URL + "/api/v1/auth/oidc/corp/login")
+		assert.True(t, strings.HasPrefix(authURL.String(), provider.URL+"/authorize?"))
+		assert.Equal(t, "openid email profile", authURL.Query().Get("scope"))
+		assert.NotEmpty(t, authURL.Query().Get("nonce"))
+
+		back := follow(authURL.String())
+		assert.Equal(t, "quiz.example.com", back.Host)
+		return back.Query().Get("code"), back.Query().Get("state")
+	}
+	callback := func(code, state string) []byte {
+		return request(t, server, "GET", "/api/v1/auth/oidc/corp/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), "")
+	}
+	type loginResp struct {
+		Data struct {
+			Token string `json:"token"`
+			User  struct {
+				ID            uuid.UUID `json:"id"`
+				Username      string    `json:"username"`
+				EmailVerified bool      `json:"email_verified"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+
+	assert.Contains(t, string(request(t, server, "GET", "/api/v1/auth/oidc/providers", "")), `"data":["corp"]`)
+	assert.Contains(t, string(request(t, server, "GET", "/api/v1/auth/oidc/other/login", "")), "unknown login provider")
+
+	// Someone registered sam's address with a password but never verified it
+	var registered struct {
+		Data struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(request(t, server, "POST", "/api/v1/auth/register", `{"username":"sam","password":"password","email":"sam@corp.example"}`), &registered))
+	squatter := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"sam@corp.example","password":"password"}`))
+
+	// Sam logs in with SSO and takes the account over
+	provider.logInAs(mockUser{Subject: "corp-1", Email: "sam@corp.example", EmailVerified: true, Username: "sam"})
+	code, state := start()
+	var sam loginResp
+	require.NoError(t, json.Unmarshal(callback(code, state), &sam))
+	assert.Equal(t, registered.Data.ID, sam.Data.User.ID)
+	assert.True(t, sam.Data.User.EmailVerified)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", sam.Data.Token)), "sam@corp.example")
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", squatter)), "revoked")
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/login", `{"email":"sam@corp.example","password":"password"}`)), "Invalid email or password")
+
+	// Each login comes back once
+	assert.Contains(t, string(callback(code, state)), "invalid or has expired")
+
+	// A code only works with the verifier of the login that asked for it
+	codeA, _ := start()
+	_, stateB := start()
+	assert.Contains(t, string(callback(codeA, stateB)), "did not confirm the login")
+
+	// A new colleague gets an account, with a free username
+	provider.logInAs(mockUser{Subject: "corp-2", Email: "new.hire@corp.example", EmailVerified: true, Username: "sam"})
+	var hire loginResp
+	require.NoError(t, json.Unmarshal(callback(start()), &hire))
+	assert.NotEqual(t, sam.Data.User.ID, hire.Data.User.ID)
+	assert.True(t, strings.HasPrefix(hire.Data.User.Username, "sam_"))
+	assert.True(t, hire.Data.User.EmailVerified)
+
+	// and keeps it when their address changes at the provider
+	provider.logInAs(mockUser{Subject: "corp-2", Email: "renamed@corp.example", EmailVerified: true})
+	var renamed loginResp
+	require.NoError(t, json.Unmarshal(callback(start()), &renamed))
+	assert.Equal(t, hire.Data.User.ID, renamed.Data.User.ID)
+
+	// Unverified addresses are not trusted to name an account
+	provider.logInAs(mockUser{Subject: "corp-3", Email: "guest@corp.example"})
+	assert.Contains(t, string(callback(start())), "has not verified your email")
+
+	assert.Contains(t, string(request(t, server, "GET", "/api/v1/auth/oidc/corp/callback?error=access_denied", "")), "Login was not completed")
+}
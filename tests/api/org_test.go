@@ -0,0 +1,131 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganisations(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.txt")
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.Mail.LogFile = outbox
+		cfg.Account.BaseURL = "https://quiz.example.com"
+	})
+
+	tokens := map[string]string{}
+	for _, name := range []string{"admin", "host", "editor", "viewer", "outsider"} {
+		request(t, server, "POST", "/api/v1/auth/register", fmt.Sprintf(`{"username":"%s","password":"password","email":"%s@example.com"}`, name, name))
+		tokens[name] = getToken(t, request(t, server, "POST", "/api/v1/auth/login", fmt.Sprintf(`{"email":"%s@example.com","password":"password"}`, name)))
+	}
+	as := func(name, method, path, body string) string {
+		return string(requestWithAuth(t, server, method, "/api/v1"+path, body, tokens[name]))
+	}
+	var created struct {
+		Data struct {
+			ID   string `json:"id"`
+			Link string `json:"link"`
+		} `json:"data"`
+	}
+	var orgPath string
+	invite := func(body string) string {
+		require.NoError(t, json.Unmarshal([]byte(as("admin", "POST", orgPath+"/invitations", body)), &created))
+		link, err := url.Parse(created.Data.Link)
+		require.NoError(t, err)
+		assert.Equal(t, "/invitations", link.Path)
+		return fmt.Sprintf(`{"token":"%s"}`, link.Query().Get("token"))
+	}
+
+	// Everyone starts with a personal organisation
+	assert.Contains(t, as("outsider", "GET", "/orgs", ""), `"personal_user_id"`)
+
+	require.NoError(t, json.Unmarshal([]byte(as("admin", "POST", "/orgs", `{"name":"Acme"}`)), &created))
+	orgID := created.Data.ID
+	orgPath = "/orgs/" + orgID
+
+	// A link anyone can follow, and one only the named address can
+	hostInvite := invite(`{"role":"host","max_uses":2}`)
+	editorInvite := invite(`{"role":"editor","email":"editor@example.com"}`)
+	mail, err := os.ReadFile(outbox)
+	require.NoError(t, err)
+	assert.Contains(t, string(mail), "To: editor@example.com\nSubject: You are invited to join Acme")
+	assert.Contains(t, as("admin", "POST", orgPath+"/invitations", `{"role":"owner"}`), "role must be viewer, host, editor or admin")
+	assert.Contains(t, as("host", "POST", orgPath+"/invitations", `{"role":"admin"}`), "organisation not found")
+
+	assert.Contains(t, as("outsider", "POST", "/invitations/accept", editorInvite), "this invitation is for another email address")
+	assert.Contains(t, as("editor", "POST", "/invitations/accept", editorInvite), `"role":"editor"`)
+	assert.Contains(t, as("editor", "POST", "/invitations/accept", editorInvite), "invalid or has expired")
+	assert.Contains(t, as("host", "POST", "/invitations/accept", hostInvite), `"role":"host"`)
+	assert.Contains(t, as("host", "POST", "/invitations/accept", hostInvite), "already a member")
+	assert.Contains(t, as("admin", "GET", orgPath+"/members", ""), `"username":"editor"`)
+	assert.Contains(t, as("host", "POST", orgPath+"/invitations", `{"role":"admin"}`), "only the organisation's admins")
+
+	// Editors write the org's quizzes, hosts run them
+	require.NoError(t, json.Unmarshal([]byte(as("editor", "POST", "/quizzes", fmt.Sprintf(`{"title":"Team Quiz","org_id":"%s"}`, orgID))), &created))
+	quizID := created.Data.ID
+	assert.Contains(t, as("host", "POST", "/quizzes", fmt.Sprintf(`{"title":"Mine","org_id":"%s"}`, orgID)), "does not allow this")
+	assert.Contains(t, as("outsider", "POST", "/quizzes", fmt.Sprintf(`{"title":"Mine","org_id":"%s"}`, orgID)), "organisation not found")
+	questionsPath := fmt.Sprintf("/quizzes/%s/questions", quizID)
+	assert.Contains(t, as("host", "POST", questionsPath, `{"text":"Q1","options":["A","B"],"correct_answer":"A"}`), "does not allow this")
+	assert.Contains(t, as("editor", "POST", questionsPath, `{"text":"Q1","options":["A","B"],"correct_answer":"A"}`), "Question added")
+
+	host := dialWS(t, server, tokens["host"])
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuestion)
+	assert.Contains(t, as("admin", "GET", orgPath+"/quizzes", ""), "Team Quiz")
+
+	// Other tenants see none of it
+	assert.Contains(t, as("outsider", "GET", "/quizzes/"+quizID, ""), "Quiz not found")
+	assert.Contains(t, as("outsider", "GET", orgPath+"/quizzes", ""), "organisation not found")
+	assert.Contains(t, as("outsider", "GET", fmt.Sprintf("/quizzes/%s/leaderboard", quizID), ""), "session not found")
+	assert.Contains(t, as("outsider", "GET", fmt.Sprintf("/quizzes/%s/report", quizID), ""), "session not found")
+	// The quiz's default session has the quiz's ID
+	assert.Contains(t, as("outsider", "GET", "/sessions/"+quizID, ""), "session not found")
+	assert.Contains(t, as("admin", "GET", "/sessions/"+quizID, ""), "Session retrieved")
+	assert.Contains(t, as("outsider", "POST", fmt.Sprintf("/quizzes/%s/questions/import?format=json", quizID), `[{"text":"Q2","options":["A","B"],"correct_answer":"A"}]`), "quiz not found")
+	assert.NotContains(t, as("outsider", "GET", "/orgs", ""), "Acme")
+
+	// Viewers see reports, but only editors export the players' details
+	assert.Contains(t, as("viewer", "POST", "/invitations/accept", invite(`{"role":"viewer"}`)), `"role":"viewer"`)
+	assert.Contains(t, as("viewer", "GET", fmt.Sprintf("/quizzes/%s/report", quizID), ""), "Quiz report retrieved")
+	exportPath := fmt.Sprintf("/api/v1/quizzes/%s/export?format=csv", quizID)
+	resp, body := doRequest(t, server, "GET", exportPath, "", map[string]string{"Authorization": "Bearer " + tokens["viewer"]})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, body, "does not allow this")
+	resp, _ = doRequest(t, server, "GET", exportPath, "", map[string]string{"Authorization": "Bearer " + tokens["editor"]})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The last admin stays, members leave or are removed
+	adminID := memberID(t, as("admin", "GET", "/auth/me", ""))
+	assert.Contains(t, as("admin", "PUT", orgPath+"/members/"+adminID, `{"role":"viewer"}`), "at least one admin")
+	assert.Contains(t, as("admin", "DELETE", "/users/me", `{"current_password":"password"}`), "make another member of Acme an admin first")
+	hostID := memberID(t, as("host", "GET", "/auth/me", ""))
+	assert.Contains(t, as("admin", "DELETE", orgPath+"/members/"+hostID, ""), "Member removed")
+	assert.Contains(t, as("host", "GET", "/quizzes/"+quizID, ""), "Quiz not found")
+
+	// Revoked links stop working
+	viewerInvite := invite(`{"role":"viewer"}`)
+	assert.Contains(t, as("admin", "DELETE", orgPath+"/invitations/"+created.Data.ID, ""), "Invitation revoked")
+	assert.Contains(t, as("outsider", "POST", "/invitations/accept", viewerInvite), "invalid or has expired")
+}
+
+func memberID(t *testing.T, body string) string {
+	var me struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &me))
+	return me.Data.ID
+}
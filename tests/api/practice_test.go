@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPracticeSessions(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"coach","password":"password","email":"coach@example.com"}`)
+	coachToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"coach@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"learner","password":"password","email":"learner@example.com"}`)
+	learnerToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"learner@example.com","password":"password"}`))
+
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Capitals"}`, coachToken), &quiz))
+	quizID := quiz.Data.ID.String()
+	var question struct {
+		Data models.Question `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Capital of Australia?","options":["Sydney","Canberra"],"correct_answer":"Canberra","explanation":"Canberra was built as a compromise between Sydney and Melbourne.","order":1}`, coachToken), &question))
+	assert.Equal(t, "Canberra was built as a compromise between Sydney and Melbourne.", question.Data.Explanation)
+
+	sessionsPath := fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", sessionsPath, `{"mode":"PRACTICE","scheduled_at":"2999-01-01T00:00:00Z"}`, coachToken)), "cannot be scheduled")
+
+	// A practice session starts at once
+	var session struct {
+		Data models.Session `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", sessionsPath, `{"label":"Revision","mode":"PRACTICE"}`, coachToken), &session))
+	assert.Equal(t, models.SessionModePractice, session.Data.Mode)
+	assert.Equal(t, models.QuizStatusActive, session.Data.Status)
+
+	// Questions are served without their answers
+	practicePath := fmt.Sprintf("/api/v1/sessions/%s/practice", session.Data.ID)
+	var practice struct {
+		Data models.PracticeState `json:"data"`
+	}
+	body := requestWithAuth(t, server, "GET", practicePath, "", learnerToken)
+	require.NoError(t, json.Unmarshal(body, &practice))
+	require.Len(t, practice.Data.Questions, 1)
+	assert.Empty(t, practice.Data.Mastery)
+	assert.NotContains(t, string(body), "compromise")
+
+	type answerResp struct {
+		Data models.Answer `json:"data"`
+	}
+	submit := func(answer string) answerResp {
+		var resp answerResp
+		body := requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/sessions/%s/submit", session.Data.ID), fmt.Sprintf(`{"question_id":"%s","answer":"%s"}`, question.Data.ID, answer), learnerToken)
+		require.NoError(t, json.Unmarshal(body, &resp), string(body))
+		return resp
+	}
+
+	// A wrong answer comes back with the right one and why
+	wrong := submit("Sydney")
+	assert.False(t, wrong.Data.IsCorrect)
+	assert.Equal(t, 0, wrong.Data.Points)
+	require.NotNil(t, wrong.Data.Feedback)
+	assert.Equal(t, "Canberra", wrong.Data.Feedback.CorrectAnswer)
+	assert.Contains(t, wrong.Data.Feedback.Explanation, "compromise")
+	require.NotNil(t, wrong.Data.Feedback.Mastery)
+	assert.Equal(t, 1, wrong.Data.Feedback.Mastery.Attempts)
+	assert.Equal(t, 0.0, wrong.Data.Feedback.Mastery.Level)
+
+	// The same question can be practised again and again
+	submit("Canberra")
+	right := submit("Canberra")
+	assert.True(t, right.Data.IsCorrect)
+	assert.Equal(t, 3, right.Data.Feedback.Mastery.Attempts)
+	assert.Equal(t, 2, right.Data.Feedback.Mastery.Correct)
+	assert.Equal(t, 2, right.Data.Feedback.Mastery.Streak)
+	assert.InDelta(t, 0.51, right.Data.Feedback.Mastery.Level, 0.001)
+
+	var mastery struct {
+		Data []models.Mastery `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/mastery", quizID), "", learnerToken), &mastery))
+	require.Len(t, mastery.Data, 1)
+	assert.Equal(t, question.Data.ID, mastery.Data[0].QuestionID)
+	assert.Equal(t, 3, mastery.Data[0].Attempts)
+
+	// Practice never counts towards scores
+	var leaderboard struct {
+		Data []models.LeaderboardEntry `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/leaderboard", session.Data.ID), "", coachToken), &leaderboard))
+	assert.Empty(t, leaderboard.Data)
+	var report struct {
+		Data models.QuizReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/report", session.Data.ID), "", coachToken), &report))
+	assert.Empty(t, report.Data.Participants)
+
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/practice", quizID), "", learnerToken)), "not a practice session")
+}
@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileManagement(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.txt")
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.Mail.LogFile = outbox
+	})
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"alice","password":"password","email":"alice@example.com"}`)
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"bob","password":"password","email":"bob@example.com"}`)
+	token := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"alice@example.com","password":"password"}`))
+	me := func(method, path, body string) string {
+		return string(requestWithAuth(t, server, method, "/api/v1/users/me"+path, body, token))
+	}
+
+	// Plain profile fields need no password
+	updated := me("PATCH", "", `{"display_name":"Alice A.","avatar_url":"https://cdn.example.com/alice.png"}`)
+	assert.Contains(t, updated, `"display_name":"Alice A."`)
+	assert.Contains(t, updated, `"avatar_url":"https://cdn.example.com/alice.png"`)
+	assert.Contains(t, me("PATCH", "", `{"avatar_url":"javascript:alert(1)"}`), "avatar must be an http or https URL")
+	assert.Contains(t, me("PATCH", "", `{"username":"bob"}`), "Username already exists")
+	assert.Contains(t, me("PATCH", "", `{"username":"al"}`), "username must be 3 to 50 characters")
+	assert.Contains(t, me("PATCH", "", `{"username":"alice2"}`), `"username":"alice2"`)
+	assert.Contains(t, me("GET", "", ""), `"display_name":"Alice A."`)
+
+	// Moving to a new address needs the password and verifying again
+	assert.Contains(t, me("PUT", "/email", `{"email":"alice@new.example","current_password":"wrong"}`), "current password is incorrect")
+	assert.Contains(t, me("PUT", "/email", `{"email":"bob@example.com","current_password":"password"}`), "Email already exists")
+	moved := me("PUT", "/email", `{"email":"alice@new.example","current_password":"password"}`)
+	assert.Contains(t, moved, `"email":"alice@new.example"`)
+	assert.NotContains(t, moved, "email_verified_at")
+	mail, err := os.ReadFile(outbox)
+	require.NoError(t, err)
+	assert.Contains(t, string(mail), "To: alice@example.com\nSubject: Your email address was changed")
+	assert.Contains(t, string(mail), "To: alice@new.example\nSubject: Verify your email address")
+
+	// A new password ends every other login
+	other := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"alice@new.example","password":"password"}`))
+	changed := me("PUT", "/password", `{"current_password":"password","new_password":"new-password"}`)
+	assert.Contains(t, changed, "Password changed")
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/users/me", "", other)), "revoked")
+	assert.Contains(t, me("GET", "", ""), "revoked")
+	token = getToken(t, []byte(changed))
+	assert.Contains(t, me("GET", "", ""), `"username":"alice2"`)
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/login", `{"email":"alice@new.example","password":"password"}`)), "Invalid email or password")
+}
+
+func TestAccountDeletion(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"leaver","password":"password","email":"leaver@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+	leaverToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"leaver@example.com","password":"password"}`))
+
+	var created struct {
+		Data struct {
+			ID   string `json:"id"`
+			Code string `json:"code"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Farewell Quiz"}`, hostToken), &created))
+	quizID, quizCode := created.Data.ID, created.Data.Code
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q1","options":["A","B"],"correct_answer":"A","time_limit":30}`, hostToken), &created))
+	questionID := created.Data.ID
+
+	// The leaver plays a game
+	host := dialWS(t, server, hostToken)
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuestion)
+	submit := fmt.Sprintf(`{"question_id":"%s","answer":"A"}`, questionID)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), submit, leaverToken)
+
+	// and can download everything kept about them
+	export := string(requestWithAuth(t, server, "GET", "/api/v1/users/me/export", "", leaverToken))
+	assert.Contains(t, export, `"email":"leaver@example.com"`)
+	assert.Contains(t, export, `"question_id":"`+questionID+`"`)
+
+	// Deleting needs the password
+	assert.Contains(t, string(requestWithAuth(t, server, "DELETE", "/api/v1/users/me", `{"current_password":"wrong"}`, leaverToken)), "current password is incorrect")
+	assert.Contains(t, string(requestWithAuth(t, server, "DELETE", "/api/v1/users/me", `{"current_password":"password"}`, leaverToken)), "Account deleted")
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", "/api/v1/users/me", "", leaverToken)), "revoked")
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/login", `{"email":"leaver@example.com","password":"password"}`)), "Invalid email or password")
+
+	// The host's results keep the answer, without saying whose it was
+	results := string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/export?format=json", quizID), "", hostToken))
+	assert.Contains(t, results, `"username":"deleted_`)
+	assert.Contains(t, results, `"score":`)
+	assert.NotContains(t, results, "leaver")
+
+	// The address is free again
+	assert.Contains(t, string(request(t, server, "POST", "/api/v1/auth/register", `{"username":"leaver","password":"password","email":"leaver@example.com"}`)), "User registered successfully")
+
+	// Deleting the host leaves the quiz and its results in place
+	assert.Contains(t, string(requestWithAuth(t, server, "DELETE", "/api/v1/users/me", `{"current_password":"password"}`, hostToken)), "Account deleted")
+	newcomer := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"leaver@example.com","password":"password"}`))
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/quizzes/join", fmt.Sprintf(`{"code":"%s"}`, quizCode), newcomer)), "Joined quiz successfully")
+}
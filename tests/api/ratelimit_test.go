@@ -0,0 +1,220 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/nguyen1302/realtime-quiz/internal/config"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doRequest sends a request with optional headers and returns the response
+// with its body
+func doRequest(t *testing.T, server *httptest.Server, method, path, body string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(respBody)
+}
+
+func TestRateLimits(t *testing.T) {
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.Rules = map[string]config.RateLimitRule{
+			"register": {PerIP: 3, WindowSeconds: 60},
+			"refresh":  {PerIP: 2, WindowSeconds: 60},
+			"join":     {PerIP: 100, PerAccount: 3, WindowSeconds: 60},
+		}
+	})
+
+	register := func(name string, headers map[string]string) (*http.Response, string) {
+		return doRequest(t, server, "POST", "/api/v1/auth/register", `{"username":"`+name+`","password":"password","email":"`+name+`@example.com"}`, headers)
+	}
+	for _, name := range []string{"guesser", "newcomer", "bystander"} {
+		resp, _ := register(name, nil)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	// One IP cannot register endlessly, nor pass itself off as another
+	resp, body := register("spammer", nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Contains(t, body, "Too many requests")
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	resp, _ = register("spammer", map[string]string{"X-Forwarded-For": "203.0.113.9"})
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// Nor guess refresh tokens
+	for i := 0; i < 2; i++ {
+		resp, _ = doRequest(t, server, "POST", "/api/v1/auth/refresh", `{"refresh_token":"guess"}`, nil)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp, _ = doRequest(t, server, "POST", "/api/v1/auth/refresh", `{"refresh_token":"guess"}`, nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// Guessing join codes is limited per user
+	guesser := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"guesser@example.com","password":"password"}`))
+	bystander := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"bystander@example.com","password":"password"}`))
+	join := func(path, token, code string) int {
+		resp, _ := doRequest(t, server, "POST", path, `{"code":"`+code+`"}`, map[string]string{"Authorization": "Bearer " + token})
+		return resp.StatusCode
+	}
+	for _, code := range []string{"000001", "000002", "000003"} {
+		assert.Equal(t, http.StatusNotFound, join("/api/v1/sessions/join", guesser, code))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, join("/api/v1/sessions/join", guesser, "000004"))
+	assert.Equal(t, http.StatusTooManyRequests, join("/api/v1/quizzes/join", guesser, "000005"))
+	assert.Equal(t, http.StatusNotFound, join("/api/v1/sessions/join", bystander, "000004"))
+}
+
+func TestLoginLockout(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.txt")
+	_, rdb, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.Mail.LogFile = outbox
+		cfg.RateLimit.Lockout = config.LockoutConfig{MaxFailures: 3, LockMinutes: 1, MaxLockMinutes: 3}
+	})
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"alice","password":"password","email":"alice@example.com"}`)
+	login := func(email, password string) (*http.Response, string) {
+		return doRequest(t, server, "POST", "/api/v1/auth/login", `{"email":"`+email+`","password":"`+password+`"}`, nil)
+	}
+	unlock := func(email string) {
+		require.NoError(t, rdb.Del(context.Background(), "auth:lockout:"+email).Err())
+	}
+
+	// Failures in a row lock the account, whatever the case of the address
+	for _, email := range []string{"alice@example.com", "Alice@Example.com"} {
+		resp, body := login(email, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, body, "Invalid email or password")
+	}
+	resp, body := login("alice@example.com", "wrong")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Contains(t, body, "account is locked")
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	// Even the right password waits, and the owner hears about it
+	resp, _ = login("alice@example.com", "password")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	mail, err := os.ReadFile(outbox)
+	require.NoError(t, err)
+	assert.Contains(t, string(mail), "Subject: Your account was locked")
+
+	// Each lock in a row lasts longer, up to the maximum
+	for _, want := range []string{"120", "180", "180"} {
+		unlock("alice@example.com")
+		for i := 0; i < 3; i++ {
+			resp, _ = login("alice@example.com", "wrong")
+		}
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, want, resp.Header.Get("Retry-After"))
+	}
+
+	// Logging in once the lock ends starts over
+	unlock("alice@example.com")
+	resp, _ = login("alice@example.com", "password")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	for i := 0; i < 3; i++ {
+		resp, _ = login("alice@example.com", "wrong")
+	}
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	// Unknown addresses lock the same way
+	for i := 0; i < 2; i++ {
+		resp, _ = login("nobody@example.com", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp, _ = login("nobody@example.com", "wrong")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestMessageLimits(t *testing.T) {
+	_, _, server := setupTestWith(t, func(cfg *config.Config) {
+		cfg.Realtime.Limits = map[string]config.MessageLimit{
+			"join_quiz": {
+				PerConnection: config.TokenBucket{Rate: 0.001, Burst: 2},
+				PerUser:       config.TokenBucket{Rate: 0.001, Burst: 3},
+			},
+		}
+		cfg.Realtime.DisconnectAfter = 3
+		cfg.RateLimit.Throttles = map[string]config.TokenBucket{"submit": {Rate: 0.001, Burst: 2}}
+	})
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"flooder","password":"password","email":"flooder@example.com"}`)
+	flooder := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"flooder@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"patient","password":"password","email":"patient@example.com"}`)
+	patient := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"patient@example.com","password":"password"}`))
+
+	var quiz struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Limited Quiz"}`, flooder), &quiz))
+	join := map[string]interface{}{"quiz_id": quiz.Data.ID}
+
+	// Each connection gets its own allowance
+	first := dialWS(t, server, flooder)
+	for i := 0; i < 2; i++ {
+		sendWS(t, first, "join_quiz", join)
+		readWSUntil(t, first, realtime.EventQuizState)
+	}
+	sendWS(t, first, "join_quiz", join)
+	refused := readWSUntil(t, first, realtime.EventError).Payload.(map[string]interface{})
+	assert.Contains(t, refused["message"], "too many join_quiz messages")
+	assert.Greater(t, refused["retry_after_ms"], float64(0))
+
+	// but a user's connections share theirs
+	second := dialWS(t, server, flooder)
+	sendWS(t, second, "join_quiz", join)
+	readWSUntil(t, second, realtime.EventQuizState)
+	sendWS(t, second, "join_quiz", join)
+	readWSUntil(t, second, realtime.EventError)
+
+	other := dialWS(t, server, patient)
+	sendWS(t, other, "join_quiz", join)
+	readWSUntil(t, other, realtime.EventQuizState)
+
+	// Carrying on regardless gets the connection closed
+	for i := 0; i < 3; i++ {
+		sendWS(t, second, "join_quiz", join)
+	}
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var err error
+	for err == nil {
+		_, _, err = second.ReadMessage()
+	}
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "got %v", err)
+
+	// Answer submissions are throttled per user
+	submit := func(token string) *http.Response {
+		resp, _ := doRequest(t, server, "POST", "/api/v1/quizzes/"+quiz.Data.ID+"/submit", `{"question_id":"`+uuid.NewString()+`","answer":"A"}`, map[string]string{"Authorization": "Bearer " + token})
+		return resp
+	}
+	for i := 0; i < 2; i++ {
+		assert.NotEqual(t, http.StatusTooManyRequests, submit(flooder).StatusCode)
+	}
+	resp := submit(flooder)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	assert.NotEqual(t, http.StatusTooManyRequests, submit(patient).StatusCode)
+}
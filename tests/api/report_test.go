@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuizReport(t *testing.T) {
+	db, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"host","password":"password","email":"host@example.com"}`)
+	hostToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"host@example.com","password":"password"}`))
+
+	quizResp := requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Report Quiz"}`, hostToken)
+	var quizObj QuizResponse
+	json.Unmarshal(quizResp, &quizObj)
+	quizID := quizObj.Data.ID
+
+	var questionIDs []string
+	for i, body := range []string{
+		`{"text":"Easy","options":["A","B","C"],"correct_answer":"A","order":1}`,
+		`{"text":"Hard","options":["A","B","C"],"correct_answer":"C","order":2}`,
+	} {
+		resp := requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), body, hostToken)
+		var questionObj QuestionResponse
+		json.Unmarshal(resp, &questionObj)
+		require.NotEmpty(t, questionObj.Data.ID, "question %d", i)
+		questionIDs = append(questionIDs, questionObj.Data.ID)
+	}
+
+	// Three players: one gets both right, one only the easy question, one
+	// gets both wrong
+	answers := map[string][]string{
+		"strong": {"A", "C"},
+		"middle": {"A", "B"},
+		"weak":   {"B", "B"},
+	}
+	tokens := map[string]string{}
+	for name := range answers {
+		request(t, server, "POST", "/api/v1/auth/register", fmt.Sprintf(`{"username":"%s","password":"password","email":"%s@example.com"}`, name, name))
+		tokens[name] = getToken(t, request(t, server, "POST", "/api/v1/auth/login", fmt.Sprintf(`{"email":"%s@example.com","password":"password"}`, name)))
+	}
+	host := startQuiz(t, server, hostToken, quizID)
+	for i, questionID := range questionIDs {
+		if i > 0 {
+			sendWS(t, host, "host_skip", map[string]interface{}{"quiz_id": quizID})
+			readWSUntil(t, host, realtime.EventQuestion)
+		}
+		for name, picks := range answers {
+			body := fmt.Sprintf(`{"question_id":"%s","answer":"%s"}`, questionID, picks[i])
+			assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), body, tokens[name])), "points")
+		}
+	}
+
+	var report struct {
+		Data models.QuizReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/report", quizID), "", hostToken), &report))
+
+	assert.Equal(t, 3, report.Data.ParticipantCount)
+	require.Len(t, report.Data.Questions, 2)
+
+	easy, hard := report.Data.Questions[0], report.Data.Questions[1]
+	assert.Equal(t, 0.67, easy.PValue)
+	assert.Equal(t, 0.33, hard.PValue)
+	require.NotNil(t, easy.DiscriminationIndex)
+	assert.Equal(t, 1.0, *easy.DiscriminationIndex)
+
+	// The weak player fell for B on the easy question
+	require.Len(t, easy.Options, 3)
+	assert.Equal(t, "B", easy.Options[1].Option)
+	assert.Equal(t, int64(1), easy.Options[1].LowerCount)
+	assert.Equal(t, int64(0), easy.Options[1].UpperCount)
+
+	require.Len(t, report.Data.Participants, 3)
+	top := report.Data.Participants[0]
+	assert.Equal(t, "strong", top.Username)
+	assert.Equal(t, 1, top.Rank)
+	assert.Equal(t, float64(100), top.Accuracy)
+	require.Len(t, top.Timeline, 2)
+	assert.Equal(t, top.Score, top.Timeline[1].CumulativeScore)
+
+	// Only the owner can see the report
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/report", quizID), "", getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"weak@example.com","password":"password"}`)))), "not found")
+
+	// A finished quiz's report is cached and no longer reads the answers table.
+	// The quiz is played in its default session, which shares its ID.
+	require.NoError(t, db.Model(&models.Session{}).Where("id = ?", quizID).Update("status", models.QuizStatusFinished).Error)
+	requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/report", quizID), "", hostToken)
+	require.NoError(t, db.Where("quiz_id = ?", quizID).Delete(&models.Answer{}).Error)
+
+	var cached struct {
+		Data models.QuizReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/report", quizID), "", hostToken), &cached))
+	assert.Equal(t, 3, cached.Data.ParticipantCount)
+	assert.Equal(t, models.QuizStatusFinished, cached.Data.Status)
+}
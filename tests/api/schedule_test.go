@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledSessions(t *testing.T) {
+	_, rdb, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"planner","password":"password","email":"planner@example.com"}`)
+	plannerToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"planner@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"early_bird","password":"password","email":"early_bird@example.com"}`)
+	playerToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"early_bird@example.com","password":"password"}`))
+
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Morning Quiz"}`, plannerToken), &quiz))
+	quizID := quiz.Data.ID.String()
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q1","options":["A","B"],"correct_answer":"A","time_limit":1,"order":1}`, plannerToken)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"Q2","options":["A","B"],"correct_answer":"B","time_limit":1,"order":2}`, plannerToken)
+
+	type sessionResp struct {
+		Data models.Session `json:"data"`
+	}
+	at := func(d time.Duration) string {
+		return time.Now().Add(d).UTC().Format(time.RFC3339Nano)
+	}
+
+	// Scheduled an hour ahead, the lobby stays shut until ten minutes before
+	var session sessionResp
+	body := fmt.Sprintf(`{"label":"Morning","scheduled_at":"%s","lobby_minutes":10}`, at(time.Hour))
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID), body, plannerToken), &session))
+	require.NotNil(t, session.Data.ScheduledAt)
+	require.NotNil(t, session.Data.LobbyOpensAt)
+	assert.Equal(t, 10*time.Minute, session.Data.ScheduledAt.Sub(*session.Data.LobbyOpensAt))
+	join := fmt.Sprintf(`{"code":"%s"}`, session.Data.Code)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/sessions/join", join, playerToken)), "not open yet")
+
+	schedulePath := fmt.Sprintf("/api/v1/sessions/%s/schedule", session.Data.ID)
+	assert.Contains(t, string(requestWithAuth(t, server, "PUT", schedulePath, fmt.Sprintf(`{"scheduled_at":"%s"}`, at(-time.Minute)), plannerToken)), "in the future")
+	assert.Contains(t, string(requestWithAuth(t, server, "PUT", schedulePath, fmt.Sprintf(`{"scheduled_at":"%s"}`, at(time.Minute)), playerToken)), "not found")
+
+	// Brought forward, the lobby is open and the session runs by itself
+	requestWithAuth(t, server, "PUT", schedulePath, fmt.Sprintf(`{"scheduled_at":"%s","lobby_minutes":1}`, at(time.Second)), plannerToken)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/sessions/join", join, playerToken)), "Joined quiz successfully")
+
+	player := dialWS(t, server, playerToken)
+	sendWS(t, player, "join_quiz", map[string]interface{}{"session_id": session.Data.ID})
+	readWSUntil(t, player, realtime.EventQuizState)
+
+	readWSUntil(t, player, realtime.EventQuizStarted)
+	first := readWSUntil(t, player, realtime.EventQuestion).Payload.(map[string]interface{})
+	assert.Equal(t, "Q1", first["text"])
+	readWSUntil(t, player, realtime.EventAnswerRevealed)
+	second := readWSUntil(t, player, realtime.EventQuestion).Payload.(map[string]interface{})
+	assert.Equal(t, "Q2", second["text"])
+	readWSUntil(t, player, realtime.EventAnswerRevealed)
+	readWSUntil(t, player, realtime.EventQuizEnded)
+
+	var ended sessionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/sessions/"+session.Data.ID.String(), "", plannerToken), &ended))
+	assert.Equal(t, models.QuizStatusFinished, ended.Data.Status)
+	assert.Contains(t, string(requestWithAuth(t, server, "PUT", schedulePath, fmt.Sprintf(`{"scheduled_at":"%s"}`, at(time.Hour)), plannerToken)), "already started")
+
+	// A session another replica holds is left to that replica
+	var other sessionResp
+	body = fmt.Sprintf(`{"label":"Elsewhere","scheduled_at":"%s"}`, at(200*time.Millisecond))
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID), body, plannerToken), &other))
+	lockKey := fmt.Sprintf("quiz:%s:scheduler", other.Data.ID)
+	require.NoError(t, rdb.Set(context.Background(), lockKey, "another-replica", time.Minute).Err())
+
+	status := func() models.QuizStatus {
+		var current sessionResp
+		require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/sessions/"+other.Data.ID.String(), "", plannerToken), &current))
+		return current.Data.Status
+	}
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, models.QuizStatusDraft, status())
+
+	// and taken over once its lock lapses
+	require.NoError(t, rdb.Del(context.Background(), lockKey).Err())
+	assert.Eventually(t, func() bool { return status() == models.QuizStatusActive }, 2*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuizSessions(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"teacher","password":"password","email":"teacher@example.com"}`)
+	teacherToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"teacher@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"pupil_a","password":"password","email":"pupil_a@example.com"}`)
+	pupilAToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"pupil_a@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"pupil_b","password":"password","email":"pupil_b@example.com"}`)
+	pupilBToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"pupil_b@example.com","password":"password"}`))
+
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Fractions"}`, teacherToken), &quiz))
+	quizID := quiz.Data.ID.String()
+	var question struct {
+		Data models.Question `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"1/2 + 1/4?","options":["3/4","2/6"],"correct_answer":"3/4","order":1}`, teacherToken), &question))
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID), `{"text":"1/3 of 9?","options":["3","6"],"correct_answer":"3","order":2}`, teacherToken)
+
+	// One session per class, each with its own code
+	type sessionResp struct {
+		Data models.Session `json:"data"`
+	}
+	var classA, classB sessionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID), `{"label":"Class A"}`, teacherToken), &classA))
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID), `{"label":"Class B"}`, teacherToken), &classB))
+	assert.Equal(t, quiz.Data.ID, classA.Data.QuizID)
+	assert.NotEqual(t, quiz.Data.Code, classA.Data.Code)
+	assert.NotEqual(t, classA.Data.Code, classB.Data.Code)
+	assert.Equal(t, models.QuizStatusDraft, classA.Data.Status)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID), "", pupilAToken)), "not found")
+
+	// Pupils join by code
+	var joined sessionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/sessions/join", fmt.Sprintf(`{"code":"%s"}`, classA.Data.Code), pupilAToken), &joined))
+	assert.Equal(t, classA.Data.ID, joined.Data.ID)
+	assert.Equal(t, "Fractions", joined.Data.Quiz.Title)
+	requestWithAuth(t, server, "POST", "/api/v1/sessions/join", fmt.Sprintf(`{"code":"%s"}`, classB.Data.Code), pupilBToken)
+
+	// Both sessions run at the same time
+	hostA := dialWS(t, server, teacherToken)
+	sendWS(t, hostA, "host_join", map[string]interface{}{"session_id": classA.Data.ID})
+	state := readWSUntil(t, hostA, realtime.EventQuizState).Payload.(map[string]interface{})
+	assert.Equal(t, classA.Data.ID.String(), state["session_id"])
+	assert.Equal(t, quizID, state["quiz_id"])
+	sendWS(t, hostA, "host_start", map[string]interface{}{"session_id": classA.Data.ID})
+	readWSUntil(t, hostA, realtime.EventQuestion)
+
+	hostB := dialWS(t, server, teacherToken)
+	sendWS(t, hostB, "host_join", map[string]interface{}{"session_id": classB.Data.ID})
+	readWSUntil(t, hostB, realtime.EventQuizState)
+	sendWS(t, hostB, "host_start", map[string]interface{}{"session_id": classB.Data.ID})
+	readWSUntil(t, hostB, realtime.EventQuestion)
+
+	submit := fmt.Sprintf(`{"question_id":"%s","answer":"%%s"}`, question.Data.ID)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/sessions/%s/submit", classA.Data.ID), fmt.Sprintf(submit, "3/4"), pupilAToken)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/sessions/%s/submit", classB.Data.ID), fmt.Sprintf(submit, "2/6"), pupilBToken)
+
+	// Leaderboards are kept apart, and the default session saw nothing
+	leaderboardA := string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/leaderboard", classA.Data.ID), "", teacherToken))
+	leaderboardB := string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/leaderboard", classB.Data.ID), "", teacherToken))
+	assert.Contains(t, leaderboardA, `"score":100`)
+	assert.NotContains(t, leaderboardB, `"score":100`)
+	assert.NotContains(t, string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/leaderboard", quizID), "", teacherToken)), `"score"`)
+
+	// Ending class A leaves class B running
+	sendWS(t, hostA, "host_skip", map[string]interface{}{"session_id": classA.Data.ID})
+	readWSUntil(t, hostA, realtime.EventQuestion)
+	sendWS(t, hostA, "host_skip", map[string]interface{}{"session_id": classA.Data.ID})
+	readWSUntil(t, hostA, realtime.EventQuizEnded)
+
+	var endedA, runningB sessionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s", classA.Data.ID), "", teacherToken), &endedA))
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s", classB.Data.ID), "", teacherToken), &runningB))
+	assert.Equal(t, models.QuizStatusFinished, endedA.Data.Status)
+	assert.NotNil(t, endedA.Data.EndedAt)
+	assert.Equal(t, 1, endedA.Data.Version)
+	assert.Equal(t, models.QuizStatusActive, runningB.Data.Status)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/sessions/%s/submit", classA.Data.ID), fmt.Sprintf(submit, "3/4"), pupilBToken)), "not running")
+
+	// The quiz itself was never played in its default session
+	var latest struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/quizzes/"+quizID, "", teacherToken), &latest))
+	assert.Equal(t, models.QuizStatusDraft, latest.Data.Status)
+	assert.Equal(t, 1, latest.Data.PlayedVersion)
+
+	// Each session has its own report
+	type reportResp struct {
+		Data models.QuizReport `json:"data"`
+	}
+	var reportA, reportB reportResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/report", classA.Data.ID), "", teacherToken), &reportA))
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/report", classB.Data.ID), "", teacherToken), &reportB))
+	assert.Equal(t, classA.Data.ID, reportA.Data.SessionID)
+	assert.Equal(t, models.QuizStatusFinished, reportA.Data.Status)
+	assert.Equal(t, int64(1), reportA.Data.Questions[0].Correct)
+	assert.Equal(t, int64(0), reportB.Data.Questions[0].Correct)
+	assert.Equal(t, 1, reportB.Data.ParticipantCount)
+
+	// and the list sets them side by side, the default session first
+	var sessions struct {
+		Data []models.SessionSummary `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/sessions", quizID), "", teacherToken), &sessions))
+	require.Len(t, sessions.Data, 3)
+	assert.Equal(t, quiz.Data.ID, sessions.Data[0].ID)
+	assert.Equal(t, "Class A", sessions.Data[1].Label)
+	assert.Equal(t, float64(100), sessions.Data[1].PercentCorrect)
+	assert.Equal(t, float64(100), sessions.Data[1].AverageScore)
+	assert.Equal(t, float64(0), sessions.Data[2].PercentCorrect)
+	assert.Equal(t, int64(1), sessions.Data[2].ParticipantCount)
+
+	export := string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/sessions/%s/export?format=csv", classB.Data.ID), "", teacherToken))
+	assert.Contains(t, export, "pupil_b")
+	assert.NotContains(t, export, "pupil_a")
+}
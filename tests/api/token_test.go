@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/nguyen1302/realtime-quiz/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshAndLogout(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"roamer","password":"password","email":"roamer@example.com"}`)
+	type pairResp struct {
+		Data service.TokenPair `json:"data"`
+	}
+	login := func() service.TokenPair {
+		var resp pairResp
+		require.NoError(t, json.Unmarshal(request(t, server, "POST", "/api/v1/auth/login", `{"email":"roamer@example.com","password":"password"}`), &resp))
+		require.NotEmpty(t, resp.Data.AccessToken)
+		require.NotEmpty(t, resp.Data.RefreshToken)
+		return resp.Data
+	}
+	refresh := func(refreshToken string) []byte {
+		return request(t, server, "POST", "/api/v1/auth/refresh", fmt.Sprintf(`{"refresh_token":"%s"}`, refreshToken))
+	}
+	me := func(token string) string {
+		return string(requestWithAuth(t, server, "GET", "/api/v1/auth/me", "", token))
+	}
+
+	// Each refresh hands out a new pair and uses the old refresh token up
+	first := login()
+	var second pairResp
+	require.NoError(t, json.Unmarshal(refresh(first.RefreshToken), &second))
+	require.NotEmpty(t, second.Data.RefreshToken)
+	assert.NotEqual(t, first.RefreshToken, second.Data.RefreshToken)
+	assert.Contains(t, me(second.Data.AccessToken), "roamer")
+
+	// Replaying a used refresh token ends the whole family
+	other := login()
+	assert.Contains(t, string(refresh(first.RefreshToken)), "already used")
+	assert.Contains(t, string(refresh(second.Data.RefreshToken)), "invalid or expired")
+	assert.Contains(t, me(first.AccessToken), "revoked")
+	assert.Contains(t, me(second.Data.AccessToken), "revoked")
+
+	// and is refused at the WebSocket handshake too
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws?token=" + second.Data.AccessToken
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Other logins are untouched, until they log out
+	assert.Contains(t, me(other.AccessToken), "roamer")
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", "/api/v1/auth/logout", "", other.AccessToken)), "Logged out")
+	assert.Contains(t, me(other.AccessToken), "revoked")
+	assert.Contains(t, string(refresh(other.RefreshToken)), "invalid or expired")
+
+	assert.Contains(t, string(refresh("not-a-token")), "invalid or expired")
+}
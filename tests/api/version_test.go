@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nguyen1302/realtime-quiz/internal/models"
+	"github.com/nguyen1302/realtime-quiz/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuizVersions(t *testing.T) {
+	_, _, server := setupTest(t)
+
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"author","password":"password","email":"author@example.com"}`)
+	authorToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"author@example.com","password":"password"}`))
+	request(t, server, "POST", "/api/v1/auth/register", `{"username":"student","password":"password","email":"student@example.com"}`)
+	studentToken := getToken(t, request(t, server, "POST", "/api/v1/auth/login", `{"email":"student@example.com","password":"password"}`))
+
+	var quiz struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", "/api/v1/quizzes", `{"title":"Versioned"}`, authorToken), &quiz))
+	quizID := quiz.Data.ID.String()
+	questionsURL := fmt.Sprintf("/api/v1/quizzes/%s/questions", quizID)
+
+	type questionResp struct {
+		Data models.Question `json:"data"`
+	}
+	var first, second questionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", questionsURL, `{"text":"Capital of Australia?","options":["Sydney","Canberra"],"correct_answer":"Sydney","order":1}`, authorToken), &first))
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "POST", questionsURL, `{"text":"Q2","options":["A","B"],"correct_answer":"A","order":2}`, authorToken), &second))
+	assert.Equal(t, 1, first.Data.Version)
+
+	// Only the owner edits questions
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", questionsURL, `{"text":"Q3","options":["A","B"],"correct_answer":"A"}`, studentToken)), "not found")
+
+	// Before the quiz is played, edits change the question in place
+	var edited questionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "PUT", questionsURL+"/"+first.Data.ID.String(), `{"text":"Capital of Australia?","options":["Sydney","Canberra"],"correct_answer":"Sydney","order":1,"points":200}`, authorToken), &edited))
+	assert.Equal(t, first.Data.ID, edited.Data.ID)
+	assert.Equal(t, 200, edited.Data.Points)
+	assert.Equal(t, 1, edited.Data.Version)
+
+	// Play version 1: the student answers with the (wrong) key of the time
+	host := dialWS(t, server, authorToken)
+	sendWS(t, host, "host_join", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuizState)
+	sendWS(t, host, "host_start", map[string]interface{}{"quiz_id": quizID})
+	readWSUntil(t, host, realtime.EventQuestion)
+	requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), fmt.Sprintf(`{"question_id":"%s","answer":"Sydney"}`, first.Data.ID), studentToken)
+
+	// Fixing the answer key mid-run starts version 2 and leaves the run alone
+	var fixed questionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "PUT", questionsURL+"/"+first.Data.ID.String(), `{"text":"What is the capital of Australia?","options":["Sydney","Canberra"],"correct_answer":"Canberra","order":1}`, authorToken), &fixed))
+	assert.NotEqual(t, first.Data.ID, fixed.Data.ID)
+	assert.Equal(t, 2, fixed.Data.Version)
+	assert.Equal(t, first.Data.ID, *fixed.Data.PreviousID)
+	assert.Contains(t, string(requestWithAuth(t, server, "POST", fmt.Sprintf("/api/v1/quizzes/%s/submit", quizID), fmt.Sprintf(`{"question_id":"%s","answer":"Canberra"}`, fixed.Data.ID), studentToken)), `"success":false`)
+
+	sendWS(t, host, "host_skip", map[string]interface{}{"quiz_id": quizID})
+	next := readWSUntil(t, host, realtime.EventQuestion).Payload.(map[string]interface{})
+	assert.Equal(t, second.Data.ID.String(), next["id"])
+	assert.Equal(t, float64(2), next["total"])
+
+	// Later edits to the unplayed version 2 happen in place
+	var again questionResp
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "PUT", questionsURL+"/"+fixed.Data.ID.String(), `{"text":"What is the capital of Australia?","options":["Sydney","Canberra","Perth"],"correct_answer":"Canberra","order":1}`, authorToken), &again))
+	assert.Equal(t, fixed.Data.ID, again.Data.ID)
+	assert.Equal(t, 2, again.Data.Version)
+
+	// Revisions from older versions cannot be edited
+	assert.Contains(t, string(requestWithAuth(t, server, "PUT", questionsURL+"/"+first.Data.ID.String(), `{"text":"X","options":["A","B"],"correct_answer":"A"}`, authorToken)), "older version")
+
+	// Deleting from version 2 leaves version 1 complete
+	var latest struct {
+		Data models.Quiz `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/quizzes/"+quizID, "", authorToken), &latest))
+	require.Len(t, latest.Data.Questions, 2)
+	requestWithAuth(t, server, "DELETE", questionsURL+"/"+latest.Data.Questions[1].ID.String(), "", authorToken)
+
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", "/api/v1/quizzes/"+quizID, "", authorToken), &latest))
+	assert.Equal(t, 2, latest.Data.Version)
+	assert.Equal(t, 1, latest.Data.PlayedVersion)
+	require.Len(t, latest.Data.Questions, 1)
+	assert.Equal(t, "What is the capital of Australia?", latest.Data.Questions[0].Text)
+
+	var played struct {
+		Data struct {
+			Questions []models.Question `json:"questions"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/versions/1", quizID), "", authorToken), &played))
+	require.Len(t, played.Data.Questions, 2)
+	assert.Equal(t, "Capital of Australia?", played.Data.Questions[0].Text)
+	assert.Equal(t, "Sydney", played.Data.Questions[0].CorrectAnswer)
+	assert.Contains(t, string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/versions/3", quizID), "", authorToken)), "version not found")
+
+	// Reports resolve answers against the revision that was played
+	var report struct {
+		Data models.QuizReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/report", quizID), "", authorToken), &report))
+	require.Len(t, report.Data.Questions, 2)
+	assert.Equal(t, "Capital of Australia?", report.Data.Questions[0].Text)
+	assert.Equal(t, "Sydney", report.Data.Questions[0].CorrectAnswer)
+	assert.Equal(t, int64(1), report.Data.Questions[0].Correct)
+
+	// and so do exports, with a column for each question that was played
+	export := string(requestWithAuth(t, server, "GET", fmt.Sprintf("/api/v1/quizzes/%s/export?format=csv", quizID), "", authorToken))
+	assert.Contains(t, export, "q2_answer")
+	assert.Contains(t, export, "Sydney,200")
+}